@@ -18,16 +18,30 @@ var CfgFile string
 var kernelKeys = map[string]bool{
 	"config.validation": true,
 	"debug":             true,
+	"raw":               true,
 	"cache.refresh":     true,
 	"provider":          true,
 	"host":              true,
 	"port":              true,
 	"model":             true,
 	"timeout_seconds":   true,
+	"fixtures_dir":      true,
+	"record":            true,
 	"plugins.enabled":   true,
 	"plugins.disabled":  true,
+	// llm.* aliases of the keys above; see llmKeys.
+	"llm.provider":        true,
+	"llm.host":            true,
+	"llm.port":            true,
+	"llm.model":           true,
+	"llm.timeout_seconds": true,
+	"llm.fixtures_dir":    true,
+	"llm.record":          true,
 }
 
+// llmKeys are the kernel keys that may also be written under llm:, such as llm.provider.
+var llmKeys = []string{"provider", "host", "port", "model", "timeout_seconds", "fixtures_dir", "record"}
+
 var (
 	pluginExactKeys  = map[string]map[string]bool{}
 	pluginPrefixKeys = map[string][]string{}
@@ -102,7 +116,25 @@ func setDefaults() {
 	viper.SetDefault("plugins.disabled", []string{})
 	viper.SetDefault("cache.enabled", false)
 	viper.SetDefault("cache.refresh", false)
+	viper.SetDefault("cache.semantic.enabled", false)
+	viper.SetDefault("cache.semantic.min_similarity", 0.92)
+	viper.SetDefault("record", false)
+	viper.SetDefault("chat.auto_title", true)
+	viper.SetDefault("chat.compact.max_tokens", 6000)
+	viper.SetDefault("chat.compact.keep_turns", 4)
 	viper.SetDefault("roles.directory", "")
+	viper.SetDefault("prompt.directory", "")
+	viper.SetDefault("shell.role", "shell")
+	viper.SetDefault("shell.key", "g")
+	viper.SetDefault("shell.confirm", true)
+	viper.SetDefault("tools.fix.tail_lines", 80)
+	viper.SetDefault("tools.git.commit.context_command", "git diff --cached --no-color")
+	viper.SetDefault("tools.git.commit.role", "commit")
+	viper.SetDefault("review.role", "review")
+	viper.SetDefault("review.concurrency", 4)
+	viper.SetDefault("review.max_chunk_bytes", 16000)
+	viper.SetDefault("review.fail_on", "none")
+	viper.SetDefault("hooks.timeout_seconds", 60)
 	viper.SetDefault("sanitize.enabled", false)
 	viper.SetDefault("sanitize.level", "light")
 	viper.SetDefault("sanitize.max_tokens_after", 0)
@@ -162,9 +194,19 @@ func InitConfig() error {
 	if err := loadTrustedLocalConfig(); err != nil {
 		return err
 	}
+	applyLLMKeys()
 	return nil
 }
 
+// applyLLMKeys makes llm.<key> the fallback for <key>; a top-level key still takes precedence.
+func applyLLMKeys() {
+	for _, key := range llmKeys {
+		if viper.IsSet("llm." + key) {
+			viper.SetDefault(key, viper.Get("llm."+key))
+		}
+	}
+}
+
 // SetConfigString sets a config key. For list keys (e.g. plugins.enabled, plugins.disabled),
 // value must be a JSON array of strings, e.g. `["a","b"]`.
 // For scalar keys, value is stored as-is.
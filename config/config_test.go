@@ -41,6 +41,23 @@ func TestInitConfig_UsesEnvVar(t *testing.T) {
 	}
 }
 
+func TestInitConfig_LLMKeys(t *testing.T) {
+	resetViper()
+	defer resetViper()
+
+	tmpDir := t.TempDir()
+	config.CfgFile = filepath.Join(tmpDir, "config.yaml")
+	content := "host: override\nllm:\n  provider: replay\n  fixtures_dir: ./fixtures\n  record: true\n  host: localhost\n"
+	require.NoError(t, os.WriteFile(config.CfgFile, []byte(content), 0o600))
+	require.NoError(t, config.InitConfig())
+
+	require.Equal(t, "replay", viper.GetString("provider"))
+	require.Equal(t, "./fixtures", viper.GetString("fixtures_dir"))
+	require.True(t, viper.GetBool("record"))
+	require.Equal(t, "override", viper.GetString("host"), "top-level keys take precedence")
+	require.True(t, config.IsValidKey("llm.fixtures_dir"))
+}
+
 func TestSetConfigString_ValidPluginKey(t *testing.T) {
 	resetViper()
 	defer resetViper()
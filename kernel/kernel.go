@@ -37,6 +37,8 @@ func NewKernel() *Kernel {
 	k.RootCmd.PersistentFlags().Bool("debug", false, "Enable debug output (includes roles debug)")
 	_ = viper.BindPFlag("debug", k.RootCmd.PersistentFlags().Lookup("debug"))
 	_ = viper.BindPFlag("roles.debug", k.RootCmd.PersistentFlags().Lookup("debug"))
+	k.RootCmd.PersistentFlags().Bool("raw", false, "Print answers as plain text instead of rendered Markdown")
+	_ = viper.BindPFlag("raw", k.RootCmd.PersistentFlags().Lookup("raw"))
 	return k
 }
 
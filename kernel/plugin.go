@@ -14,6 +14,27 @@ type MCPTool struct {
 	Handler     func(ctx context.Context, args map[string]interface{}) (string, error)
 }
 
+// MCPPromptArgument describes one argument of an MCP prompt.
+type MCPPromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// MCPPrompt describes one prompt template exposed via the MCP server. Handler returns the
+// prompt text for the given arguments.
+type MCPPrompt struct {
+	Name        string
+	Description string
+	Arguments   []MCPPromptArgument
+	Handler     func(ctx context.Context, args map[string]string) (string, error)
+}
+
+// MCPPromptProvider is implemented by plugins that expose prompts via the MCP server.
+type MCPPromptProvider interface {
+	MCPPrompts() []MCPPrompt
+}
+
 // Plugin defines a built-in plugin.
 type Plugin interface {
 	ID() string
@@ -0,0 +1,283 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"gaia/plugins/shared"
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/viper"
+)
+
+// Capabilities describes what a model supports. Zero ContextLength means unknown.
+type Capabilities struct {
+	ContextLength int
+	MaxOutput     int
+	Tools         bool
+	Vision        bool
+	JSON          bool
+	Source        string // "config", "ollama", "builtin" or "" when unknown
+}
+
+// builtinCapabilities covers cloud models; keys match by longest name prefix.
+var builtinCapabilities = map[string]Capabilities{
+	"gpt-4.1":           {ContextLength: 1047576, MaxOutput: 32768, Tools: true, Vision: true, JSON: true},
+	"gpt-4o":            {ContextLength: 128000, MaxOutput: 16384, Tools: true, Vision: true, JSON: true},
+	"gpt-4-turbo":       {ContextLength: 128000, MaxOutput: 4096, Tools: true, Vision: true, JSON: true},
+	"gpt-4":             {ContextLength: 8192, MaxOutput: 8192, Tools: true},
+	"gpt-3.5-turbo":     {ContextLength: 16385, MaxOutput: 4096, Tools: true, JSON: true},
+	"o3":                {ContextLength: 200000, MaxOutput: 100000, Tools: true, Vision: true, JSON: true},
+	"o4-mini":           {ContextLength: 200000, MaxOutput: 100000, Tools: true, Vision: true, JSON: true},
+	"mistral-large":     {ContextLength: 131072, MaxOutput: 8192, Tools: true, JSON: true},
+	"mistral-medium":    {ContextLength: 131072, MaxOutput: 8192, Tools: true, Vision: true, JSON: true},
+	"mistral-small":     {ContextLength: 131072, MaxOutput: 8192, Tools: true, Vision: true, JSON: true},
+	"codestral":         {ContextLength: 256000, MaxOutput: 8192, Tools: true, JSON: true},
+	"pixtral":           {ContextLength: 131072, MaxOutput: 8192, Tools: true, Vision: true, JSON: true},
+	"open-mistral-nemo": {ContextLength: 131072, MaxOutput: 8192, Tools: true, JSON: true},
+	"ministral":         {ContextLength: 131072, MaxOutput: 8192, Tools: true, JSON: true},
+}
+
+// capabilityOverride is one entry of the models.capabilities config list.
+type capabilityOverride struct {
+	Name          string `mapstructure:"name"`
+	ContextLength int    `mapstructure:"context_length"`
+	MaxOutput     int    `mapstructure:"max_output"`
+	Tools         *bool  `mapstructure:"tools"`
+	Vision        *bool  `mapstructure:"vision"`
+	JSON          *bool  `mapstructure:"json"`
+}
+
+var (
+	capsMu    sync.Mutex
+	capsCache = map[string]Capabilities{}
+)
+
+// LookupCapabilities returns the capabilities of req.Model on req.Provider.
+// Ollama models are queried once per process via /api/show; cloud models use the
+// built-in table. Entries in models.capabilities override either source.
+func LookupCapabilities(ctx context.Context, req AskRequest) Capabilities {
+	key := strings.Join([]string{req.Provider, req.Host, fmt.Sprint(req.Port), req.Model}, "|")
+	capsMu.Lock()
+	caps, ok := capsCache[key]
+	capsMu.Unlock()
+	if ok {
+		return applyCapabilityOverride(caps, req.Model)
+	}
+
+	cacheable := true
+	if req.Provider == "ollama" || req.Provider == "" {
+		var err error
+		// A failed lookup is retried on the next request rather than remembered.
+		caps, err = ollamaCapabilities(ctx, req)
+		cacheable = err == nil
+	}
+	if caps.Source == "" {
+		if name, found := matchModelName(req.Model, builtinNames()); found {
+			caps = builtinCapabilities[name]
+			caps.Source = "builtin"
+		}
+	}
+	if cacheable {
+		capsMu.Lock()
+		capsCache[key] = caps
+		capsMu.Unlock()
+	}
+	return applyCapabilityOverride(caps, req.Model)
+}
+
+// ollamaCapabilities queries /api/show. The context length is the one Ollama runs the model
+// with (num_ctx or its default), not the longer length it was trained for.
+func ollamaCapabilities(ctx context.Context, req AskRequest) (Capabilities, error) {
+	var decoded struct {
+		Parameters   string                     `json:"parameters"`
+		ModelInfo    map[string]json.RawMessage `json:"model_info"`
+		Capabilities []string                   `json:"capabilities"`
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return Capabilities{}, nil
+	}
+	if err := ollamaCall(ctx, req, http.MethodPost, "/api/show", map[string]any{"model": req.Model}, &decoded); err != nil {
+		return Capabilities{}, err
+	}
+	caps := Capabilities{
+		ContextLength: ollamaRuntimeContext(contextLengthFromModelInfo(decoded.ModelInfo), numCtxFromParameters(decoded.Parameters)),
+		JSON:          true, // Ollama constrains any model with "format"
+		Source:        "ollama",
+	}
+	for _, c := range decoded.Capabilities {
+		switch c {
+		case "tools":
+			caps.Tools = true
+		case "vision":
+			caps.Vision = true
+		}
+	}
+	return caps, nil
+}
+
+func applyCapabilityOverride(caps Capabilities, model string) Capabilities {
+	var overrides []capabilityOverride
+	if err := viper.UnmarshalKey("models.capabilities", &overrides); err != nil || len(overrides) == 0 {
+		return caps
+	}
+	names := make([]string, 0, len(overrides))
+	byName := map[string]capabilityOverride{}
+	for _, o := range overrides {
+		name := strings.TrimSpace(o.Name)
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+		byName[name] = o
+	}
+	name, found := matchModelName(model, names)
+	if !found {
+		return caps
+	}
+	o := byName[name]
+	if o.ContextLength > 0 {
+		caps.ContextLength = o.ContextLength
+	}
+	if o.MaxOutput > 0 {
+		caps.MaxOutput = o.MaxOutput
+	}
+	if o.Tools != nil {
+		caps.Tools = *o.Tools
+	}
+	if o.Vision != nil {
+		caps.Vision = *o.Vision
+	}
+	if o.JSON != nil {
+		caps.JSON = *o.JSON
+	}
+	caps.Source = "config"
+	return caps
+}
+
+func builtinNames() []string {
+	names := make([]string, 0, len(builtinCapabilities))
+	for name := range builtinCapabilities {
+		names = append(names, name)
+	}
+	return names
+}
+
+// matchModelName returns the exact name, else the longest name that model starts with.
+func matchModelName(model string, names []string) (string, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return "", false
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		if strings.ToLower(name) == model {
+			return name, true
+		}
+	}
+	for _, name := range names {
+		if strings.HasPrefix(model, strings.ToLower(name)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// fitContext drops the oldest history turns until the conversation fits the model's
+// context window, keeping the system prompt, the last user message and room for the reply.
+// Unknown context lengths leave the request untouched.
+func fitContext(ctx context.Context, req AskRequest) AskRequest {
+	if len(req.Messages) == 0 {
+		return req
+	}
+	caps := LookupCapabilities(ctx, req)
+	if caps.ContextLength <= 0 {
+		return req
+	}
+	budget := caps.ContextLength - replyReserve(caps)
+	messages, dropped := trimHistory(req.SystemPrompt, req.Messages, budget)
+	if dropped == 0 {
+		return req
+	}
+	if viper.GetBool("debug") && req.ProgressOut != nil {
+		_ = shared.PrintRaw(req.ProgressOut, fmt.Sprintf("[DEBUG] context: dropped %d oldest messages to fit %s (context=%d, budget=%d tokens)\n",
+			dropped, req.Model, caps.ContextLength, budget))
+	}
+	req.Messages = messages
+	return req
+}
+
+// replyReserve is the number of tokens kept free for the model's answer.
+func replyReserve(caps Capabilities) int {
+	reserve := caps.ContextLength / 4
+	if reserve > 4096 {
+		reserve = 4096
+	}
+	if caps.MaxOutput > 0 && caps.MaxOutput < reserve {
+		reserve = caps.MaxOutput
+	}
+	return reserve
+}
+
+// trimHistory removes messages from the front until the estimated token count fits budget.
+// System messages, the last user message and the latest message are kept; an assistant
+// tool call is dropped together with its tool results so the conversation stays well-formed.
+func trimHistory(systemPrompt string, messages []ChatMessage, budget int) ([]ChatMessage, int) {
+	total := sanitizepkg.EstimateTokens(systemPrompt)
+	lastUser := -1
+	for i, m := range messages {
+		total += sanitizepkg.EstimateTokens(m.Content)
+		if m.Role == "user" {
+			lastUser = i
+		}
+	}
+	if total <= budget {
+		return messages, 0
+	}
+	drop := make([]bool, len(messages))
+	dropped := 0
+	last := len(messages) - 1
+	for i := 0; i < last && total > budget; i++ {
+		if drop[i] || messages[i].Role == "system" || i == lastUser || messages[i].Role == "tool" {
+			continue
+		}
+		if len(messages[i].ToolCalls) > 0 && toolResultsReach(messages, i, lastUser, last) {
+			continue
+		}
+		drop[i] = true
+		dropped++
+		total -= sanitizepkg.EstimateTokens(messages[i].Content)
+		if len(messages[i].ToolCalls) > 0 {
+			for j := i + 1; j < len(messages) && messages[j].Role == "tool"; j++ {
+				drop[j] = true
+				dropped++
+				total -= sanitizepkg.EstimateTokens(messages[j].Content)
+			}
+		}
+	}
+	if dropped == 0 {
+		return messages, 0
+	}
+	out := make([]ChatMessage, 0, len(messages)-dropped)
+	for i, m := range messages {
+		if !drop[i] {
+			out = append(out, m)
+		}
+	}
+	return out, dropped
+}
+
+// toolResultsReach reports whether the tool results following messages[i] include a protected index.
+func toolResultsReach(messages []ChatMessage, i, lastUser, last int) bool {
+	for j := i + 1; j < len(messages) && messages[j].Role == "tool"; j++ {
+		if j == lastUser || j == last {
+			return true
+		}
+	}
+	return false
+}
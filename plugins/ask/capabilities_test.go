@@ -0,0 +1,153 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func resetCapabilities(t *testing.T) {
+	t.Helper()
+	capsMu.Lock()
+	capsCache = map[string]Capabilities{}
+	capsMu.Unlock()
+	t.Cleanup(func() {
+		viper.Set("models.capabilities", nil)
+		capsMu.Lock()
+		capsCache = map[string]Capabilities{}
+		capsMu.Unlock()
+	})
+}
+
+func TestLookupCapabilities_Builtin(t *testing.T) {
+	resetCapabilities(t)
+	caps := LookupCapabilities(context.Background(), AskRequest{Provider: "openai", Model: "gpt-4o-mini"})
+	if caps.Source != "builtin" || caps.ContextLength != 128000 || !caps.Tools {
+		t.Errorf("caps = %+v", caps)
+	}
+	caps = LookupCapabilities(context.Background(), AskRequest{Provider: "openai", Model: "gpt-4-0613"})
+	if caps.ContextLength != 8192 {
+		t.Errorf("gpt-4 prefix should win over nothing, got %+v", caps)
+	}
+	if caps := LookupCapabilities(context.Background(), AskRequest{Provider: "openai", Model: "unknown"}); caps.Source != "" {
+		t.Errorf("unknown model caps = %+v", caps)
+	}
+}
+
+func TestLookupCapabilities_OllamaCachedAndOverridden(t *testing.T) {
+	resetCapabilities(t)
+	var shows int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&shows, 1)
+		_, _ = w.Write([]byte(`{"parameters":"num_ctx                        65536","model_info":{"qwen2.context_length":32768},"capabilities":["completion","tools"]}`))
+	}))
+	defer srv.Close()
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Provider: "ollama", Host: host, Port: port, Model: "qwen2.5:14b", Timeout: time.Second}
+
+	caps := LookupCapabilities(context.Background(), req)
+	if caps.Source != "ollama" || caps.ContextLength != 32768 || !caps.Tools || caps.Vision {
+		t.Errorf("caps = %+v", caps)
+	}
+	_ = LookupCapabilities(context.Background(), req)
+	if shows != 1 {
+		t.Errorf("/api/show called %d times, want 1", shows)
+	}
+
+	viper.Set("models.capabilities", []map[string]any{{"name": "qwen2.5", "context_length": 8192, "vision": true}})
+	caps = LookupCapabilities(context.Background(), req)
+	if caps.Source != "config" || caps.ContextLength != 8192 || !caps.Vision || !caps.Tools {
+		t.Errorf("overridden caps = %+v", caps)
+	}
+}
+
+func TestLookupCapabilities_OllamaRuntimeContext(t *testing.T) {
+	resetCapabilities(t)
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "loading", http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		params := ""
+		if body.Model == "tuned" {
+			params = "stop \"<|im_end|>\"\nnum_ctx 8192"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"parameters": params, "model_info": map[string]int{"llama.context_length": 131072}})
+	}))
+	defer srv.Close()
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Provider: "ollama", Host: host, Port: port, Model: "tuned", Timeout: time.Second}
+
+	if caps := LookupCapabilities(context.Background(), req); caps.ContextLength != 0 {
+		t.Errorf("failed lookup caps = %+v", caps)
+	}
+	fail.Store(false)
+	if caps := LookupCapabilities(context.Background(), req); caps.ContextLength != 8192 {
+		t.Errorf("context after a failed lookup = %d, want num_ctx 8192", caps.ContextLength)
+	}
+	req.Model = "plain"
+	if caps := LookupCapabilities(context.Background(), req); caps.ContextLength != ollamaDefaultNumCtx {
+		t.Errorf("context without num_ctx = %d, want the runtime default %d", caps.ContextLength, ollamaDefaultNumCtx)
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	long := strings.Repeat("x", 400) // 100 tokens
+	messages := []ChatMessage{
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: "last"},
+	}
+	out, dropped := trimHistory("sys", messages, 250)
+	if dropped != 2 || len(out) != 3 || out[len(out)-1].Content != "last" {
+		t.Errorf("dropped=%d out=%+v", dropped, out)
+	}
+	if _, dropped := trimHistory("sys", messages, 10000); dropped != 0 {
+		t.Errorf("no trimming expected, dropped=%d", dropped)
+	}
+}
+
+func TestTrimHistory_ToolCallsDroppedWithResults(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	messages := []ChatMessage{
+		{Role: "user", Content: "Goal: disk"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Name: "run_cmd"}}},
+		{Role: "tool", Content: long, ToolCallID: "call_0"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "run_cmd"}}},
+		{Role: "tool", Content: "small", ToolCallID: "call_1"},
+	}
+	out, dropped := trimHistory("", messages, 50)
+	if dropped != 2 || len(out) != 3 {
+		t.Fatalf("dropped=%d out=%+v", dropped, out)
+	}
+	if out[1].ToolCalls[0].ID != "call_1" || out[2].ToolCallID != "call_1" {
+		t.Errorf("out = %+v", out)
+	}
+}
+
+func TestFitContext_UnknownModelUntouched(t *testing.T) {
+	resetCapabilities(t)
+	req := AskRequest{Provider: "openai", Model: "unknown", Messages: []ChatMessage{{Role: "user", Content: strings.Repeat("x", 100000)}, {Role: "user", Content: "hi"}}}
+	if got := fitContext(context.Background(), req); len(got.Messages) != 2 {
+		t.Errorf("messages = %d", len(got.Messages))
+	}
+}
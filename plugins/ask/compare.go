@@ -0,0 +1,302 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gaia/plugins/shared"
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// CompareTarget is one model of an `ask --compare` run.
+type CompareTarget struct {
+	Provider string
+	Model    string
+}
+
+func (t CompareTarget) String() string { return t.Provider + ":" + t.Model }
+
+// CompareResult is the outcome of one target; Usage falls back to estimates when the
+// provider reports no token counts (Estimated is then true).
+type CompareResult struct {
+	Target     CompareTarget
+	Text       string
+	FirstToken time.Duration
+	Latency    time.Duration
+	Usage      Usage
+	Estimated  bool
+	Err        error
+}
+
+// defaultEndpoints are used for a compared provider that has no ask.endpoints entry.
+var defaultEndpoints = map[string]struct {
+	host string
+	port int
+}{
+	"ollama":  {"localhost", 11434},
+	"openai":  {"api.openai.com", 443},
+	"mistral": {"api.mistral.ai", 443},
+}
+
+// ParseCompareTarget reads "model" or "provider:model". The prefix only counts as a provider
+// when it names a registered one, so Ollama tags such as "llama3:8b" stay intact.
+func ParseCompareTarget(spec string, providers map[string]Provider, defaultProvider string) CompareTarget {
+	spec = strings.TrimSpace(spec)
+	if name, model, ok := strings.Cut(spec, ":"); ok {
+		if _, known := providers[name]; known || name == ReplayProviderName {
+			return CompareTarget{Provider: name, Model: model}
+		}
+	}
+	provider := ResolveProviderFromModel(spec)
+	if provider == "" {
+		provider = defaultProvider
+	}
+	return CompareTarget{Provider: provider, Model: spec}
+}
+
+// TargetRequest derives the request for target from base, using ask.endpoints.<provider>
+// when set, the base endpoint for the base provider, and the provider's public endpoint otherwise.
+func TargetRequest(base AskRequest, target CompareTarget) AskRequest {
+	req := base
+	req.Provider = target.Provider
+	req.Model = target.Model
+	req.ProgressClearer = nil
+	if target.Provider == base.Provider {
+		return applyEndpointOverride(req)
+	}
+	if def, ok := defaultEndpoints[target.Provider]; ok {
+		req.Host, req.Port = def.host, def.port
+	}
+	return applyEndpointOverride(req)
+}
+
+func applyEndpointOverride(req AskRequest) AskRequest {
+	prefix := "ask.endpoints." + req.Provider
+	if host := strings.TrimSpace(viper.GetString(prefix + ".host")); host != "" {
+		req.Host = host
+	}
+	if port := viper.GetInt(prefix + ".port"); port != 0 {
+		req.Port = port
+	}
+	return req
+}
+
+// RunCompare streams base to every target concurrently. onChunk receives each answer chunk
+// with the index of its target; it may be called from several goroutines.
+func RunCompare(ctx context.Context, providers map[string]Provider, base AskRequest, targets []CompareTarget, onChunk func(int, string)) []CompareResult {
+	results := make([]CompareResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target CompareTarget) {
+			defer wg.Done()
+			results[i] = runCompareTarget(ctx, providers, base, target, func(chunk string) { onChunk(i, chunk) })
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+func runCompareTarget(ctx context.Context, providers map[string]Provider, base AskRequest, target CompareTarget, onChunk func(string)) CompareResult {
+	result := CompareResult{Target: target}
+	provider, err := SelectProvider(providers, target.Provider)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req := TargetRequest(base, target)
+	start := time.Now()
+	resp, err := provider.SendStream(ctx, req, func(chunk string) {
+		if result.FirstToken == 0 {
+			result.FirstToken = time.Since(start)
+		}
+		onChunk(chunk)
+	})
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Text = resp.Text
+	result.Usage = resp.Usage
+	if result.Usage == (Usage{}) {
+		prompt := req.SystemPrompt
+		for _, m := range buildMessages(req) {
+			prompt += m.Content
+		}
+		result.Usage = Usage{PromptTokens: sanitizepkg.EstimateTokens(prompt), CompletionTokens: sanitizepkg.EstimateTokens(resp.Text)}
+		result.Estimated = true
+	}
+	return result
+}
+
+// FormatCompareSummary renders one line per target with latency and token counts.
+func FormatCompareSummary(results []CompareResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(&b, "%s\terror: %v\n", r.Target, r.Err)
+			continue
+		}
+		approx := ""
+		if r.Estimated {
+			approx = "~"
+		}
+		tokensPerSec := 0.0
+		if r.Latency > 0 {
+			tokensPerSec = float64(r.Usage.CompletionTokens) / r.Latency.Seconds()
+		}
+		fmt.Fprintf(&b, "%s\t%s (first token %s)\t%s%d in / %s%d out\t%.1f tok/s\n",
+			r.Target, r.Latency.Round(time.Millisecond), r.FirstToken.Round(time.Millisecond),
+			approx, r.Usage.PromptTokens, approx, r.Usage.CompletionTokens, tokensPerSec)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// JudgeScore is the judge's verdict on one answer.
+type JudgeScore struct {
+	Model  string  `json:"model"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// JudgeVerdict is the structured reply of the --judge model.
+type JudgeVerdict struct {
+	Scores []JudgeScore `json:"scores"`
+	Winner string       `json:"winner"`
+}
+
+const judgeSchema = `{
+  "type": "object",
+  "required": ["scores", "winner"],
+  "properties": {
+    "scores": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["model", "score", "reason"],
+        "properties": {
+          "model": {"type": "string"},
+          "score": {"type": "number", "minimum": 0, "maximum": 10},
+          "reason": {"type": "string"}
+        }
+      }
+    },
+    "winner": {"type": "string"}
+  }
+}`
+
+const judgePrompt = "You are an impartial judge. Score each answer to the question from 0 to 10 for correctness, " +
+	"completeness and clarity, give a one-sentence reason, and name the winning model. Reply with JSON only."
+
+// JudgeAnswers asks the judge model to score the successful results through structured output.
+func JudgeAnswers(ctx context.Context, provider Provider, judge AskRequest, question string, results []CompareResult) (JudgeVerdict, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question:\n%s\n", question)
+	answered := 0
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\nAnswer from %s:\n%s\n", r.Target, strings.TrimSpace(r.Text))
+		answered++
+	}
+	if answered == 0 {
+		return JudgeVerdict{}, fmt.Errorf("no answers to judge")
+	}
+	judge.SystemPrompt = judgePrompt
+	judge.Message = b.String()
+	judge.Messages = nil
+	judge.Format = &ResponseFormat{Schema: json.RawMessage(judgeSchema)}
+	out, err := SendStructured(ctx, provider, judge, viper.GetInt("ask.json_retries"))
+	if err != nil {
+		return JudgeVerdict{}, err
+	}
+	var verdict JudgeVerdict
+	if err := json.Unmarshal([]byte(out), &verdict); err != nil {
+		return JudgeVerdict{}, err
+	}
+	sort.SliceStable(verdict.Scores, func(i, j int) bool { return verdict.Scores[i].Score > verdict.Scores[j].Score })
+	return verdict, nil
+}
+
+// FormatVerdict renders the judge's scores, best first.
+func FormatVerdict(v JudgeVerdict) string {
+	var b strings.Builder
+	for _, s := range v.Scores {
+		fmt.Fprintf(&b, "%s\t%.1f\t%s\n", s.Model, s.Score, s.Reason)
+	}
+	if v.Winner != "" {
+		fmt.Fprintf(&b, "Winner: %s\n", v.Winner)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// compareExclusiveFlags act on a single answer, which `ask --compare` does not produce.
+var compareExclusiveFlags = []string{"output-file", "code-only", "json", "schema", "extract", "lang", "block", "write", "apply", "save"}
+
+// checkCompareFlags rejects flags that cannot be combined with --compare.
+func checkCompareFlags(cmd *cobra.Command) error {
+	for _, name := range compareExclusiveFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return fmt.Errorf("--%s cannot be used with --compare", name)
+		}
+	}
+	return nil
+}
+
+// runCompare implements `ask --compare`; it bypasses the cache and MemPalace persistence.
+func (p *AskPlugin) runCompare(cmd *cobra.Command, req AskRequest, specs []string, msg string) error {
+	targets := make([]CompareTarget, 0, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) != "" {
+			targets = append(targets, ParseCompareTarget(spec, p.providers, req.Provider))
+		}
+	}
+	if len(targets) < 2 {
+		return shared.PrintError(cmd.ErrOrStderr(), "--compare needs at least two models")
+	}
+	titles := make([]string, len(targets))
+	for i, t := range targets {
+		titles[i] = t.String()
+	}
+
+	sreq := ApplySanitize(cmd.ErrOrStderr(), req)
+	var results []CompareResult
+	err := shared.DisplayComparison(cmd.Context(), cmd.OutOrStdout(), titles, func(ctx context.Context, send func(int, string)) {
+		results = RunCompare(ctx, p.providers, sreq, targets, send)
+	})
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Ask failed: %v", err))
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		_ = shared.PrintBox(cmd.OutOrStdout(), r.Target.String(), r.Text)
+	}
+	_ = shared.PrintBox(cmd.OutOrStdout(), "Comparison", FormatCompareSummary(results))
+
+	judgeSpec, _ := cmd.Flags().GetString("judge")
+	if strings.TrimSpace(judgeSpec) == "" {
+		return nil
+	}
+	target := ParseCompareTarget(judgeSpec, p.providers, req.Provider)
+	provider, err := SelectProvider(p.providers, target.Provider)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	verdict, err := JudgeAnswers(cmd.Context(), provider, TargetRequest(req, target), msg, results)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Judge failed: %v", err))
+	}
+	return shared.PrintBox(cmd.OutOrStdout(), "Judge ("+target.String()+")", FormatVerdict(verdict))
+}
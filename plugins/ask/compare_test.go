@@ -0,0 +1,120 @@
+package ask
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// namedProvider is a scriptedProvider registered under its own name.
+type namedProvider struct {
+	scriptedProvider
+	name string
+}
+
+func (p *namedProvider) Name() string { return p.name }
+
+func TestParseCompareTarget(t *testing.T) {
+	providers := map[string]Provider{"ollama": &scriptedProvider{}, "openai": &scriptedProvider{}}
+	tests := []struct {
+		spec string
+		want CompareTarget
+	}{
+		{"llama3:8b", CompareTarget{Provider: "ollama", Model: "llama3:8b"}},
+		{"openai:gpt-4o", CompareTarget{Provider: "openai", Model: "gpt-4o"}},
+		{"gpt-4o-mini", CompareTarget{Provider: "openai", Model: "gpt-4o-mini"}},
+		{" qwen3 ", CompareTarget{Provider: "ollama", Model: "qwen3"}},
+	}
+	for _, tt := range tests {
+		if got := ParseCompareTarget(tt.spec, providers, "ollama"); got != tt.want {
+			t.Errorf("ParseCompareTarget(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestCompareRequestEndpoints(t *testing.T) {
+	t.Cleanup(func() { viper.Set("ask.endpoints", nil) })
+	base := AskRequest{Provider: "ollama", Host: "gpu-box", Port: 11434, Model: "llama3"}
+
+	same := TargetRequest(base, CompareTarget{Provider: "ollama", Model: "qwen3"})
+	if same.Host != "gpu-box" || same.Model != "qwen3" {
+		t.Errorf("same provider should keep the base endpoint, got %+v", same)
+	}
+	cloud := TargetRequest(base, CompareTarget{Provider: "openai", Model: "gpt-4o"})
+	if cloud.Host != "api.openai.com" || cloud.Port != 443 {
+		t.Errorf("openai should use its public endpoint, got %s:%d", cloud.Host, cloud.Port)
+	}
+	viper.Set("ask.endpoints", map[string]any{"openai": map[string]any{"host": "proxy", "port": 8443}})
+	proxied := TargetRequest(base, CompareTarget{Provider: "openai", Model: "gpt-4o"})
+	if proxied.Host != "proxy" || proxied.Port != 8443 {
+		t.Errorf("ask.endpoints.openai should override, got %s:%d", proxied.Host, proxied.Port)
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	providers := map[string]Provider{
+		"ollama": &namedProvider{scriptedProvider{replies: []string{"from ollama"}}, "ollama"},
+		"openai": &namedProvider{scriptedProvider{replies: []string{"from openai"}}, "openai"},
+	}
+	targets := []CompareTarget{{Provider: "ollama", Model: "llama3"}, {Provider: "openai", Model: "gpt-4o"}}
+	var mu sync.Mutex
+	streamed := map[int]string{}
+	results := RunCompare(context.Background(), providers, AskRequest{Provider: "ollama", Message: "hi"}, targets, func(i int, chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed[i] += chunk
+	})
+	if len(results) != 2 || results[0].Text != "from ollama" || results[1].Text != "from openai" {
+		t.Fatalf("results = %+v", results)
+	}
+	if streamed[0] != "from ollama" || streamed[1] != "from openai" {
+		t.Errorf("chunks were not routed to their panes: %v", streamed)
+	}
+	if !results[0].Estimated || results[0].Usage.CompletionTokens == 0 {
+		t.Errorf("expected estimated usage without provider counts, got %+v", results[0])
+	}
+	summary := FormatCompareSummary(results)
+	if !strings.Contains(summary, "ollama:llama3") || !strings.Contains(summary, "openai:gpt-4o") || !strings.Contains(summary, "~") {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+func TestJudgeAnswers(t *testing.T) {
+	judge := &scriptedProvider{replies: []string{`{"scores":[{"model":"a:1","score":4,"reason":"vague"},{"model":"b:2","score":9,"reason":"precise"}],"winner":"b:2"}`}}
+	results := []CompareResult{
+		{Target: CompareTarget{Provider: "a", Model: "1"}, Text: "maybe"},
+		{Target: CompareTarget{Provider: "b", Model: "2"}, Text: "exactly 42"},
+	}
+	verdict, err := JudgeAnswers(context.Background(), judge, AskRequest{Model: "judge"}, "what is it?", results)
+	if err != nil {
+		t.Fatalf("JudgeAnswers: %v", err)
+	}
+	if verdict.Winner != "b:2" || verdict.Scores[0].Model != "b:2" {
+		t.Errorf("verdict = %+v, want b:2 first", verdict)
+	}
+	if sent := judge.reqs[0].Messages[0].Content; !strings.Contains(sent, "exactly 42") || !strings.Contains(sent, "Answer from a:1") {
+		t.Errorf("judge prompt = %q", sent)
+	}
+}
+
+func TestCheckCompareFlags(t *testing.T) {
+	for _, args := range [][]string{{"--json"}, {"--schema", "s.json"}, {"--extract", "code"}, {"--write", "x.go"}, {"--apply", "x.go"}} {
+		if err := checkCompareFlags(newExtractCmd(t, args...)); err == nil || !strings.Contains(err.Error(), "--compare") {
+			t.Errorf("checkCompareFlags(%v) = %v", args, err)
+		}
+	}
+	cmd := newExtractCmd(t)
+	cmd.Flags().String("save", "", "")
+	if err := checkCompareFlags(cmd); err != nil {
+		t.Errorf("checkCompareFlags without flags = %v", err)
+	}
+	if err := cmd.Flags().Parse([]string{"--save", "out.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCompareFlags(cmd); err == nil {
+		t.Error("expected --save to be rejected")
+	}
+}
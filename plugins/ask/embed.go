@@ -0,0 +1,278 @@
+package ask
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultEmbedBatchSize is the number of inputs sent per embedding request.
+const DefaultEmbedBatchSize = 32
+
+// EmbedRequest holds the connection settings and texts for an embedding call.
+type EmbedRequest struct {
+	Provider string
+	Host     string
+	Port     int
+	Model    string
+	Timeout  time.Duration
+	Inputs   []string
+}
+
+// Embedder turns texts into vectors, one per input in order.
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, req EmbedRequest) ([][]float32, error)
+}
+
+// DefaultEmbedModel returns the embedding model used when none is configured.
+func DefaultEmbedModel(provider string) string {
+	switch provider {
+	case "openai":
+		return "text-embedding-3-small"
+	case "mistral":
+		return "mistral-embed"
+	default:
+		return "nomic-embed-text"
+	}
+}
+
+// EmbedRequestFromConfig resolves the embedding provider, endpoint and model from embed.* with
+// kernel-level fallbacks. The chat model is never reused for embeddings.
+func EmbedRequestFromConfig() EmbedRequest {
+	req := EmbedRequest{
+		Provider: FirstNonEmpty(viper.GetString("embed.provider"), viper.GetString("provider")),
+		Host:     FirstNonEmpty(viper.GetString("embed.host"), viper.GetString("host")),
+		Port:     FirstNonZero(viper.GetInt("embed.port"), viper.GetInt("port")),
+		Model:    strings.TrimSpace(viper.GetString("embed.model")),
+		Timeout:  time.Duration(FirstNonZero(viper.GetInt("embed.timeout_seconds"), viper.GetInt("timeout_seconds"))) * time.Second,
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 120 * time.Second
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = ResolveProviderFromModel(viper.GetString("model"))
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = "ollama"
+	}
+	if req.Model == "" {
+		req.Model = DefaultEmbedModel(req.Provider)
+	}
+	return req
+}
+
+func (p *OllamaProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	if len(req.Inputs) == 0 {
+		return nil, nil
+	}
+	var decoded struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	ar := AskRequest{Host: req.Host, Port: req.Port, Timeout: req.Timeout}
+	if err := ollamaCall(ctx, ar, http.MethodPost, "/api/embed", map[string]any{"model": req.Model, "input": req.Inputs}, &decoded); err != nil {
+		return nil, err
+	}
+	if len(decoded.Embeddings) != len(req.Inputs) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(decoded.Embeddings), len(req.Inputs))
+	}
+	return decoded.Embeddings, nil
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	return remoteEmbed(ctx, req, "openai", "OPENAI_API_KEY")
+}
+
+func (p *MistralProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	return remoteEmbed(ctx, req, "mistral", "MISTRAL_API_KEY")
+}
+
+// remoteEmbed calls the OpenAI-compatible /v1/embeddings endpoint.
+func remoteEmbed(ctx context.Context, req EmbedRequest, name, keyEnv string) ([][]float32, error) {
+	if len(req.Inputs) == 0 {
+		return nil, nil
+	}
+	apiKey := strings.TrimSpace(os.Getenv(keyEnv))
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s environment variable is not set", keyEnv)
+	}
+	if strings.TrimSpace(req.Host) == "" || req.Port == 0 {
+		return nil, fmt.Errorf("%s requires host and port to be set", name)
+	}
+	scheme := "http"
+	if req.Port == 443 {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s:%d/v1/embeddings", scheme, req.Host, req.Port)
+	body, err := json.Marshal(map[string]any{"model": req.Model, "input": req.Inputs})
+	if err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := withTimeout(ctx, req.Timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: req.Timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return nil, fmt.Errorf("%s error: %s - %s", name, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var decoded struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	out := make([][]float32, len(req.Inputs))
+	for _, d := range decoded.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("%s returned embedding index %d out of range", name, d.Index)
+		}
+		out[d.Index] = d.Embedding
+	}
+	for i, v := range out {
+		if v == nil {
+			return nil, fmt.Errorf("%s returned no embedding for input %d", name, i)
+		}
+	}
+	return out, nil
+}
+
+// EmbedOptions controls batching and the on-disk vector cache.
+type EmbedOptions struct {
+	BatchSize int  // inputs per request; DefaultEmbedBatchSize when <= 0
+	NoCache   bool // skip the vector cache entirely
+}
+
+// EmbedTexts embeds req.Inputs in batches. Vectors already in the cache are not requested
+// again, and new vectors are stored, keyed by sha256(model + text).
+func EmbedTexts(ctx context.Context, e Embedder, req EmbedRequest, opts EmbedOptions) ([][]float32, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultEmbedBatchSize
+	}
+	out := make([][]float32, len(req.Inputs))
+	missing := make([]int, 0, len(req.Inputs))
+	for i, text := range req.Inputs {
+		if !opts.NoCache {
+			if vec, ok := loadVector(req.Model, text); ok {
+				out[i] = vec
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+
+	for start := 0; start < len(missing); start += batchSize {
+		end := start + batchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := req
+		batch.Inputs = make([]string, 0, end-start)
+		for _, idx := range missing[start:end] {
+			batch.Inputs = append(batch.Inputs, req.Inputs[idx])
+		}
+		vectors, err := e.Embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(batch.Inputs) {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", e.Name(), len(vectors), len(batch.Inputs))
+		}
+		for j, idx := range missing[start:end] {
+			out[idx] = vectors[j]
+			if !opts.NoCache {
+				_ = storeVector(req.Model, req.Inputs[idx], vectors[j])
+			}
+		}
+	}
+	return out, nil
+}
+
+type cachedVector struct {
+	Model  string    `json:"model"`
+	Vector []float32 `json:"vector"`
+}
+
+// VectorKey is the cache key of a text embedded with model.
+func VectorKey(model, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadVector(model, text string) ([]float32, bool) {
+	dir, err := embedCacheDir()
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, VectorKey(model, text)+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var cached cachedVector
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Model != model || len(cached.Vector) == 0 {
+		return nil, false
+	}
+	return cached.Vector, true
+}
+
+func storeVector(model, text string, vec []float32) error {
+	dir, err := embedCacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cachedVector{Model: model, Vector: vec})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, VectorKey(model, text)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func embedCacheDir() (string, error) {
+	dir := strings.TrimSpace(viper.GetString("embed.cache_dir"))
+	if dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory for embeddings cache: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "gaia", "embeddings"), nil
+}
@@ -0,0 +1,118 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type countingEmbedder struct {
+	batches [][]string
+}
+
+func (e *countingEmbedder) Name() string { return "counting" }
+
+func (e *countingEmbedder) Embed(_ context.Context, req EmbedRequest) ([][]float32, error) {
+	e.batches = append(e.batches, req.Inputs)
+	out := make([][]float32, 0, len(req.Inputs))
+	for _, in := range req.Inputs {
+		out = append(out, []float32{float32(len(in)), 1})
+	}
+	return out, nil
+}
+
+func TestOllamaEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "nomic-embed-text" || len(body.Input) != 2 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"embeddings":[[0.1,0.2],[0.3,0.4]]}`))
+	}))
+	defer srv.Close()
+	host, port := parseHostPort(t, srv.URL)
+	vectors, err := NewOllamaProvider().Embed(context.Background(), EmbedRequest{
+		Host: host, Port: port, Model: "nomic-embed-text", Timeout: time.Second, Inputs: []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 0.3 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
+
+func TestOpenAIEmbed_OrdersByIndex(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[2]},{"index":0,"embedding":[1]}]}`))
+	}))
+	defer srv.Close()
+	host, port := parseHostPort(t, srv.URL)
+	vectors, err := NewOpenAIProvider().Embed(context.Background(), EmbedRequest{
+		Host: host, Port: port, Model: "text-embedding-3-small", Timeout: time.Second, Inputs: []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if vectors[0][0] != 1 || vectors[1][0] != 2 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
+
+func TestEmbedTexts_BatchesAndCaches(t *testing.T) {
+	viper.Set("embed.cache_dir", t.TempDir())
+	t.Cleanup(func() { viper.Set("embed.cache_dir", "") })
+
+	e := &countingEmbedder{}
+	req := EmbedRequest{Model: "m", Inputs: []string{"a", "bb", "ccc"}}
+	vectors, err := EmbedTexts(context.Background(), e, req, EmbedOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("EmbedTexts: %v", err)
+	}
+	if len(e.batches) != 2 || len(e.batches[0]) != 2 || len(e.batches[1]) != 1 {
+		t.Errorf("batches = %v", e.batches)
+	}
+	if vectors[2][0] != 3 {
+		t.Errorf("vectors = %v", vectors)
+	}
+
+	req.Inputs = []string{"ccc", "dddd"}
+	vectors, err = EmbedTexts(context.Background(), e, req, EmbedOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("EmbedTexts: %v", err)
+	}
+	if len(e.batches) != 3 || len(e.batches[2]) != 1 || e.batches[2][0] != "dddd" {
+		t.Errorf("cached input should not be re-embedded, batches = %v", e.batches)
+	}
+	if vectors[0][0] != 3 || vectors[1][0] != 4 {
+		t.Errorf("vectors = %v", vectors)
+	}
+
+	if _, err := EmbedTexts(context.Background(), e, req, EmbedOptions{NoCache: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.batches) != 4 || len(e.batches[3]) != 2 {
+		t.Errorf("NoCache should embed everything, batches = %v", e.batches)
+	}
+}
+
+func TestVectorKey_DependsOnModel(t *testing.T) {
+	if VectorKey("a", "text") == VectorKey("b", "text") {
+		t.Error("keys for different models must differ")
+	}
+}
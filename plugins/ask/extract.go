@@ -0,0 +1,154 @@
+package ask
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+)
+
+// extracting reports whether the answer is reduced to its code (--extract, --write or --apply).
+func extracting(cmd *cobra.Command) bool {
+	extract, _ := cmd.Flags().GetString("extract")
+	write, _ := cmd.Flags().GetString("write")
+	apply, _ := cmd.Flags().GetString("apply")
+	return extract != "" || write != "" || apply != ""
+}
+
+// validateExtract checks the extraction flags before the request is sent, and confirms
+// overwriting an existing --write file.
+func validateExtract(cmd *cobra.Command) error {
+	if !extracting(cmd) {
+		return nil
+	}
+	if extract, _ := cmd.Flags().GetString("extract"); extract != "" && extract != "code" {
+		return fmt.Errorf("unknown --extract %q (only \"code\" is supported)", extract)
+	}
+	if jsonOut, _ := cmd.Flags().GetBool("json"); jsonOut {
+		return errors.New("code extraction cannot be used with --json")
+	}
+	if schema, _ := cmd.Flags().GetString("schema"); schema != "" {
+		return errors.New("code extraction cannot be used with --schema")
+	}
+	if block, _ := cmd.Flags().GetInt("block"); block < 0 {
+		return errors.New("--block must be 1 or more")
+	}
+	write, _ := cmd.Flags().GetString("write")
+	apply, _ := cmd.Flags().GetString("apply")
+	if write != "" && apply != "" {
+		return errors.New("use either --write or --apply")
+	}
+	if write != "" {
+		return confirmOverwrite(cmd, write)
+	}
+	return nil
+}
+
+// selectCode picks the code blocks of answer matching --lang and --block.
+func selectCode(cmd *cobra.Command, answer string) ([]shared.CodeBlock, error) {
+	blocks := shared.ExtractCodeBlocks(answer)
+	if lang, _ := cmd.Flags().GetString("lang"); lang != "" {
+		wanted := map[string]bool{}
+		for _, l := range strings.Split(lang, ",") {
+			wanted[strings.ToLower(strings.TrimSpace(l))] = true
+		}
+		var filtered []shared.CodeBlock
+		for _, b := range blocks {
+			if wanted[b.Lang] {
+				filtered = append(filtered, b)
+			}
+		}
+		if len(filtered) == 0 {
+			return nil, fmt.Errorf("the answer has no %s code blocks", lang)
+		}
+		blocks = filtered
+	}
+	if len(blocks) == 0 {
+		return nil, errors.New("the answer has no code blocks")
+	}
+	if n, _ := cmd.Flags().GetInt("block"); n > 0 {
+		if n > len(blocks) {
+			return nil, fmt.Errorf("--block %d: the answer has %d matching code blocks", n, len(blocks))
+		}
+		blocks = blocks[n-1 : n]
+	}
+	return blocks, nil
+}
+
+// extractCode prints, writes or applies the code of answer. out receives the code when it
+// is not written to a file.
+func extractCode(cmd *cobra.Command, out io.Writer, answer string) error {
+	blocks, err := selectCode(cmd, answer)
+	if err != nil {
+		return err
+	}
+	code := strings.TrimRight(shared.JoinCode(blocks), "\n") + "\n"
+	if path, _ := cmd.Flags().GetString("apply"); path != "" {
+		if len(blocks) > 1 {
+			return fmt.Errorf("the answer has %d code blocks; pick one with --block or --lang", len(blocks))
+		}
+		return applyCode(cmd, path, code)
+	}
+	if path, _ := cmd.Flags().GetString("write"); path != "" {
+		if err := writeFile(path, code); err != nil {
+			return err
+		}
+		return shared.PrintBox(cmd.ErrOrStderr(), "Code", fmt.Sprintf("Wrote %d lines to %s", strings.Count(code, "\n"), path))
+	}
+	return shared.PrintRaw(out, code)
+}
+
+// applyCode shows the diff between path and code and writes code after approval.
+func applyCode(cmd *cobra.Command, path, code string) error {
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	diff := shared.UnifiedDiff("a/"+filepath.ToSlash(path), "b/"+filepath.ToSlash(path), string(current), code)
+	if diff == "" {
+		return shared.PrintBox(cmd.ErrOrStderr(), "Code", fmt.Sprintf("%s is already up to date", path))
+	}
+	if shared.HasTTYStdout() {
+		diff = shared.ColorizeDiff(diff)
+	}
+	if err := shared.PrintRaw(cmd.OutOrStdout(), diff); err != nil {
+		return err
+	}
+	if force, _ := cmd.Flags().GetBool("force"); !force {
+		if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
+			return errors.New("no TTY available to approve the change (pass --force to apply it)")
+		}
+		ok, err := shared.RunConfirmationPromptTUI(fmt.Sprintf("Apply these changes to %s?", path), "Apply", cmd.InOrStdin(), cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return shared.PrintBox(cmd.ErrOrStderr(), "Code", fmt.Sprintf("%s left unchanged", path))
+		}
+	}
+	if err := writeFile(path, code); err != nil {
+		return err
+	}
+	return shared.PrintBox(cmd.ErrOrStderr(), "Code", fmt.Sprintf("Applied changes to %s", path))
+}
+
+// writeFile writes content to path, creating parent directories and keeping the mode of an
+// existing file.
+func writeFile(path, content string) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(content), mode)
+}
@@ -0,0 +1,102 @@
+package ask
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const codeAnswer = "Two options:\n\n```go\npackage main\n```\n\n```python\nprint(1)\n```\n\n```go\nfunc f() {}\n```\n"
+
+func newExtractCmd(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("extract", "", "")
+	cmd.Flags().String("lang", "", "")
+	cmd.Flags().Int("block", 0, "")
+	cmd.Flags().String("write", "", "")
+	cmd.Flags().String("apply", "", "")
+	cmd.Flags().Bool("force", false, "")
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().String("schema", "", "")
+	if err := cmd.Flags().Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	var errOut bytes.Buffer
+	cmd.SetErr(&errOut)
+	return cmd
+}
+
+func TestSelectCode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--extract", "code"}, "package main\n\nprint(1)\n\nfunc f() {}\n"},
+		{[]string{"--extract", "code", "--lang", "go"}, "package main\n\nfunc f() {}\n"},
+		{[]string{"--extract", "code", "--lang", "go", "--block", "2"}, "func f() {}\n"},
+		{[]string{"--extract", "code", "--block", "2"}, "print(1)\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := extractCode(newExtractCmd(t, tt.args...), &out, codeAnswer); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, out.String(), tt.want)
+		}
+	}
+	if _, err := selectCode(newExtractCmd(t, "--lang", "rust"), codeAnswer); err == nil {
+		t.Errorf("--lang without matching blocks should fail")
+	}
+	if _, err := selectCode(newExtractCmd(t, "--block", "4"), codeAnswer); err == nil {
+		t.Errorf("--block past the last block should fail")
+	}
+	if err := validateExtract(newExtractCmd(t, "--extract", "links")); err == nil {
+		t.Errorf("unknown --extract should fail")
+	}
+}
+
+func TestWriteAndApplyCode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := extractCode(newExtractCmd(t, "--write", path, "--lang", "python"), nil, codeAnswer); err != nil {
+		t.Fatalf("--write: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "print(1)\n" {
+		t.Errorf("--write wrote %q", data)
+	}
+	if err := validateExtract(newExtractCmd(t, "--write", path)); err == nil {
+		t.Errorf("--write over an existing file needs confirmation or --force")
+	}
+
+	if err := extractCode(newExtractCmd(t, "--apply", path), nil, codeAnswer); err == nil || !strings.Contains(err.Error(), "--block") {
+		t.Errorf("--apply with several blocks: err = %v", err)
+	}
+	// Tests run without a TTY: approval is impossible, so the file stays unchanged.
+	cmd := newExtractCmd(t, "--apply", path, "--lang", "go", "--block", "1")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := extractCode(cmd, nil, codeAnswer); err == nil {
+		t.Errorf("--apply without a TTY or --force should fail")
+	}
+	if !strings.Contains(out.String(), "-print(1)\n+package main") {
+		t.Errorf("--apply should print the diff, got:\n%s", out.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "print(1)\n" {
+		t.Errorf("file changed without approval: %q", data)
+	}
+	cmd = newExtractCmd(t, "--apply", path, "--lang", "go", "--block", "1", "--force")
+	cmd.SetOut(&out)
+	if err := extractCode(cmd, nil, codeAnswer); err != nil {
+		t.Fatalf("--apply --force: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "package main\n" {
+		t.Errorf("--apply --force wrote %q", data)
+	}
+}
@@ -0,0 +1,347 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gaia/plugins/shared"
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/viper"
+)
+
+// Limits bounds the load sent to one provider endpoint. Zero values mean unlimited.
+type Limits struct {
+	MaxConcurrent     int `mapstructure:"max_concurrent"`
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	TokensPerMinute   int `mapstructure:"tokens_per_minute"`
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxConcurrent > 0 || l.RequestsPerMinute > 0 || l.TokensPerMinute > 0
+}
+
+// LimitsFor reads ask.limits.<provider>.
+func LimitsFor(provider string) Limits {
+	var l Limits
+	_ = viper.UnmarshalKey("ask.limits."+provider, &l)
+	return l
+}
+
+// tokenBucket refills continuously at capacity per minute. Its level is kept in the limiter
+// state file; capacity comes from the configured limits.
+type tokenBucket struct {
+	capacity float64
+	Tokens   float64   `json:"tokens"`
+	Last     time.Time `json:"last"`
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{capacity: float64(perMinute), Tokens: float64(perMinute), Last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.Last); elapsed > 0 {
+		b.Tokens += elapsed.Minutes() * b.capacity
+		if b.Tokens > b.capacity {
+			b.Tokens = b.capacity
+		}
+		b.Last = now
+	}
+}
+
+// wait returns how long until n tokens are available. A request larger than the whole
+// bucket only waits for a full bucket, so it cannot block forever.
+func (b *tokenBucket) wait(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.refill(now)
+	if n > b.capacity {
+		n = b.capacity
+	}
+	if b.Tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.Tokens) / b.capacity * float64(time.Minute))
+}
+
+func (b *tokenBucket) take(n float64) {
+	if b != nil {
+		b.Tokens -= n
+	}
+}
+
+// limiterState is the shared state of one provider endpoint: the requests in flight, by
+// holder and process, and the levels of the rate budgets.
+type limiterState struct {
+	Slots    map[string]int `json:"slots,omitempty"`
+	Requests *tokenBucket   `json:"requests,omitempty"`
+	Tokens   *tokenBucket   `json:"tokens,omitempty"`
+}
+
+// slotPollInterval is how often a request waiting for a free slot checks again.
+const slotPollInterval = 50 * time.Millisecond
+
+// limiterLockTimeout bounds the wait for the state file lock, which is only held briefly.
+const limiterLockTimeout = 10 * time.Second
+
+// limiter enforces Limits for one provider endpoint. Its state lives in a file under the
+// config directory, so every gaia process sharing the endpoint shares the limits.
+type limiter struct {
+	limits Limits
+	path   string
+	now    func() time.Time
+}
+
+func newLimiter(limits Limits, path string, now func() time.Time) *limiter {
+	return &limiter{limits: limits, path: path, now: now}
+}
+
+var holderSeq atomic.Int64
+
+// update runs fn on the state under the exclusive lock of the state file and saves it.
+func (l *limiter) update(fn func(st *limiterState, now time.Time)) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	return shared.With(l.path+".lock", shared.Exclusive, limiterLockTimeout, func() error {
+		st := limiterState{}
+		if data, err := os.ReadFile(l.path); err == nil {
+			// A corrupt state file only resets the budgets.
+			_ = json.Unmarshal(data, &st)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		now := l.now()
+		st.Requests = l.bucket(st.Requests, l.limits.RequestsPerMinute, now)
+		st.Tokens = l.bucket(st.Tokens, l.limits.TokensPerMinute, now)
+		for id, pid := range st.Slots {
+			// Slots of processes that exited without releasing them are reclaimed.
+			if !processAlive(pid) {
+				delete(st.Slots, id)
+			}
+		}
+		fn(&st, now)
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		tmp := l.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, l.path)
+	})
+}
+
+// bucket restores a saved bucket with the configured capacity, or starts a full one.
+func (l *limiter) bucket(saved *tokenBucket, perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	if saved == nil || saved.Last.IsZero() {
+		return newTokenBucket(perMinute, now)
+	}
+	saved.capacity = float64(perMinute)
+	if saved.Tokens > saved.capacity {
+		saved.Tokens = saved.capacity
+	}
+	return saved
+}
+
+// acquire queues until a slot and the rate budgets allow a request of cost prompt tokens.
+// It returns the slot held and how long it waited; release must be called with the slot
+// and the completion token count.
+func (l *limiter) acquire(ctx context.Context, cost int) (string, time.Duration, error) {
+	start := l.now()
+	for {
+		var wait time.Duration
+		slot := ""
+		err := l.update(func(st *limiterState, now time.Time) {
+			wait = 0
+			if l.limits.MaxConcurrent > 0 && len(st.Slots) >= l.limits.MaxConcurrent {
+				wait = slotPollInterval
+			}
+			if w := st.Requests.wait(1, now); w > wait {
+				wait = w
+			}
+			if w := st.Tokens.wait(float64(cost), now); w > wait {
+				wait = w
+			}
+			if wait > 0 {
+				return
+			}
+			st.Requests.take(1)
+			st.Tokens.take(float64(cost))
+			if l.limits.MaxConcurrent > 0 {
+				if st.Slots == nil {
+					st.Slots = map[string]int{}
+				}
+				slot = fmt.Sprintf("%d-%d", os.Getpid(), holderSeq.Add(1))
+				st.Slots[slot] = os.Getpid()
+			}
+		})
+		if err != nil {
+			return "", l.now().Sub(start), err
+		}
+		if wait == 0 {
+			return slot, l.now().Sub(start), nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", l.now().Sub(start), ctx.Err()
+		}
+	}
+}
+
+func (l *limiter) release(slot string, completionTokens int) {
+	_ = l.update(func(st *limiterState, _ time.Time) {
+		st.Tokens.take(float64(completionTokens))
+		delete(st.Slots, slot)
+	})
+}
+
+// processAlive reports whether pid is a running process.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// limiterFor returns the limiter of a provider endpoint, or nil when unlimited.
+func limiterFor(provider string, req AskRequest) (*limiter, error) {
+	limits := LimitsFor(provider)
+	if !limits.enabled() {
+		return nil, nil
+	}
+	dir, err := limitsDir()
+	if err != nil {
+		return nil, err
+	}
+	name := unsafeFileChars.ReplaceAllString(fmt.Sprintf("%s-%s-%d", provider, req.Host, req.Port), "_")
+	return newLimiter(limits, filepath.Join(dir, name+".json"), time.Now), nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// limitsDir holds the limiter state files: ask.limits_dir, or ~/.config/gaia/limits.
+func limitsDir() (string, error) {
+	dir := strings.TrimSpace(viper.GetString("ask.limits_dir"))
+	if dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory for rate limits: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "gaia", "limits"), nil
+}
+
+// LimitedProvider applies ask.limits to every request of the wrapped provider.
+type LimitedProvider struct {
+	inner Provider
+}
+
+// WithLimits wraps provider when ask.limits.<name> sets any limit.
+func WithLimits(provider Provider) Provider {
+	if !LimitsFor(provider.Name()).enabled() {
+		return provider
+	}
+	return &LimitedProvider{inner: provider}
+}
+
+func (p *LimitedProvider) Name() string { return p.inner.Name() }
+
+func (p *LimitedProvider) Send(ctx context.Context, req AskRequest) (AskResponse, error) {
+	l, slot, err := p.acquire(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	resp, err := p.inner.Send(ctx, req)
+	p.release(l, slot, resp)
+	return resp, err
+}
+
+func (p *LimitedProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	l, slot, err := p.acquire(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	resp, err := p.inner.SendStream(ctx, req, onChunk)
+	p.release(l, slot, resp)
+	return resp, err
+}
+
+// Embed applies the limits to an embedding request of the wrapped provider.
+func (p *LimitedProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	embedder, ok := p.inner.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support embeddings", p.inner.Name())
+	}
+	l, slot, err := p.acquire(ctx, AskRequest{Host: req.Host, Port: req.Port, Message: strings.Join(req.Inputs, "\n")})
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := embedder.Embed(ctx, req)
+	if l != nil {
+		// Embeddings produce no completion tokens.
+		l.release(slot, 0)
+	}
+	return vectors, err
+}
+
+func (p *LimitedProvider) acquire(ctx context.Context, req AskRequest) (*limiter, string, error) {
+	l, err := limiterFor(p.inner.Name(), req)
+	if err != nil || l == nil {
+		return nil, "", err
+	}
+	slot, waited, err := l.acquire(ctx, estimatePromptTokens(req))
+	if err != nil {
+		return nil, "", fmt.Errorf("waiting for %s rate limit: %w", p.inner.Name(), err)
+	}
+	if waited >= 10*time.Millisecond && viper.GetBool("debug") && req.ProgressOut != nil {
+		_ = shared.PrintRaw(req.ProgressOut, fmt.Sprintf("[DEBUG] limiter: waited %s for %s %s:%d (max_concurrent=%d, rpm=%d, tpm=%d)\n",
+			waited.Round(time.Millisecond), p.inner.Name(), req.Host, req.Port,
+			l.limits.MaxConcurrent, l.limits.RequestsPerMinute, l.limits.TokensPerMinute))
+	}
+	return l, slot, nil
+}
+
+func (p *LimitedProvider) release(l *limiter, slot string, resp AskResponse) {
+	if l == nil {
+		return
+	}
+	completion := resp.Usage.CompletionTokens
+	if completion == 0 {
+		completion = sanitizepkg.EstimateTokens(resp.Text)
+	}
+	l.release(slot, completion)
+}
+
+func estimatePromptTokens(req AskRequest) int {
+	var b strings.Builder
+	// buildMessages includes the system prompt.
+	for _, m := range buildMessages(req) {
+		b.WriteString(m.Content)
+	}
+	return sanitizepkg.EstimateTokens(b.String())
+}
@@ -0,0 +1,188 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/viper"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	start := time.Unix(0, 0)
+	b := newTokenBucket(60, start)
+	for i := 0; i < 60; i++ {
+		if w := b.wait(1, start); w != 0 {
+			t.Fatalf("request %d should not wait, got %s", i, w)
+		}
+		b.take(1)
+	}
+	if w := b.wait(1, start); w != time.Second {
+		t.Errorf("61st request in the same instant should wait 1s, got %s", w)
+	}
+	if w := b.wait(1, start.Add(time.Second)); w != 0 {
+		t.Errorf("bucket should refill after 1s, got %s", w)
+	}
+	if w := b.wait(1000, start.Add(2*time.Minute)); w != 0 {
+		t.Errorf("oversized request should pass on a full bucket, got %s", w)
+	}
+	if newTokenBucket(0, start).wait(1, start) != 0 {
+		t.Errorf("a disabled bucket never waits")
+	}
+}
+
+// blockingProvider counts concurrent Send calls until release is closed.
+type blockingProvider struct {
+	active, peak atomic.Int32
+	release      chan struct{}
+}
+
+func (p *blockingProvider) Name() string { return "ollama" }
+
+func (p *blockingProvider) Send(ctx context.Context, _ AskRequest) (AskResponse, error) {
+	n := p.active.Add(1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-p.release
+	p.active.Add(-1)
+	return AskResponse{Text: "ok"}, nil
+}
+
+func (p *blockingProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	return p.Send(ctx, req)
+}
+
+func TestEstimatePromptTokensCountsSystemPromptOnce(t *testing.T) {
+	req := AskRequest{SystemPrompt: strings.Repeat("system ", 100), Message: strings.Repeat("user ", 100)}
+	want := sanitizepkg.EstimateTokens(req.SystemPrompt + req.Message)
+	if got := estimatePromptTokens(req); got != want {
+		t.Errorf("estimatePromptTokens = %d, want %d", got, want)
+	}
+}
+
+func TestLimitedProviderMaxConcurrent(t *testing.T) {
+	viper.Set("ask.limits.ollama.max_concurrent", 2)
+	viper.Set("ask.limits_dir", t.TempDir())
+	t.Cleanup(func() { viper.Set("ask.limits", nil); viper.Set("ask.limits_dir", "") })
+
+	inner := &blockingProvider{release: make(chan struct{})}
+	provider := WithLimits(inner)
+	if _, ok := provider.(*LimitedProvider); !ok {
+		t.Fatalf("expected a LimitedProvider, got %T", provider)
+	}
+	req := AskRequest{Host: "limit-test", Port: 1, Message: "hi"}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = provider.Send(context.Background(), req)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+	if peak := inner.peak.Load(); peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+}
+
+func TestLimitedProviderQueueCancelled(t *testing.T) {
+	viper.Set("ask.limits.ollama.max_concurrent", 1)
+	viper.Set("ask.limits_dir", t.TempDir())
+	t.Cleanup(func() { viper.Set("ask.limits", nil); viper.Set("ask.limits_dir", "") })
+
+	inner := &blockingProvider{release: make(chan struct{})}
+	provider := WithLimits(inner)
+	req := AskRequest{Host: "cancel-test", Port: 1, Message: "hi"}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = provider.Send(context.Background(), req)
+	}()
+	// Let the first request release its slot before the state directory is removed.
+	defer func() {
+		close(inner.release)
+		<-done
+	}()
+	for inner.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := provider.Send(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued request should fail with the context error, got %v", err)
+	}
+}
+
+// Two limiters on one state file stand for two gaia processes sharing an endpoint.
+func TestLimiterSharedAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ollama.json")
+	limits := Limits{MaxConcurrent: 1, RequestsPerMinute: 2}
+	first := newLimiter(limits, path, time.Now)
+	second := newLimiter(limits, path, time.Now)
+
+	slot, _, err := first.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := second.acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second process should wait for the slot, got %v", err)
+	}
+
+	first.release(slot, 0)
+	slot, _, err = second.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.release(slot, 0)
+
+	// Both requests used the shared per-minute budget of 2.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := first.acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("third request should wait for the shared rpm budget, got %v", err)
+	}
+}
+
+func TestLimiterReclaimsSlotsOfExitedProcesses(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("true not available")
+	}
+	path := filepath.Join(t.TempDir(), "ollama.json")
+	state := fmt.Sprintf(`{"slots":{"%d-1":%d}}`, cmd.Process.Pid, cmd.Process.Pid)
+	if err := os.WriteFile(path, []byte(state), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l := newLimiter(Limits{MaxConcurrent: 1}, path, time.Now)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := l.acquire(ctx, 1); err != nil {
+		t.Errorf("slot of an exited process should be reclaimed, got %v", err)
+	}
+}
+
+func TestWithLimitsUnconfigured(t *testing.T) {
+	inner := &scriptedProvider{}
+	if got := WithLimits(inner); got != Provider(inner) {
+		t.Errorf("provider without limits should not be wrapped, got %T", got)
+	}
+}
@@ -18,23 +18,56 @@ func NewMistralProvider() *MistralProvider { return &MistralProvider{} }
 func (p *MistralProvider) Name() string { return "mistral" }
 
 type mistralChatCompletionRequest struct {
-	Model    string           `json:"model"`
-	Messages []mistralMessage `json:"messages"`
-	Stream   bool             `json:"stream"`
+	Model          string                 `json:"model"`
+	Messages       []mistralMessage       `json:"messages"`
+	Stream         bool                   `json:"stream"`
+	ResponseFormat *mistralResponseFormat `json:"response_format,omitempty"`
+	Tools          []functionTool         `json:"tools,omitempty"`
+}
+
+// mistralResponseFormat enables Mistral JSON mode; the schema itself is carried in the prompt.
+type mistralResponseFormat struct {
+	Type string `json:"type"`
+}
+
+func mistralFormat(format *ResponseFormat) *mistralResponseFormat {
+	if format == nil {
+		return nil
+	}
+	return &mistralResponseFormat{Type: "json_object"}
 }
 
 type mistralMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []remoteToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+	Name       string           `json:"name,omitempty"`
+}
+
+func mistralMessages(messages []ChatMessage) []mistralMessage {
+	out := make([]mistralMessage, 0, len(messages))
+	for _, msg := range messages {
+		out = append(out, mistralMessage{
+			Role:       strings.TrimSpace(msg.Role),
+			Content:    strings.TrimSpace(msg.Content),
+			ToolCalls:  toRemoteToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+			Name:       msg.Name,
+		})
+	}
+	return out
 }
 
 type mistralChatCompletionResponse struct {
 	Choices []struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string           `json:"role"`
+			Content   string           `json:"content"`
+			ToolCalls []remoteToolCall `json:"tool_calls"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *remoteUsage `json:"usage"`
 }
 
 func (p *MistralProvider) Send(ctx context.Context, req AskRequest) (AskResponse, error) {
@@ -50,22 +83,14 @@ func (p *MistralProvider) Send(ctx context.Context, req AskRequest) (AskResponse
 		scheme = "https"
 	}
 	url := fmt.Sprintf("%s://%s:%d/v1/chat/completions", scheme, req.Host, req.Port)
-
-	rawMessages := buildMessages(req)
-	messages := make([]mistralMessage, 0, len(rawMessages))
-	for _, msg := range rawMessages {
-		role := strings.TrimSpace(msg["role"])
-		content := strings.TrimSpace(msg["content"])
-		if role == "" || content == "" {
-			continue
-		}
-		messages = append(messages, mistralMessage{Role: role, Content: content})
-	}
+	req = fitContext(ctx, req)
 
 	mistralReq := mistralChatCompletionRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   false,
+		Model:          req.Model,
+		Messages:       mistralMessages(buildMessages(req)),
+		Stream:         false,
+		ResponseFormat: mistralFormat(req.Format),
+		Tools:          toolSpecs(req.Tools),
 	}
 	body, err := json.Marshal(mistralReq)
 	if err != nil {
@@ -92,6 +117,11 @@ func (p *MistralProvider) Send(ctx context.Context, req AskRequest) (AskResponse
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		errBody, _ := io.ReadAll(resp.Body)
+		if len(req.Tools) > 0 {
+			if err := toolsUnsupportedError(resp.StatusCode, string(errBody)); err != nil {
+				return AskResponse{}, err
+			}
+		}
 		return AskResponse{}, fmt.Errorf("mistral error: %s - %s", resp.Status, strings.TrimSpace(string(errBody)))
 	}
 
@@ -106,10 +136,15 @@ func (p *MistralProvider) Send(ctx context.Context, req AskRequest) (AskResponse
 	if len(mistralResp.Choices) == 0 {
 		return AskResponse{}, fmt.Errorf("mistral response has no choices")
 	}
-	return AskResponse{Text: mistralResp.Choices[0].Message.Content}, nil
+	choice := mistralResp.Choices[0].Message
+	text, thinking := SplitThinking(choice.Content)
+	return AskResponse{Text: text, Thinking: thinking, ToolCalls: fromRemoteToolCalls(choice.ToolCalls), Usage: mistralResp.Usage.toUsage()}, nil
 }
 
 func (p *MistralProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	if len(req.Tools) > 0 {
+		return sendToolsAsStream(ctx, p, req, onChunk)
+	}
 	apiKey := strings.TrimSpace(os.Getenv("MISTRAL_API_KEY"))
 	if apiKey == "" {
 		return AskResponse{}, fmt.Errorf("MISTRAL_API_KEY environment variable is not set")
@@ -122,22 +157,13 @@ func (p *MistralProvider) SendStream(ctx context.Context, req AskRequest, onChun
 		scheme = "https"
 	}
 	url := fmt.Sprintf("%s://%s:%d/v1/chat/completions", scheme, req.Host, req.Port)
-
-	rawMessages := buildMessages(req)
-	messages := make([]mistralMessage, 0, len(rawMessages))
-	for _, msg := range rawMessages {
-		role := strings.TrimSpace(msg["role"])
-		content := strings.TrimSpace(msg["content"])
-		if role == "" || content == "" {
-			continue
-		}
-		messages = append(messages, mistralMessage{Role: role, Content: content})
-	}
+	req = fitContext(ctx, req)
 
 	mistralReq := mistralChatCompletionRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   true,
+		Model:          req.Model,
+		Messages:       mistralMessages(buildMessages(req)),
+		Stream:         true,
+		ResponseFormat: mistralFormat(req.Format),
 	}
 	body, err := json.Marshal(mistralReq)
 	if err != nil {
@@ -167,7 +193,7 @@ func (p *MistralProvider) SendStream(ctx context.Context, req AskRequest, onChun
 		return AskResponse{}, fmt.Errorf("mistral error: %s - %s", resp.Status, strings.TrimSpace(string(errBody)))
 	}
 
-	var full strings.Builder
+	split := newStreamSplitter(req, onChunk)
 	buf := make([]byte, 4096)
 	leftover := ""
 	for {
@@ -187,7 +213,7 @@ func (p *MistralProvider) SendStream(ctx context.Context, req AskRequest, onChun
 					continue
 				}
 				if line == "data: [DONE]" {
-					return AskResponse{Text: full.String()}, nil
+					return split.Response(), nil
 				}
 				if strings.HasPrefix(line, "data: ") {
 					jsonData := strings.TrimPrefix(line, "data: ")
@@ -197,15 +223,18 @@ func (p *MistralProvider) SendStream(ctx context.Context, req AskRequest, onChun
 								Content string `json:"content"`
 							} `json:"delta"`
 						} `json:"choices"`
+						Usage *remoteUsage `json:"usage"`
 					}
 					if err := json.Unmarshal([]byte(jsonData), &streamResp); err != nil {
 						continue
 					}
+					if streamResp.Usage != nil {
+						split.usage = streamResp.Usage.toUsage()
+					}
 					if len(streamResp.Choices) > 0 {
 						delta := streamResp.Choices[0].Delta.Content
 						if delta != "" {
-							onChunk(delta)
-							full.WriteString(delta)
+							split.Write(delta)
 						}
 					}
 				}
@@ -218,5 +247,5 @@ func (p *MistralProvider) SendStream(ctx context.Context, req AskRequest, onChun
 			return AskResponse{}, err
 		}
 	}
-	return AskResponse{Text: full.String()}, nil
+	return split.Response(), nil
 }
@@ -0,0 +1,96 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelInfo describes a model known to a provider.
+type ModelInfo struct {
+	Name          string
+	Size          int64 // bytes; 0 when the provider does not report it
+	ModifiedAt    time.Time
+	Family        string
+	ParameterSize string
+	Quantization  string
+	OwnedBy       string
+}
+
+// ModelLister is implemented by providers that can enumerate their models.
+// req supplies Host, Port and Timeout.
+type ModelLister interface {
+	ListModels(ctx context.Context, req AskRequest) ([]ModelInfo, error)
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context, req AskRequest) ([]ModelInfo, error) {
+	return listRemoteModels(ctx, req, "openai", "OPENAI_API_KEY")
+}
+
+func (p *MistralProvider) ListModels(ctx context.Context, req AskRequest) ([]ModelInfo, error) {
+	return listRemoteModels(ctx, req, "mistral", "MISTRAL_API_KEY")
+}
+
+// listRemoteModels reads the OpenAI-compatible /v1/models endpoint.
+func listRemoteModels(ctx context.Context, req AskRequest, name, keyEnv string) ([]ModelInfo, error) {
+	apiKey := strings.TrimSpace(os.Getenv(keyEnv))
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s environment variable is not set", keyEnv)
+	}
+	if strings.TrimSpace(req.Host) == "" || req.Port == 0 {
+		return nil, fmt.Errorf("%s requires host and port to be set", name)
+	}
+	scheme := "http"
+	if req.Port == 443 {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s:%d/v1/models", scheme, req.Host, req.Port)
+
+	reqCtx, cancel := withTimeout(ctx, req.Timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: req.Timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return nil, fmt.Errorf("%s error: %s - %s", name, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var decoded struct {
+		Data []struct {
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	out := make([]ModelInfo, 0, len(decoded.Data))
+	for _, m := range decoded.Data {
+		info := ModelInfo{Name: m.ID, OwnedBy: m.OwnedBy}
+		if m.Created > 0 {
+			info.ModifiedAt = time.Unix(m.Created, 0)
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
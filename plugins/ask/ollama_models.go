@@ -0,0 +1,224 @@
+package ask
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ModelDetails is the subset of Ollama /api/show used by `gaia models show`.
+type ModelDetails struct {
+	Name          string
+	Family        string
+	ParameterSize string
+	Quantization  string
+	ContextLength int
+	Parameters    string
+	Template      string
+	Capabilities  []string
+}
+
+// RunningModel is a model currently loaded by Ollama (/api/ps).
+type RunningModel struct {
+	Name      string
+	Size      int64
+	SizeVRAM  int64
+	ExpiresAt time.Time
+}
+
+type ollamaModelDetails struct {
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// ListModels returns the locally available models from /api/tags.
+func (p *OllamaProvider) ListModels(ctx context.Context, req AskRequest) ([]ModelInfo, error) {
+	var decoded struct {
+		Models []struct {
+			Name       string             `json:"name"`
+			Size       int64              `json:"size"`
+			ModifiedAt time.Time          `json:"modified_at"`
+			Details    ollamaModelDetails `json:"details"`
+		} `json:"models"`
+	}
+	if err := ollamaCall(ctx, req, http.MethodGet, "/api/tags", nil, &decoded); err != nil {
+		return nil, err
+	}
+	out := make([]ModelInfo, 0, len(decoded.Models))
+	for _, m := range decoded.Models {
+		out = append(out, ModelInfo{
+			Name:          m.Name,
+			Size:          m.Size,
+			ModifiedAt:    m.ModifiedAt,
+			Family:        m.Details.Family,
+			ParameterSize: m.Details.ParameterSize,
+			Quantization:  m.Details.QuantizationLevel,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// PullModel downloads req.Model, showing the progress bar on req.ProgressOut.
+func (p *OllamaProvider) PullModel(ctx context.Context, req AskRequest) error {
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		return fmt.Errorf("model name is required")
+	}
+	client := &http.Client{Timeout: req.Timeout}
+	return p.pullModel(ctx, client, ollamaBaseURL(req), model, req.ProgressOut, req.ProgressClearer)
+}
+
+// ShowModel returns details, parameters and template for req.Model from /api/show.
+func (p *OllamaProvider) ShowModel(ctx context.Context, req AskRequest) (ModelDetails, error) {
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		return ModelDetails{}, fmt.Errorf("model name is required")
+	}
+	var decoded struct {
+		Parameters   string                     `json:"parameters"`
+		Template     string                     `json:"template"`
+		Details      ollamaModelDetails         `json:"details"`
+		ModelInfo    map[string]json.RawMessage `json:"model_info"`
+		Capabilities []string                   `json:"capabilities"`
+	}
+	if err := ollamaCall(ctx, req, http.MethodPost, "/api/show", map[string]any{"model": model}, &decoded); err != nil {
+		return ModelDetails{}, err
+	}
+	return ModelDetails{
+		Name:          model,
+		Family:        decoded.Details.Family,
+		ParameterSize: decoded.Details.ParameterSize,
+		Quantization:  decoded.Details.QuantizationLevel,
+		ContextLength: contextLengthFromModelInfo(decoded.ModelInfo),
+		Parameters:    strings.TrimSpace(decoded.Parameters),
+		Template:      strings.TrimSpace(decoded.Template),
+		Capabilities:  decoded.Capabilities,
+	}, nil
+}
+
+// DeleteModel removes req.Model via /api/delete.
+func (p *OllamaProvider) DeleteModel(ctx context.Context, req AskRequest) error {
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		return fmt.Errorf("model name is required")
+	}
+	return ollamaCall(ctx, req, http.MethodDelete, "/api/delete", map[string]any{"model": model}, nil)
+}
+
+// RunningModels lists the models currently loaded in memory (/api/ps).
+func (p *OllamaProvider) RunningModels(ctx context.Context, req AskRequest) ([]RunningModel, error) {
+	var decoded struct {
+		Models []struct {
+			Name      string    `json:"name"`
+			Size      int64     `json:"size"`
+			SizeVRAM  int64     `json:"size_vram"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"models"`
+	}
+	if err := ollamaCall(ctx, req, http.MethodGet, "/api/ps", nil, &decoded); err != nil {
+		return nil, err
+	}
+	out := make([]RunningModel, 0, len(decoded.Models))
+	for _, m := range decoded.Models {
+		out = append(out, RunningModel{Name: m.Name, Size: m.Size, SizeVRAM: m.SizeVRAM, ExpiresAt: m.ExpiresAt})
+	}
+	return out, nil
+}
+
+// contextLengthFromModelInfo reads "<architecture>.context_length" from /api/show model_info.
+func contextLengthFromModelInfo(info map[string]json.RawMessage) int {
+	for key, raw := range info {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(raw, &n); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// ollamaDefaultNumCtx is the context Ollama gives a model when neither its Modelfile nor the
+// request sets num_ctx.
+const ollamaDefaultNumCtx = 4096
+
+// numCtxFromParameters reads num_ctx from the parameters of /api/show, one "name value"
+// pair per line. It returns 0 when the Modelfile does not set it.
+func numCtxFromParameters(parameters string) int {
+	for _, line := range strings.Split(parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// ollamaRuntimeContext returns the context Ollama actually runs a model with: num_ctx, or the
+// runtime default, capped by the length the model was trained for.
+func ollamaRuntimeContext(trained, numCtx int) int {
+	n := numCtx
+	if n <= 0 {
+		n = ollamaDefaultNumCtx
+	}
+	if trained > 0 && trained < n {
+		n = trained
+	}
+	return n
+}
+
+func ollamaBaseURL(req AskRequest) string {
+	return fmt.Sprintf("http://%s:%d", req.Host, req.Port)
+}
+
+// ollamaCall sends a JSON request to an Ollama management endpoint and decodes the reply into out.
+func ollamaCall(ctx context.Context, req AskRequest, method, path string, payload any, out any) error {
+	if strings.TrimSpace(req.Host) == "" || req.Port == 0 {
+		return fmt.Errorf("ollama requires host and port to be set")
+	}
+	reqCtx, cancel := withTimeout(ctx, req.Timeout)
+	defer cancel()
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	httpReq, err := http.NewRequestWithContext(reqCtx, method, ollamaBaseURL(req)+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	client := &http.Client{Timeout: req.Timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("ollama error: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
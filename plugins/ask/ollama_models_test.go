@@ -0,0 +1,109 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newModelsTestServer(t *testing.T, deleted *string) AskRequest {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"qwen2.5:14b","size":9000000000,"modified_at":"2026-01-02T03:04:05Z","details":{"family":"qwen2","parameter_size":"14.8B","quantization_level":"Q4_K_M"}},{"name":"llama3:latest","size":4700000000}]}`))
+		case r.URL.Path == "/api/show" && r.Method == http.MethodPost:
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["model"] != "llama3:latest" {
+				http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"parameters":"stop \"<|eot_id|>\"","template":"{{ .Prompt }}","details":{"family":"llama"},"model_info":{"general.architecture":"llama","llama.context_length":8192},"capabilities":["completion","tools"]}`))
+		case r.URL.Path == "/api/delete" && r.Method == http.MethodDelete:
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			*deleted = body["model"]
+		case r.URL.Path == "/api/ps":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3:latest","size":6000000000,"size_vram":3000000000,"expires_at":"2026-01-02T03:09:05Z"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	host, port := parseHostPort(t, srv.URL)
+	return AskRequest{Host: host, Port: port, Timeout: time.Second}
+}
+
+func TestOllamaListModels(t *testing.T) {
+	req := newModelsTestServer(t, new(string))
+	models, err := NewOllamaProvider().ListModels(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "llama3:latest" || models[1].Quantization != "Q4_K_M" {
+		t.Fatalf("models = %+v", models)
+	}
+	if models[1].ModifiedAt.Year() != 2026 || models[1].Size != 9000000000 {
+		t.Errorf("model = %+v", models[1])
+	}
+}
+
+func TestOllamaShowModel(t *testing.T) {
+	req := newModelsTestServer(t, new(string))
+	req.Model = "llama3:latest"
+	details, err := NewOllamaProvider().ShowModel(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ShowModel: %v", err)
+	}
+	if details.ContextLength != 8192 || details.Template != "{{ .Prompt }}" || len(details.Capabilities) != 2 {
+		t.Errorf("details = %+v", details)
+	}
+
+	req.Model = "missing"
+	if _, err := NewOllamaProvider().ShowModel(context.Background(), req); err == nil {
+		t.Error("expected error for unknown model")
+	}
+}
+
+func TestOllamaDeleteAndRunningModels(t *testing.T) {
+	var deleted string
+	req := newModelsTestServer(t, &deleted)
+	req.Model = "llama3:latest"
+	if err := NewOllamaProvider().DeleteModel(context.Background(), req); err != nil {
+		t.Fatalf("DeleteModel: %v", err)
+	}
+	if deleted != "llama3:latest" {
+		t.Errorf("deleted = %q", deleted)
+	}
+	running, err := NewOllamaProvider().RunningModels(context.Background(), req)
+	if err != nil {
+		t.Fatalf("RunningModels: %v", err)
+	}
+	if len(running) != 1 || running[0].SizeVRAM != 3000000000 {
+		t.Errorf("running = %+v", running)
+	}
+}
+
+func TestOpenAIListModels(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer test" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"gpt-4o","created":1715367049,"owned_by":"system"},{"id":"gpt-4o-mini","owned_by":"system"}]}`))
+	}))
+	defer srv.Close()
+	host, port := parseHostPort(t, srv.URL)
+	models, err := NewOpenAIProvider().ListModels(context.Background(), AskRequest{Host: host, Port: port, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "gpt-4o" || models[0].OwnedBy != "system" || models[0].ModifiedAt.IsZero() {
+		t.Errorf("models = %+v", models)
+	}
+}
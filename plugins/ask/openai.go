@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
 type OpenAIProvider struct{}
@@ -18,23 +19,71 @@ func NewOpenAIProvider() *OpenAIProvider { return &OpenAIProvider{} }
 func (p *OpenAIProvider) Name() string { return "openai" }
 
 type openAIChatCompletionRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Stream         bool                  `json:"stream"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Tools          []functionTool        `json:"tools,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// openAIFormat maps a ResponseFormat to OpenAI's response_format (json_schema or json_object).
+func openAIFormat(format *ResponseFormat) *openAIResponseFormat {
+	if format == nil {
+		return nil
+	}
+	if len(format.Schema) > 0 {
+		return &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "response", Schema: format.Schema},
+		}
+	}
+	return &openAIResponseFormat{Type: "json_object"}
 }
 
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []remoteToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+func openAIMessages(messages []ChatMessage) []openAIMessage {
+	out := make([]openAIMessage, 0, len(messages))
+	for _, msg := range messages {
+		out = append(out, openAIMessage{
+			Role:       strings.TrimSpace(msg.Role),
+			Content:    strings.TrimSpace(msg.Content),
+			ToolCalls:  toRemoteToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	return out
 }
 
 type openAIChatCompletionResponse struct {
 	Choices []struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string           `json:"role"`
+			Content   string           `json:"content"`
+			ToolCalls []remoteToolCall `json:"tool_calls"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *remoteUsage `json:"usage"`
 }
 
 func (p *OpenAIProvider) Send(ctx context.Context, req AskRequest) (AskResponse, error) {
@@ -50,22 +99,14 @@ func (p *OpenAIProvider) Send(ctx context.Context, req AskRequest) (AskResponse,
 		scheme = "https"
 	}
 	url := fmt.Sprintf("%s://%s:%d/v1/chat/completions", scheme, req.Host, req.Port)
-
-	rawMessages := buildMessages(req)
-	messages := make([]openAIMessage, 0, len(rawMessages))
-	for _, msg := range rawMessages {
-		role := strings.TrimSpace(msg["role"])
-		content := strings.TrimSpace(msg["content"])
-		if role == "" || content == "" {
-			continue
-		}
-		messages = append(messages, openAIMessage{Role: role, Content: content})
-	}
+	req = fitContext(ctx, req)
 
 	openaiReq := openAIChatCompletionRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   false,
+		Model:          req.Model,
+		Messages:       openAIMessages(buildMessages(req)),
+		Stream:         false,
+		ResponseFormat: openAIFormat(req.Format),
+		Tools:          toolSpecs(req.Tools),
 	}
 	body, err := json.Marshal(openaiReq)
 	if err != nil {
@@ -92,6 +133,11 @@ func (p *OpenAIProvider) Send(ctx context.Context, req AskRequest) (AskResponse,
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		errBody, _ := io.ReadAll(resp.Body)
+		if len(req.Tools) > 0 {
+			if err := toolsUnsupportedError(resp.StatusCode, string(errBody)); err != nil {
+				return AskResponse{}, err
+			}
+		}
 		return AskResponse{}, fmt.Errorf("openai error: %s - %s", resp.Status, strings.TrimSpace(string(errBody)))
 	}
 
@@ -106,10 +152,15 @@ func (p *OpenAIProvider) Send(ctx context.Context, req AskRequest) (AskResponse,
 	if len(openaiResp.Choices) == 0 {
 		return AskResponse{}, fmt.Errorf("openai response has no choices")
 	}
-	return AskResponse{Text: openaiResp.Choices[0].Message.Content}, nil
+	choice := openaiResp.Choices[0].Message
+	text, thinking := SplitThinking(choice.Content)
+	return AskResponse{Text: text, Thinking: thinking, ToolCalls: fromRemoteToolCalls(choice.ToolCalls), Usage: openaiResp.Usage.toUsage()}, nil
 }
 
 func (p *OpenAIProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	if len(req.Tools) > 0 {
+		return sendToolsAsStream(ctx, p, req, onChunk)
+	}
 	apiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
 	if apiKey == "" {
 		return AskResponse{}, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
@@ -122,41 +173,29 @@ func (p *OpenAIProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 		scheme = "https"
 	}
 	url := fmt.Sprintf("%s://%s:%d/v1/chat/completions", scheme, req.Host, req.Port)
-
-	rawMessages := buildMessages(req)
-	messages := make([]openAIMessage, 0, len(rawMessages))
-	for _, msg := range rawMessages {
-		role := strings.TrimSpace(msg["role"])
-		content := strings.TrimSpace(msg["content"])
-		if role == "" || content == "" {
-			continue
-		}
-		messages = append(messages, openAIMessage{Role: role, Content: content})
-	}
+	req = fitContext(ctx, req)
 
 	openaiReq := openAIChatCompletionRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   true,
+		Model:          req.Model,
+		Messages:       openAIMessages(buildMessages(req)),
+		Stream:         true,
+		ResponseFormat: openAIFormat(req.Format),
+		StreamOptions:  &openAIStreamOptions{IncludeUsage: true},
 	}
-	body, err := json.Marshal(openaiReq)
-	if err != nil {
-		return AskResponse{}, err
-	}
-
 	reqCtx, cancel := withTimeout(ctx, req.Timeout)
 	defer cancel()
-	httpReq, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(body))
+	resp, err := postOpenAIStream(reqCtx, url, apiKey, req.Timeout, openaiReq)
 	if err != nil {
 		return AskResponse{}, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{Timeout: req.Timeout}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return AskResponse{}, err
+	if resp.StatusCode == http.StatusBadRequest {
+		// Some OpenAI-compatible servers reject stream_options; usage is optional, so
+		// retry once without it.
+		_ = resp.Body.Close()
+		openaiReq.StreamOptions = nil
+		if resp, err = postOpenAIStream(reqCtx, url, apiKey, req.Timeout, openaiReq); err != nil {
+			return AskResponse{}, err
+		}
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -167,7 +206,7 @@ func (p *OpenAIProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 		return AskResponse{}, fmt.Errorf("openai error: %s - %s", resp.Status, strings.TrimSpace(string(errBody)))
 	}
 
-	var full strings.Builder
+	split := newStreamSplitter(req, onChunk)
 	buf := make([]byte, 4096)
 	leftover := ""
 	for {
@@ -187,7 +226,7 @@ func (p *OpenAIProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 					continue
 				}
 				if line == "data: [DONE]" {
-					return AskResponse{Text: full.String()}, nil
+					return split.Response(), nil
 				}
 				if strings.HasPrefix(line, "data: ") {
 					jsonData := strings.TrimPrefix(line, "data: ")
@@ -197,15 +236,18 @@ func (p *OpenAIProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 								Content string `json:"content"`
 							} `json:"delta"`
 						} `json:"choices"`
+						Usage *remoteUsage `json:"usage"`
 					}
 					if err := json.Unmarshal([]byte(jsonData), &streamResp); err != nil {
 						continue
 					}
+					if streamResp.Usage != nil {
+						split.usage = streamResp.Usage.toUsage()
+					}
 					if len(streamResp.Choices) > 0 {
 						delta := streamResp.Choices[0].Delta.Content
 						if delta != "" {
-							onChunk(delta)
-							full.WriteString(delta)
+							split.Write(delta)
 						}
 					}
 				}
@@ -218,5 +260,21 @@ func (p *OpenAIProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 			return AskResponse{}, err
 		}
 	}
-	return AskResponse{Text: full.String()}, nil
+	return split.Response(), nil
+}
+
+// postOpenAIStream posts a streaming chat completion request.
+func postOpenAIStream(ctx context.Context, url, apiKey string, timeout time.Duration, openaiReq openAIChatCompletionRequest) (*http.Response, error) {
+	body, err := json.Marshal(openaiReq)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	client := &http.Client{Timeout: timeout}
+	return client.Do(httpReq)
 }
@@ -0,0 +1,45 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenAISendStream_RetriesWithoutStreamOptions(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test")
+	var attempts []bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var payload map[string]json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, withOptions := payload["stream_options"]
+		attempts = append(attempts, withOptions)
+		if withOptions {
+			http.Error(w, `{"error":"Unrecognized request argument supplied: stream_options"}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Host: host, Port: port, Model: "local-model", Timeout: time.Second, Message: "hello"}
+	var text string
+	resp, err := NewOpenAIProvider().SendStream(context.Background(), req, func(chunk string) { text += chunk })
+	if err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+	if text != "hi" || resp.Text != "hi" {
+		t.Errorf("streamed %q, response %q", text, resp.Text)
+	}
+	if len(attempts) != 2 || !attempts[0] || attempts[1] {
+		t.Errorf("attempts with stream_options = %v, want [true false]", attempts)
+	}
+}
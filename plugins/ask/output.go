@@ -0,0 +1,170 @@
+package ask
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gaia/config"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+)
+
+// editorHelp is appended to the editor buffer and removed before sending.
+const editorHelp = "<!-- gaia: write your question above. Quoted context (> lines) is sent with it; " +
+	"delete what you do not need. Save and quit to send; an empty message cancels. -->"
+
+// composeInEditor opens $EDITOR on msg followed by each context quoted, and returns the
+// edited message.
+func composeInEditor(msg string, contexts []string) (string, error) {
+	var b strings.Builder
+	b.WriteString(msg)
+	b.WriteString("\n")
+	for _, c := range contexts {
+		fmt.Fprintf(&b, "\n%s\n", quoteText(c))
+	}
+	fmt.Fprintf(&b, "\n%s\n", editorHelp)
+	edited, err := shared.EditText(b.String(), "gaia-ask-*.md")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.ReplaceAll(edited, editorHelp, "")), nil
+}
+
+// quoteText prefixes every line of text with "> ".
+func quoteText(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ">"
+			continue
+		}
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lastAnswerPath() string {
+	return filepath.Join(config.ConfigDir(), "last-answer.md")
+}
+
+// rememberAnswer keeps the answer for ask --quote-last. It is best effort.
+func rememberAnswer(answer string) {
+	path := lastAnswerPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, []byte(answer), 0o600)
+}
+
+// lastAnswer returns the previous answer of ask.
+func lastAnswer() (string, error) {
+	data, err := os.ReadFile(lastAnswerPath())
+	if errors.Is(err, os.ErrNotExist) || (err == nil && strings.TrimSpace(string(data)) == "") {
+		return "", errors.New("no previous answer to quote")
+	}
+	return string(data), err
+}
+
+// editorMessage builds the message of ask --editor from the arguments, piped stdin and,
+// with --quote-last, the previous answer.
+func editorMessage(cmd *cobra.Command, msg string) (string, error) {
+	var contexts []string
+	if stdin := strings.TrimSpace(readStdin(cmd.InOrStdin())); stdin != "" {
+		contexts = append(contexts, stdin)
+	}
+	if quote, _ := cmd.Flags().GetBool("quote-last"); quote {
+		last, err := lastAnswer()
+		if err != nil {
+			return "", err
+		}
+		contexts = append(contexts, last)
+	}
+	return composeInEditor(msg, contexts)
+}
+
+// checkOutputFile confirms overwriting an existing --output-file before the request is sent.
+func checkOutputFile(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("output-file")
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	return confirmOverwrite(cmd, path)
+}
+
+// confirmOverwrite asks before an existing file is replaced, unless --force is given.
+func confirmOverwrite(cmd *cobra.Command, path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		return nil
+	}
+	if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
+		return fmt.Errorf("%s already exists (pass --force to overwrite)", path)
+	}
+	ok, err := shared.RunConfirmationPromptTUI(fmt.Sprintf("Overwrite %s?", path), "Output file", cmd.InOrStdin(), cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("not overwriting %s", path)
+	}
+	return nil
+}
+
+// writeOutputFile writes the answer, or with --code-only its code blocks, to --output-file.
+func writeOutputFile(cmd *cobra.Command, answer string) error {
+	path, _ := cmd.Flags().GetString("output-file")
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	content := answer
+	if codeOnly, _ := cmd.Flags().GetBool("code-only"); codeOnly {
+		blocks := shared.ExtractCodeBlocks(answer)
+		if len(blocks) == 0 {
+			return errors.New("the answer has no code blocks")
+		}
+		content = shared.JoinCode(blocks)
+	}
+	return writeFile(path, strings.TrimRight(content, "\n")+"\n")
+}
+
+// finishAnswer runs what follows every displayed answer, fresh or cached: --output-file,
+// --save, code extraction, and keeping the answer for --quote-last. usage is nil for
+// cached answers.
+func finishAnswer(cmd *cobra.Command, req AskRequest, msg, answer string, usage *Usage) error {
+	rememberAnswer(answer)
+	if err := writeOutputFile(cmd, answer); err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Writing the output file failed: %v", err))
+	}
+	if err := saveAnswer(cmd, req, msg, answer, usage); err != nil {
+		return err
+	}
+	if !extracting(cmd) {
+		return nil
+	}
+	if err := extractCode(cmd, cmd.OutOrStdout(), answer); err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	return nil
+}
+
+// answerWriter is where the answer is displayed: stderr when only its code goes to stdout.
+func answerWriter(cmd *cobra.Command) io.Writer {
+	if extracting(cmd) {
+		return cmd.ErrOrStderr()
+	}
+	return cmd.OutOrStdout()
+}
@@ -0,0 +1,87 @@
+package ask
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newOutputCmd(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output-file", "", "")
+	cmd.Flags().Bool("code-only", false, "")
+	cmd.Flags().Bool("force", false, "")
+	cmd.Flags().String("save", "", "")
+	if err := cmd.Flags().Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestComposeInEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "true")
+	got, err := composeInEditor("Why does this fail?", []string{"panic: boom\n\ngoroutine 1"})
+	if err != nil {
+		t.Fatalf("composeInEditor: %v", err)
+	}
+	want := "Why does this fail?\n\n> panic: boom\n>\n> goroutine 1"
+	if got != want {
+		t.Errorf("composeInEditor = %q, want %q", got, want)
+	}
+}
+
+func TestLastAnswer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := lastAnswer(); err == nil {
+		t.Errorf("lastAnswer without a previous answer should fail")
+	}
+	rememberAnswer("42")
+	if got, err := lastAnswer(); err != nil || got != "42" {
+		t.Errorf("lastAnswer = %q, %v", got, err)
+	}
+}
+
+func TestWriteOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	answer := "Here:\n\n```go\nfmt.Println(1)\n```\n\nand\n\n```go\nfmt.Println(2)\n```"
+	path := filepath.Join(dir, "out", "main.go")
+	if err := writeOutputFile(newOutputCmd(t, "--output-file", path, "--code-only"), answer); err != nil {
+		t.Fatalf("writeOutputFile: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "fmt.Println(1)\n\nfmt.Println(2)\n" {
+		t.Errorf("code-only output = %q", data)
+	}
+	if err := writeOutputFile(newOutputCmd(t, "--output-file", path, "--code-only"), "no code"); err == nil {
+		t.Errorf("--code-only without code blocks should fail")
+	}
+
+	// Tests run without a TTY, so an existing file needs --force.
+	if err := checkOutputFile(newOutputCmd(t, "--output-file", path)); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("existing file without --force: err = %v", err)
+	}
+	if err := checkOutputFile(newOutputCmd(t, "--output-file", path, "--force")); err != nil {
+		t.Errorf("existing file with --force: %v", err)
+	}
+	if err := checkOutputFile(newOutputCmd(t, "--output-file", filepath.Join(dir, "new.md"))); err != nil {
+		t.Errorf("new file: %v", err)
+	}
+}
+
+func TestSaveAnswerRecordsResolvedRole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answer.json")
+	// No --role was given; the role was picked by auto-selection.
+	req := AskRequest{Provider: "ollama", Model: "llama3", Role: "code"}
+	if err := saveAnswer(newOutputCmd(t, "--save", path), req, "why?", "because", &Usage{}); err != nil {
+		t.Fatalf("saveAnswer: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"role": "code"`) {
+		t.Errorf("the saved answer should record the resolved role:\n%s", data)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +20,7 @@ import (
 	"gaia/plugins/mempalace"
 	"gaia/plugins/roles"
 	"gaia/plugins/shared"
+	"gaia/plugins/shared/export"
 	sanitizepkg "gaia/plugins/shared/sanitize"
 
 	"github.com/spf13/cobra"
@@ -49,6 +52,9 @@ func (p *AskPlugin) ConfigSchema() []string {
 		"ask.model",
 		"ask.timeout_seconds",
 		"ask.role",
+		"ask.json_retries",
+		"ask.endpoints.*",
+		"ask.limits.*",
 	}
 }
 
@@ -68,7 +74,20 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 		Args:  cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			msg := strings.TrimSpace(strings.Join(args, " "))
-			if msg == "" {
+			useEditor, _ := cmd.Flags().GetBool("editor")
+			if quote, _ := cmd.Flags().GetBool("quote-last"); quote {
+				useEditor = true
+			}
+			if useEditor {
+				edited, err := editorMessage(cmd, msg)
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				if edited == "" {
+					return shared.PrintError(cmd.ErrOrStderr(), "Empty message, nothing sent.")
+				}
+				msg = edited
+			} else if msg == "" {
 				msg = strings.TrimSpace(readStdin(cmd.InOrStdin()))
 			}
 			if msg == "" {
@@ -108,13 +127,28 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 				req.SystemPrompt = mempalace.AppendMemory(req.SystemPrompt, memCtx)
 			}
 
-			provider, ok := p.providers[req.Provider]
-			if !ok {
-				if fallback, hasFallback := p.providers["ollama"]; hasFallback {
-					provider = fallback
-				} else {
-					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Unknown provider %q", req.Provider))
+			provider, err := SelectProvider(p.providers, req.Provider)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+
+			if specs, _ := cmd.Flags().GetStringSlice("compare"); len(specs) > 0 {
+				if err := checkCompareFlags(cmd); err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
 				}
+				return p.runCompare(cmd, req, specs, msg)
+			}
+			if err := checkOutputFile(cmd); err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if err := validateExtract(cmd); err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+
+			if structured, err := structuredFormat(cmd); err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			} else if structured != nil {
+				return runStructured(cmd, provider, req, structured, msg)
 			}
 
 			noCache, _ := cmd.Flags().GetBool("no-cache")
@@ -131,30 +165,54 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 			if canWrite {
 				label := BuildLabel("ask", msg)
 				keyPayload := cache.KeyPayload{
-					PluginID: "ask",
-					Provider: provider.Name(),
-					Host:     req.Host,
-					Port:     req.Port,
-					Model:    req.Model,
-					Messages: []cache.Message{{Role: "user", Content: msg}},
-					Label:    label,
+					PluginID:     "ask",
+					Provider:     provider.Name(),
+					Host:         req.Host,
+					Port:         req.Port,
+					Model:        req.Model,
+					Role:         req.Role,
+					SystemPrompt: req.SystemPrompt,
+					Params:       CacheParams(req),
+					Messages:     []cache.Message{{Role: "user", Content: msg}},
+					Label:        label,
 				}
 				key, err := cache.BuildKey(keyPayload)
 				if err == nil {
 					cacheKey = key
 					if canRead {
 						if entry, ok, err := cache.Get(cacheKey); err == nil && ok {
-							return shared.PrintBox(cmd.OutOrStdout(), "Answer", entry.Response)
+							if err := shared.PrintAnswer(answerWriter(cmd), "Answer", entry.Response, viper.GetBool("raw")); err != nil {
+								return err
+							}
+							return finishAnswer(cmd, req, msg, entry.Response, nil)
+						}
+					}
+				}
+			}
+			noSemantic, _ := cmd.Flags().GetBool("no-semantic")
+			var promptEmb *promptEmbedding
+			if canWrite && cacheKey != "" && cache.SemanticEnabled() && !noSemantic {
+				if promptEmb, err = p.embedPrompt(cmd.Context(), msg); err != nil {
+					debugSemantic(cmd.ErrOrStderr(), err)
+				} else if canRead {
+					if entry, score, ok := semanticLookup(provider.Name(), req, promptEmb); ok {
+						if err := shared.PrintAnswer(answerWriter(cmd), semanticTitle(score), entry.Response, viper.GetBool("raw")); err != nil {
+							return err
 						}
+						return finishAnswer(cmd, req, msg, entry.Response, nil)
 					}
 				}
 			}
 
 			sreq := ApplySanitize(cmd.ErrOrStderr(), req)
-			finalText, err := shared.DisplayStreamedAnswer(cmd.Context(), cmd.OutOrStdout(), "Answer", func(send func(string)) (string, error) {
+			showThinking, _ := cmd.Flags().GetBool("show-thinking")
+			streamOpts := shared.StreamOptions{ShowThinking: showThinking, Markdown: !viper.GetBool("raw"), ThinkingOut: cmd.ErrOrStderr()}
+			var usage Usage
+			finalText, err := shared.DisplayStreamedAnswerWithOptions(cmd.Context(), answerWriter(cmd), "Answer", streamOpts, func(ctx context.Context, send, think func(string)) (string, error) {
+				sreq.OnThinking = think
 				var streamed strings.Builder
 				cleared := false
-				resp, streamErr := provider.SendStream(cmd.Context(), sreq, func(chunk string) {
+				resp, streamErr := provider.SendStream(ctx, sreq, func(chunk string) {
 					if strings.TrimSpace(chunk) == "" {
 						return
 					}
@@ -171,8 +229,13 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 				if resp.Text == "" {
 					resp.Text = streamed.String()
 				}
+				usage = resp.Usage
 				return resp.Text, nil
 			})
+			if errors.Is(err, shared.ErrStreamInterrupted) {
+				// The partial answer is already on screen; never cache or persist it.
+				return nil
+			}
 			if err != nil {
 				return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Ask failed: %v", err))
 			}
@@ -181,17 +244,23 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 			}
 			if canWrite && cacheKey != "" {
 				_ = cache.Set(cache.Entry{
-					Key:       cacheKey,
-					Label:     BuildLabel("ask", msg),
-					PluginID:  "ask",
-					Provider:  provider.Name(),
-					Host:      req.Host,
-					Port:      req.Port,
-					Model:     req.Model,
-					Messages:  []cache.Message{{Role: "user", Content: msg}},
-					Response:  finalText,
-					CreatedAt: time.Now().UTC(),
+					Key:        cacheKey,
+					Label:      BuildLabel("ask", msg),
+					PluginID:   "ask",
+					Provider:   provider.Name(),
+					Host:       req.Host,
+					Port:       req.Port,
+					Model:      req.Model,
+					Role:       req.Role,
+					SystemHash: cache.SystemHash(req.SystemPrompt),
+					ParamsHash: cache.ParamsHash(CacheParams(req)),
+					Messages:   []cache.Message{{Role: "user", Content: msg}},
+					Response:   finalText,
+					CreatedAt:  time.Now().UTC(),
 				})
+				if promptEmb != nil {
+					_ = cache.SetEmbedding(cacheKey, promptEmb.model, promptEmb.vector)
+				}
 			}
 			if err := mempalace.PersistAskResponse(cmd.Context(), msg, finalText); err != nil && viper.GetBool("debug") {
 				_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
@@ -199,7 +268,7 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 			if err := mempalace.DiaryWriteIfEnabled(cmd.Context(), msg, finalText); err != nil && viper.GetBool("debug") {
 				_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace diary write failed: %v\n", err))
 			}
-			return nil
+			return finishAnswer(cmd, sreq, msg, finalText, &usage)
 		},
 	}
 
@@ -209,41 +278,133 @@ func (p *AskPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 	cmd.Flags().String("model", "", "Model name (overrides ask.model)")
 	cmd.Flags().Int("timeout", 0, "Request timeout in seconds (overrides ask.timeout_seconds)")
 	cmd.Flags().Bool("no-cache", false, "Disable cache for this request")
+	cmd.Flags().Bool("no-semantic", false, "Only reuse cached answers to the exact same prompt")
+	cmd.Flags().StringSlice("compare", nil, "Run the prompt against several models side by side (model or provider:model, comma-separated)")
+	cmd.Flags().String("judge", "", "With --compare, a model (or provider:model) that scores the answers")
+	cmd.Flags().Bool("show-thinking", false, "Show model reasoning in a dimmed pane above the answer")
+	cmd.Flags().String("save", "", "Also write the question and answer to a file (.md, .json or .html)")
+	cmd.Flags().Bool("editor", false, "Compose the message in $EDITOR (arguments and piped stdin are pre-filled)")
+	cmd.Flags().Bool("quote-last", false, "Pre-fill the editor with the previous answer as quoted context (implies --editor)")
+	cmd.Flags().String("output-file", "", "Write the final answer to a file")
+	cmd.Flags().Bool("code-only", false, "With --output-file, write only the code blocks of the answer")
+	cmd.Flags().Bool("force", false, "Overwrite --output-file or --write, or --apply, without asking")
+	cmd.Flags().String("extract", "", "Print only part of the answer: \"code\" prints its fenced code blocks")
+	cmd.Flags().String("lang", "", "With code extraction, keep only blocks in these languages (comma-separated)")
+	cmd.Flags().Int("block", 0, "With code extraction, keep only the Nth matching block (1-based)")
+	cmd.Flags().String("write", "", "Write the extracted code to a file (implies --extract code)")
+	cmd.Flags().String("apply", "", "Show a diff of the extracted code against a file and write it after approval (implies --extract code)")
 	cmd.Flags().Bool("refresh-cache", false, "Refresh cache for this request")
 	cmd.Flags().String("role", "", "Role name to apply to the request")
 	cmd.Flags().Bool("pull", false, "Pull model from Ollama if available (force refresh)")
+	cmd.Flags().Bool("json", false, "Request JSON output and print only the validated JSON")
+	cmd.Flags().String("schema", "", "Path to a JSON Schema the reply must satisfy (implies --json)")
+	cmd.Flags().Int("json-retries", DefaultJSONRetries, "Corrective retries when the JSON reply is invalid (overrides ask.json_retries)")
 
+	_ = viper.BindPFlag("ask.provider", cmd.Flags().Lookup("provider"))
 	_ = viper.BindPFlag("ask.host", cmd.Flags().Lookup("host"))
 	_ = viper.BindPFlag("ask.port", cmd.Flags().Lookup("port"))
 	_ = viper.BindPFlag("ask.model", cmd.Flags().Lookup("model"))
 	_ = viper.BindPFlag("ask.timeout_seconds", cmd.Flags().Lookup("timeout"))
 	_ = viper.BindPFlag("cache.refresh", cmd.Flags().Lookup("refresh-cache"))
 	_ = viper.BindPFlag("ask.role", cmd.Flags().Lookup("role"))
+	_ = viper.BindPFlag("ask.json_retries", cmd.Flags().Lookup("json-retries"))
 
 	return []*cobra.Command{cmd}, nil
 }
 
+// structuredFormat returns the requested JSON format from --json/--schema, or nil for plain text.
+func structuredFormat(cmd *cobra.Command) (*ResponseFormat, error) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	schemaPath, _ := cmd.Flags().GetString("schema")
+	if strings.TrimSpace(schemaPath) == "" {
+		if !asJSON {
+			return nil, nil
+		}
+		return &ResponseFormat{}, nil
+	}
+	schema, err := LoadSchema(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseFormat{Schema: schema}, nil
+}
+
+// runStructured sends req in JSON mode and prints only the validated JSON to stdout.
+// Structured replies bypass the answer panel and the cache.
+func runStructured(cmd *cobra.Command, provider Provider, req AskRequest, format *ResponseFormat, msg string) error {
+	retries := viper.GetInt("ask.json_retries")
+	req.Format = format
+	sreq := ApplySanitize(cmd.ErrOrStderr(), req)
+	out, err := SendStructured(cmd.Context(), provider, sreq, retries)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Ask failed: %v", err))
+	}
+	if err := shared.PrintRaw(cmd.OutOrStdout(), out+"\n"); err != nil {
+		return err
+	}
+	if err := writeOutputFile(cmd, out); err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Writing the output file failed: %v", err))
+	}
+	if err := mempalace.PersistAskResponse(cmd.Context(), msg, out); err != nil && viper.GetBool("debug") {
+		_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
+	}
+	return nil
+}
+
 type AskRequest struct {
-	Provider        string
-	Host            string
-	Port            int
-	Model           string
-	Timeout         time.Duration
-	SystemPrompt    string
+	Provider     string
+	Host         string
+	Port         int
+	Model        string
+	Timeout      time.Duration
+	SystemPrompt string
+	// Role is the name of the role that produced SystemPrompt, if any.
+	Role            string
 	Message         string
 	Messages        []ChatMessage
+	Format          *ResponseFormat
+	Tools           []ToolDefinition
 	Pull            bool
 	ProgressOut     io.Writer
 	ProgressClearer *shared.ProgressClearer
+	// OnThinking receives streamed reasoning; it is never part of the answer text.
+	OnThinking func(string)
 }
 
 type AskResponse struct {
-	Text string
+	Text      string
+	Thinking  string
+	ToolCalls []ToolCall
+	Usage     Usage
+}
+
+// Usage is the token count reported by the provider; zero when it reports none.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// remoteUsage is the OpenAI-compatible usage object.
+type remoteUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *remoteUsage) toUsage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
 }
 
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls holds the calls requested by an assistant message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID and Name identify the call a "tool" message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
 }
 
 type Provider interface {
@@ -262,14 +423,18 @@ func (p *OllamaProvider) Send(ctx context.Context, req AskRequest) (AskResponse,
 	if err := p.ensureModel(ctx, req); err != nil {
 		return AskResponse{}, err
 	}
+	req = fitContext(ctx, req)
 	reqCtx, cancel := withTimeout(ctx, req.Timeout)
 	defer cancel()
 	url := fmt.Sprintf("http://%s:%d/api/chat", req.Host, req.Port)
-	messages := buildMessages(req)
 	payload := map[string]any{
 		"model":    req.Model,
 		"stream":   false,
-		"messages": messages,
+		"messages": ollamaMessages(buildMessages(req)),
+	}
+	applyOllamaFormat(payload, req.Format)
+	if tools := toolSpecs(req.Tools); tools != nil {
+		payload["tools"] = tools
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -292,32 +457,52 @@ func (p *OllamaProvider) Send(ctx context.Context, req AskRequest) (AskResponse,
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		if len(req.Tools) > 0 {
+			if err := toolsUnsupportedError(resp.StatusCode, string(b)); err != nil {
+				return AskResponse{}, err
+			}
+		}
 		return AskResponse{}, fmt.Errorf("ollama error: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(b)))
 	}
 
 	var decoded struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string           `json:"content"`
+			Thinking  string           `json:"thinking"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
 		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
 		return AskResponse{}, err
 	}
-	return AskResponse{Text: decoded.Message.Content}, nil
+	split := newThinkSplitter(nil, nil)
+	split.Thinking(decoded.Message.Thinking)
+	split.Write(decoded.Message.Content)
+	out := split.Response()
+	out.ToolCalls = fromOllamaToolCalls(decoded.Message.ToolCalls)
+	out.Usage = Usage{PromptTokens: decoded.PromptEvalCount, CompletionTokens: decoded.EvalCount}
+	return out, nil
 }
 
 func (p *OllamaProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	if len(req.Tools) > 0 {
+		return sendToolsAsStream(ctx, p, req, onChunk)
+	}
 	if err := p.ensureModel(ctx, req); err != nil {
 		return AskResponse{}, err
 	}
+	req = fitContext(ctx, req)
 	reqCtx, cancel := withTimeout(ctx, req.Timeout)
 	defer cancel()
 	url := fmt.Sprintf("http://%s:%d/api/chat", req.Host, req.Port)
 	payload := map[string]any{
 		"model":    req.Model,
 		"stream":   true,
-		"messages": buildMessages(req),
+		"messages": ollamaMessages(buildMessages(req)),
 	}
+	applyOllamaFormat(payload, req.Format)
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return AskResponse{}, err
@@ -342,14 +527,17 @@ func (p *OllamaProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 		return AskResponse{}, fmt.Errorf("ollama error: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(b)))
 	}
 
-	var full strings.Builder
+	split := newStreamSplitter(req, onChunk)
 	decoder := json.NewDecoder(resp.Body)
 	for {
 		var chunk struct {
 			Message struct {
-				Content string `json:"content"`
+				Content  string `json:"content"`
+				Thinking string `json:"thinking"`
 			} `json:"message"`
-			Done bool `json:"done"`
+			Done            bool `json:"done"`
+			PromptEvalCount int  `json:"prompt_eval_count"`
+			EvalCount       int  `json:"eval_count"`
 		}
 		if err := decoder.Decode(&chunk); err != nil {
 			if err == io.EOF {
@@ -357,15 +545,73 @@ func (p *OllamaProvider) SendStream(ctx context.Context, req AskRequest, onChunk
 			}
 			return AskResponse{}, err
 		}
+		split.Thinking(chunk.Message.Thinking)
 		if chunk.Message.Content != "" {
-			onChunk(chunk.Message.Content)
-			full.WriteString(chunk.Message.Content)
+			split.Write(chunk.Message.Content)
 		}
 		if chunk.Done {
+			split.usage = Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
 			break
 		}
 	}
-	return AskResponse{Text: full.String()}, nil
+	return split.Response(), nil
+}
+
+// applyOllamaFormat sets the Ollama "format" field: a JSON Schema when given, else "json".
+func applyOllamaFormat(payload map[string]any, format *ResponseFormat) {
+	if format == nil {
+		return
+	}
+	if len(format.Schema) > 0 {
+		payload["format"] = format.Schema
+		return
+	}
+	payload["format"] = "json"
+}
+
+// ollamaToolCall is Ollama's tool_calls entry; arguments is a JSON object and there is no call ID.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+func fromOllamaToolCalls(calls []ollamaToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, 0, len(calls))
+	for i, c := range calls {
+		out = append(out, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i),
+			Name:      c.Function.Name,
+			Arguments: normalizeArguments(c.Function.Arguments),
+		})
+	}
+	return out
+}
+
+func ollamaMessages(messages []ChatMessage) []map[string]any {
+	out := make([]map[string]any, 0, len(messages))
+	for _, m := range messages {
+		msg := map[string]any{"role": m.Role, "content": m.Content}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ollamaToolCall, 0, len(m.ToolCalls))
+			for _, c := range m.ToolCalls {
+				var oc ollamaToolCall
+				oc.Function.Name = c.Name
+				oc.Function.Arguments = normalizeArguments(c.Arguments)
+				calls = append(calls, oc)
+			}
+			msg["tool_calls"] = calls
+		}
+		if m.Role == "tool" && m.Name != "" {
+			msg["tool_name"] = m.Name
+		}
+		out = append(out, msg)
+	}
+	return out
 }
 
 func (p *OllamaProvider) ensureModel(ctx context.Context, req AskRequest) error {
@@ -538,11 +784,13 @@ func validateAskConfig(req AskRequest) error {
 	if strings.TrimSpace(req.Provider) == "" {
 		missing = append(missing, "ask.provider")
 	}
-	if strings.TrimSpace(req.Host) == "" {
-		missing = append(missing, "ask.host")
-	}
-	if req.Port == 0 {
-		missing = append(missing, "ask.port")
+	if NeedsEndpoint(req.Provider) {
+		if strings.TrimSpace(req.Host) == "" {
+			missing = append(missing, "ask.host")
+		}
+		if req.Port == 0 {
+			missing = append(missing, "ask.port")
+		}
 	}
 	if strings.TrimSpace(req.Model) == "" {
 		missing = append(missing, "ask.model")
@@ -578,26 +826,37 @@ func BuildLabel(pluginID, message string) string {
 	return label
 }
 
-func buildMessages(req AskRequest) []map[string]string {
-	out := []map[string]string{}
+// buildMessages returns the conversation to send. Messages without content are dropped
+// unless they carry tool calls or answer one.
+func buildMessages(req AskRequest) []ChatMessage {
+	out := []ChatMessage{}
 	if strings.TrimSpace(req.SystemPrompt) != "" {
-		out = append(out, map[string]string{"role": "system", "content": req.SystemPrompt})
+		out = append(out, ChatMessage{Role: "system", Content: req.SystemPrompt})
 	}
 	if len(req.Messages) > 0 {
 		for _, msg := range req.Messages {
-			if strings.TrimSpace(msg.Role) == "" || strings.TrimSpace(msg.Content) == "" {
-				continue
+			if keepMessage(msg) {
+				out = append(out, msg)
 			}
-			out = append(out, map[string]string{"role": msg.Role, "content": msg.Content})
 		}
 		return out
 	}
 	if strings.TrimSpace(req.Message) != "" {
-		out = append(out, map[string]string{"role": "user", "content": req.Message})
+		out = append(out, ChatMessage{Role: "user", Content: req.Message})
 	}
 	return out
 }
 
+func keepMessage(msg ChatMessage) bool {
+	if strings.TrimSpace(msg.Role) == "" {
+		return false
+	}
+	if strings.TrimSpace(msg.Content) != "" {
+		return true
+	}
+	return len(msg.ToolCalls) > 0 || msg.Role == "tool"
+}
+
 func FirstNonEmpty(primary, fallback string) string {
 	if strings.TrimSpace(primary) != "" {
 		return primary
@@ -629,6 +888,52 @@ func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, c
 	return context.WithTimeout(ctx, timeout)
 }
 
+// saveAnswer writes the exchange to the --save path, if any. usage is nil for cached answers.
+func saveAnswer(cmd *cobra.Command, req AskRequest, msg, answer string, usage *Usage) error {
+	path, _ := cmd.Flags().GetString("save")
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	firstLine, _, _ := strings.Cut(msg, "\n")
+	doc := export.Document{
+		Title:     BuildLabel("ask", firstLine),
+		Source:    "ask",
+		Provider:  req.Provider,
+		Model:     req.Model,
+		Role:      req.Role,
+		CreatedAt: time.Now().UTC(),
+		Messages:  []export.Message{{Role: "user", Content: msg}, {Role: "assistant", Content: answer}},
+	}
+	if usage == nil {
+		doc.Metadata = map[string]string{"Cache": "hit"}
+	} else if *usage != (Usage{}) {
+		doc.Usage = &export.Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+	} else {
+		doc.Usage = &export.Usage{PromptTokens: estimatePromptTokens(req), CompletionTokens: sanitizepkg.EstimateTokens(answer), Estimated: true}
+	}
+	if err := export.WriteFile(path, doc, ""); err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Saving the answer failed: %v", err))
+	}
+	return nil
+}
+
+// CacheParams returns the settings that change what the model receives beyond the messages
+// and system prompt, for the cache key.
+func CacheParams(req AskRequest) map[string]string {
+	params := map[string]string{}
+	if viper.GetBool("sanitize.enabled") {
+		params["sanitize.level"] = strings.ToLower(strings.TrimSpace(viper.GetString("sanitize.level")))
+		params["sanitize.max_tokens_after"] = strconv.Itoa(viper.GetInt("sanitize.max_tokens_after"))
+	}
+	if req.Format != nil {
+		params["format"] = FirstNonEmpty(string(req.Format.Schema), "json")
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
 // ApplySanitize sanitizes the conversation messages in req based on sanitize config.
 // errOut receives debug log lines; pass cmd.ErrOrStderr() from callers.
 func ApplySanitize(errOut io.Writer, req AskRequest) AskRequest {
@@ -652,7 +957,15 @@ func ApplySanitize(errOut io.Writer, req AskRequest) AskRequest {
 		PreserveLastUser:  true,
 		MaxDurationMillis: 100,
 	}
-	raw := buildMessagesForSanitize(req)
+	source := sanitizeSource(req)
+	if hasToolMetadata(source) {
+		// Tool calls must stay paired with their results, so only contents are cleaned.
+		opts.MaxTokensAfter = 0
+	}
+	raw := make([]sanitizepkg.Message, 0, len(source))
+	for _, m := range source {
+		raw = append(raw, sanitizepkg.Message{Role: m.Role, Content: m.Content})
+	}
 	out, stats, err := sanitizepkg.Sanitize(sanitizepkg.Request{Messages: raw}, opts)
 	if err != nil {
 		if viper.GetBool("debug") {
@@ -665,28 +978,53 @@ func ApplySanitize(errOut io.Writer, req AskRequest) AskRequest {
 			fmt.Sprintf("[sanitize] tokens before=%d after=%d removed≈%d ms=%d\n",
 				stats.TokensBefore, stats.TokensAfter, stats.RemovedCount, stats.DurationMillis))
 	}
+	messages, ok := mergeSanitized(source, out.Messages)
+	if !ok {
+		if viper.GetBool("debug") {
+			_ = shared.PrintRaw(errOut, "[DEBUG] sanitize: dropped messages of a tool conversation; sending it unsanitized\n")
+		}
+		return req
+	}
 	req.SystemPrompt = ""
 	req.Message = ""
-	req.Messages = make([]ChatMessage, 0, len(out.Messages))
-	for _, m := range out.Messages {
-		req.Messages = append(req.Messages, ChatMessage{Role: m.Role, Content: m.Content})
-	}
+	req.Messages = messages
 	return req
 }
 
-func buildMessagesForSanitize(req AskRequest) []sanitizepkg.Message {
-	out := []sanitizepkg.Message{}
+// mergeSanitized applies the sanitized contents to the source messages, keeping their tool
+// calls, call IDs and names. It reports false when sanitization dropped messages from a
+// conversation with tool metadata, which cannot be realigned without unpairing tool calls
+// from their results.
+func mergeSanitized(source []ChatMessage, sanitized []sanitizepkg.Message) ([]ChatMessage, bool) {
+	aligned := len(sanitized) == len(source)
+	if !aligned && hasToolMetadata(source) {
+		return nil, false
+	}
+	out := make([]ChatMessage, 0, len(sanitized))
+	for i, m := range sanitized {
+		msg := ChatMessage{Role: m.Role, Content: m.Content}
+		if aligned {
+			msg = source[i]
+			msg.Content = m.Content
+		}
+		out = append(out, msg)
+	}
+	return out, true
+}
+
+// sanitizeSource flattens the system prompt, history and message into one conversation.
+func sanitizeSource(req AskRequest) []ChatMessage {
+	out := []ChatMessage{}
 	if strings.TrimSpace(req.SystemPrompt) != "" {
-		out = append(out, sanitizepkg.Message{Role: "system", Content: req.SystemPrompt})
+		out = append(out, ChatMessage{Role: "system", Content: req.SystemPrompt})
 	}
 	for _, m := range req.Messages {
-		if strings.TrimSpace(m.Role) == "" || strings.TrimSpace(m.Content) == "" {
-			continue
+		if keepMessage(m) {
+			out = append(out, m)
 		}
-		out = append(out, sanitizepkg.Message{Role: m.Role, Content: m.Content})
 	}
 	if strings.TrimSpace(req.Message) != "" {
-		out = append(out, sanitizepkg.Message{Role: "user", Content: req.Message})
+		out = append(out, ChatMessage{Role: "user", Content: req.Message})
 	}
 	return out
 }
@@ -738,5 +1076,6 @@ func applyRolePrompt(cmd *cobra.Command, req *AskRequest, msg string) error {
 		return fmt.Errorf("role %q not found", roleName)
 	}
 	req.SystemPrompt = roles.ResolveSystemPrompt(role, req.Provider, req.Model)
+	req.Role = roleName
 	return nil
 }
@@ -0,0 +1,333 @@
+package ask
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ReplayProviderName selects the fixture-backed provider (provider: replay).
+const ReplayProviderName = "replay"
+
+// ErrFixtureNotFound is returned by the replay provider when no fixture matches a request.
+var ErrFixtureNotFound = errors.New("replay fixture not found")
+
+// Fixture is one recorded request/response pair.
+type Fixture struct {
+	Key        string         `json:"key"`
+	Provider   string         `json:"provider"`
+	Request    FixtureRequest `json:"request"`
+	Text       string         `json:"text"`
+	Thinking   string         `json:"thinking,omitempty"`
+	ToolCalls  []ToolCall     `json:"tool_calls,omitempty"`
+	Chunks     []FixtureChunk `json:"chunks,omitempty"`
+	Embeddings [][]float32    `json:"embeddings,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+	RecordedAt time.Time      `json:"recorded_at"`
+}
+
+// FixtureChunk is a streamed chunk and its offset from the start of the request.
+type FixtureChunk struct {
+	OffsetMS int64  `json:"offset_ms"`
+	Text     string `json:"text"`
+}
+
+// FixtureRequest is the normalized request a fixture key is derived from. Endpoint,
+// timeouts and provider name are left out so fixtures replay under provider: replay.
+type FixtureRequest struct {
+	Model    string           `json:"model"`
+	Messages []ChatMessage    `json:"messages"`
+	Tools    []FixtureTool    `json:"tools,omitempty"`
+	Format   *json.RawMessage `json:"format,omitempty"`
+	Inputs   []string         `json:"inputs,omitempty"` // texts of an embedding request
+}
+
+// FixtureTool is a tool definition as it enters the fixture key.
+type FixtureTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// SelectProvider returns the provider named name, falling back to ollama for unknown names,
+// with its ask.limits applied. provider: replay serves fixtures from fixtures_dir, and
+// record: true wraps the selected provider so every exchange is written there.
+func SelectProvider(providers map[string]Provider, name string) (Provider, error) {
+	if name == ReplayProviderName {
+		dir, err := fixturesDir()
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayProvider(dir), nil
+	}
+	provider, ok := providers[name]
+	if !ok {
+		fallback, hasFallback := providers["ollama"]
+		if !hasFallback {
+			return nil, fmt.Errorf("unknown provider %q", name)
+		}
+		provider = fallback
+	}
+	provider = WithLimits(provider)
+	if viper.GetBool("record") {
+		dir, err := fixturesDir()
+		if err != nil {
+			return nil, err
+		}
+		return NewRecordingProvider(provider, dir), nil
+	}
+	return provider, nil
+}
+
+// NeedsEndpoint reports whether provider requires host and port to be configured.
+func NeedsEndpoint(provider string) bool {
+	return provider != ReplayProviderName
+}
+
+func fixturesDir() (string, error) {
+	dir := strings.TrimSpace(viper.GetString("fixtures_dir"))
+	if dir == "" {
+		return "", fmt.Errorf("fixtures_dir is required for record and replay")
+	}
+	return dir, nil
+}
+
+// FixtureKey hashes the normalized request: model, conversation, tools and response format.
+// It also returns the normalized request, which fixtures store for inspection.
+func FixtureKey(req AskRequest) (string, FixtureRequest, error) {
+	norm := FixtureRequest{Model: strings.TrimSpace(req.Model)}
+	for _, m := range buildMessages(req) {
+		m.Role = strings.ToLower(strings.TrimSpace(m.Role))
+		m.Content = strings.TrimSpace(m.Content)
+		calls := make([]ToolCall, len(m.ToolCalls))
+		for i, c := range m.ToolCalls {
+			c.Arguments = compactJSON(normalizeArguments(c.Arguments))
+			calls[i] = c
+		}
+		if len(calls) > 0 {
+			m.ToolCalls = calls
+		}
+		norm.Messages = append(norm.Messages, m)
+	}
+	for _, t := range req.Tools {
+		norm.Tools = append(norm.Tools, FixtureTool{Name: t.Name, Description: t.Description, Parameters: compactJSON(t.Parameters)})
+	}
+	if req.Format != nil {
+		format := json.RawMessage(`"json"`)
+		if len(req.Format.Schema) > 0 {
+			format = compactJSON(req.Format.Schema)
+		}
+		norm.Format = &format
+	}
+	data, err := json.Marshal(norm)
+	if err != nil {
+		return "", norm, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), norm, nil
+}
+
+// embedFixtureKey hashes an embedding request: model and inputs.
+func embedFixtureKey(req EmbedRequest) (string, FixtureRequest, error) {
+	norm := FixtureRequest{Model: strings.TrimSpace(req.Model), Inputs: req.Inputs}
+	data, err := json.Marshal(norm)
+	if err != nil {
+		return "", norm, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), norm, nil
+}
+
+func compactJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return json.RawMessage(buf.Bytes())
+}
+
+// ReplayProvider serves recorded fixtures and fails on any request it has not seen.
+type ReplayProvider struct {
+	dir string
+}
+
+func NewReplayProvider(dir string) *ReplayProvider { return &ReplayProvider{dir: dir} }
+
+func (p *ReplayProvider) Name() string { return ReplayProviderName }
+
+func (p *ReplayProvider) Send(_ context.Context, req AskRequest) (AskResponse, error) {
+	fx, err := p.load(req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	return AskResponse{Text: fx.Text, Thinking: fx.Thinking, ToolCalls: fx.ToolCalls}, nil
+}
+
+// SendStream replays recorded chunks in order, or the full text as one chunk when the
+// fixture was recorded without streaming. Recorded timing is not waited on.
+func (p *ReplayProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	fx, err := p.load(req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	if fx.Thinking != "" && req.OnThinking != nil {
+		req.OnThinking(fx.Thinking)
+	}
+	if len(fx.Chunks) == 0 && fx.Text != "" {
+		onChunk(fx.Text)
+	}
+	for _, c := range fx.Chunks {
+		if err := ctx.Err(); err != nil {
+			return AskResponse{}, err
+		}
+		onChunk(c.Text)
+	}
+	return AskResponse{Text: fx.Text, Thinking: fx.Thinking, ToolCalls: fx.ToolCalls}, nil
+}
+
+// Embed replays recorded embeddings.
+func (p *ReplayProvider) Embed(_ context.Context, req EmbedRequest) ([][]float32, error) {
+	key, _, err := embedFixtureKey(req)
+	if err != nil {
+		return nil, err
+	}
+	fx, err := p.loadKey(key, req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if len(fx.Embeddings) != len(req.Inputs) {
+		return nil, fmt.Errorf("fixture %s has %d embeddings for %d inputs", key, len(fx.Embeddings), len(req.Inputs))
+	}
+	return fx.Embeddings, nil
+}
+
+func (p *ReplayProvider) load(req AskRequest) (Fixture, error) {
+	key, _, err := FixtureKey(req)
+	if err != nil {
+		return Fixture{}, err
+	}
+	return p.loadKey(key, req.Model)
+}
+
+func (p *ReplayProvider) loadKey(key, model string) (Fixture, error) {
+	path := filepath.Join(p.dir, key+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Fixture{}, fmt.Errorf("%w: %s (model %q) in %s; record it with record: true", ErrFixtureNotFound, key, model, p.dir)
+		}
+		return Fixture{}, err
+	}
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return fx, nil
+}
+
+// RecordingProvider forwards to another provider and writes each exchange as a fixture.
+type RecordingProvider struct {
+	inner Provider
+	dir   string
+}
+
+func NewRecordingProvider(inner Provider, dir string) *RecordingProvider {
+	return &RecordingProvider{inner: inner, dir: dir}
+}
+
+func (p *RecordingProvider) Name() string { return p.inner.Name() }
+
+func (p *RecordingProvider) Send(ctx context.Context, req AskRequest) (AskResponse, error) {
+	start := time.Now()
+	resp, err := p.inner.Send(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	return resp, p.write(req, resp, nil, time.Since(start))
+}
+
+func (p *RecordingProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	start := time.Now()
+	var chunks []FixtureChunk
+	resp, err := p.inner.SendStream(ctx, req, func(chunk string) {
+		chunks = append(chunks, FixtureChunk{OffsetMS: time.Since(start).Milliseconds(), Text: chunk})
+		onChunk(chunk)
+	})
+	if err != nil {
+		return resp, err
+	}
+	return resp, p.write(req, resp, chunks, time.Since(start))
+}
+
+// Embed forwards to the wrapped provider and records the embeddings.
+func (p *RecordingProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	embedder, ok := p.inner.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support embeddings", p.inner.Name())
+	}
+	start := time.Now()
+	vectors, err := embedder.Embed(ctx, req)
+	if err != nil {
+		return vectors, err
+	}
+	key, norm, err := embedFixtureKey(req)
+	if err != nil {
+		return vectors, err
+	}
+	return vectors, p.save(Fixture{
+		Key:        key,
+		Provider:   p.inner.Name(),
+		Request:    norm,
+		Embeddings: vectors,
+		DurationMS: time.Since(start).Milliseconds(),
+		RecordedAt: time.Now().UTC(),
+	})
+}
+
+func (p *RecordingProvider) write(req AskRequest, resp AskResponse, chunks []FixtureChunk, elapsed time.Duration) error {
+	key, norm, err := FixtureKey(req)
+	if err != nil {
+		return err
+	}
+	fx := Fixture{
+		Key:        key,
+		Provider:   p.inner.Name(),
+		Request:    norm,
+		Text:       resp.Text,
+		Thinking:   resp.Thinking,
+		ToolCalls:  resp.ToolCalls,
+		Chunks:     chunks,
+		DurationMS: elapsed.Milliseconds(),
+		RecordedAt: time.Now().UTC(),
+	}
+	return p.save(fx)
+}
+
+func (p *RecordingProvider) save(fx Fixture) error {
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(p.dir, fx.Key+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write fixture: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
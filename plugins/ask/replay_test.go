@@ -0,0 +1,141 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	live := &scriptedProvider{replies: []string{"hello from the model"}}
+	req := AskRequest{Provider: "ollama", Host: "localhost", Port: 11434, Model: "llama3", SystemPrompt: "Be brief.", Message: "hi"}
+
+	var streamed []string
+	if _, err := NewRecordingProvider(live, dir).SendStream(context.Background(), req, func(s string) { streamed = append(streamed, s) }); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected one fixture, got %d", len(entries))
+	}
+	var fx Fixture
+	data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err := json.Unmarshal(data, &fx); err != nil {
+		t.Fatalf("fixture is not JSON: %v", err)
+	}
+	if fx.Provider != "scripted" || fx.Text != "hello from the model" || len(fx.Chunks) != 1 {
+		t.Errorf("unexpected fixture: %+v", fx)
+	}
+
+	// Endpoint and provider are not part of the key, so the fixture replays under provider: replay.
+	replayReq := req
+	replayReq.Provider, replayReq.Host, replayReq.Port = ReplayProviderName, "", 0
+	var replayed []string
+	resp, err := NewReplayProvider(dir).SendStream(context.Background(), replayReq, func(s string) { replayed = append(replayed, s) })
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if resp.Text != "hello from the model" || strings.Join(replayed, "") != strings.Join(streamed, "") {
+		t.Errorf("replay = %q (chunks %q), want recorded answer", resp.Text, replayed)
+	}
+}
+
+// embeddingProvider is a chat provider that also embeds.
+type embeddingProvider struct {
+	scriptedProvider
+	embedder countingEmbedder
+}
+
+func (p *embeddingProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	return p.embedder.Embed(ctx, req)
+}
+
+func TestRecordThenReplayEmbeddings(t *testing.T) {
+	dir := t.TempDir()
+	req := EmbedRequest{Provider: "ollama", Host: "localhost", Port: 11434, Model: "nomic-embed-text", Inputs: []string{"hello", "disk full"}}
+	recorded, err := NewRecordingProvider(&embeddingProvider{}, dir).Embed(context.Background(), req)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	req.Provider, req.Host, req.Port = ReplayProviderName, "", 0
+	replayed, err := NewReplayProvider(dir).Embed(context.Background(), req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(replayed) != 2 || replayed[1][0] != recorded[1][0] {
+		t.Errorf("replayed %v, want %v", replayed, recorded)
+	}
+	req.Inputs = []string{"never recorded"}
+	if _, err := NewReplayProvider(dir).Embed(context.Background(), req); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("expected ErrFixtureNotFound, got %v", err)
+	}
+}
+
+func TestReplayMissFailsLoudly(t *testing.T) {
+	dir := t.TempDir()
+	req := AskRequest{Model: "llama3", Message: "never recorded"}
+	key, _, _ := FixtureKey(req)
+	_, err := NewReplayProvider(dir).Send(context.Background(), req)
+	if !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("expected ErrFixtureNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), key) || !strings.Contains(err.Error(), dir) {
+		t.Errorf("error should name the key and directory: %v", err)
+	}
+}
+
+func TestFixtureKeyNormalizes(t *testing.T) {
+	base := AskRequest{Model: "llama3", Message: "hi", Tools: []ToolDefinition{{Name: "ls", Parameters: json.RawMessage(`{"type": "object"}`)}}}
+	same := base
+	same.Message = "  hi\n"
+	same.Host, same.Port, same.Provider = "other", 1, "openai"
+	same.Tools = []ToolDefinition{{Name: "ls", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	different := base
+	different.Model = "llama3.1"
+
+	k1, _, _ := FixtureKey(base)
+	k2, _, _ := FixtureKey(same)
+	k3, _, _ := FixtureKey(different)
+	if k1 != k2 {
+		t.Errorf("whitespace, endpoint and provider should not change the key")
+	}
+	if k1 == k3 {
+		t.Errorf("model should change the key")
+	}
+}
+
+func TestSelectProvider(t *testing.T) {
+	providers := map[string]Provider{"ollama": &scriptedProvider{}}
+	t.Cleanup(func() {
+		viper.Set("fixtures_dir", "")
+		viper.Set("record", false)
+	})
+
+	if _, err := SelectProvider(providers, ReplayProviderName); err == nil {
+		t.Errorf("replay without fixtures_dir should fail")
+	}
+	viper.Set("fixtures_dir", t.TempDir())
+	if p, err := SelectProvider(providers, ReplayProviderName); err != nil || p.Name() != ReplayProviderName {
+		t.Errorf("expected replay provider, got %v, %v", p, err)
+	}
+	if p, _ := SelectProvider(providers, "unknown"); p != providers["ollama"] {
+		t.Errorf("unknown provider should fall back to ollama")
+	}
+	viper.Set("record", true)
+	if p, _ := SelectProvider(providers, "ollama"); p == nil {
+		t.Errorf("expected a provider")
+	} else if _, ok := p.(*RecordingProvider); !ok {
+		t.Errorf("record: true should wrap the provider, got %T", p)
+	}
+	if _, err := SelectProvider(map[string]Provider{}, "nope"); err == nil {
+		t.Errorf("expected an error without an ollama fallback")
+	}
+}
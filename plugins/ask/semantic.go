@@ -0,0 +1,71 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gaia/plugins/cache"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/viper"
+)
+
+// promptEmbedding is the embedding of an ask prompt for the semantic cache.
+type promptEmbedding struct {
+	model  string
+	vector []float32
+}
+
+// embedPrompt embeds msg with the embed.* settings. The provider comes from SelectProvider,
+// so rate limits and record/replay apply, and the vector cache is used, so asking the same
+// question again costs no embedding request.
+func (p *AskPlugin) embedPrompt(ctx context.Context, msg string) (*promptEmbedding, error) {
+	req := EmbedRequestFromConfig()
+	provider, err := SelectProvider(p.providers, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support embeddings", req.Provider)
+	}
+	req.Inputs = []string{msg}
+	vectors, err := EmbedTexts(ctx, embedder, req, EmbedOptions{BatchSize: viper.GetInt("embed.batch_size")})
+	if err != nil {
+		return nil, err
+	}
+	return &promptEmbedding{model: req.Model, vector: vectors[0]}, nil
+}
+
+// semanticLookup returns the cached answer to the prompt most similar to emb, asked with the
+// same provider endpoint, model, role, system prompt and parameters.
+func semanticLookup(providerName string, req AskRequest, emb *promptEmbedding) (cache.Entry, float64, bool) {
+	entry, score, ok, err := cache.FindSimilar(cache.SemanticQuery{
+		PluginID:   "ask",
+		Provider:   providerName,
+		Host:       req.Host,
+		Port:       req.Port,
+		Model:      req.Model,
+		Role:       req.Role,
+		SystemHash: cache.SystemHash(req.SystemPrompt),
+		ParamsHash: cache.ParamsHash(CacheParams(req)),
+		EmbedModel: emb.model,
+		Vector:     emb.vector,
+	}, cache.MinSimilarity())
+	if err != nil || !ok {
+		return cache.Entry{}, 0, false
+	}
+	return entry, score, true
+}
+
+// semanticTitle labels an answer reused from a similar prompt.
+func semanticTitle(score float64) string {
+	return fmt.Sprintf("Answer (semantic cache, %.0f%% similar)", score*100)
+}
+
+func debugSemantic(w io.Writer, err error) {
+	if viper.GetBool("debug") {
+		_ = shared.PrintRaw(w, fmt.Sprintf("[DEBUG] semantic cache skipped: %v\n", err))
+	}
+}
@@ -0,0 +1,170 @@
+package ask
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// DefaultJSONRetries is the number of corrective retries for structured output.
+const DefaultJSONRetries = 2
+
+// ResponseFormat asks the provider for a JSON reply (Ollama "format", OpenAI "response_format").
+type ResponseFormat struct {
+	// Schema is an optional JSON Schema the reply must satisfy; nil asks for any JSON value.
+	Schema json.RawMessage
+}
+
+// LoadSchema reads a JSON Schema file and checks that it resolves.
+func LoadSchema(path string) (json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema %s: %w", path, err)
+	}
+	if _, err := resolveSchema(data); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+func resolveSchema(raw json.RawMessage) (*jsonschema.Resolved, error) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return schema.Resolve(nil)
+}
+
+var jsonBlockRe = regexp.MustCompile("(?s)```(?:json)?\\s*([^`]+)```")
+
+// ExtractJSON returns the first JSON object or array from s, optionally inside ```json ... ```.
+func ExtractJSON(s string) string {
+	return extractJSON(s, "{[")
+}
+
+// ExtractJSONObject returns the first JSON object from s, optionally inside ```json ... ```,
+// skipping any array before it such as "Step [1]: {...}".
+func ExtractJSONObject(s string) string {
+	return extractJSON(s, "{")
+}
+
+// extractJSON returns the balanced JSON value starting at the first of openers in s.
+func extractJSON(s, openers string) string {
+	s = strings.TrimSpace(s)
+	if m := jsonBlockRe.FindStringSubmatch(s); len(m) >= 2 {
+		return strings.TrimSpace(m[1])
+	}
+	start := strings.IndexAny(s, openers)
+	if start < 0 {
+		return s
+	}
+	open, closing := s[start], byte('}')
+	if open == '[' {
+		closing = ']'
+	}
+	depth := 0
+	inString := false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return s[start : i+1]
+			}
+		}
+	}
+	return s[start:]
+}
+
+// ValidateJSON parses text as JSON and validates it against schema when one is given.
+// It returns the indented JSON on success.
+func ValidateJSON(text string, schema json.RawMessage) (string, error) {
+	text = strings.TrimSpace(text)
+	var instance any
+	if err := json.Unmarshal([]byte(text), &instance); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	if len(schema) > 0 {
+		resolved, err := resolveSchema(schema)
+		if err != nil {
+			return "", err
+		}
+		if err := resolved.Validate(instance); err != nil {
+			return "", fmt.Errorf("schema validation failed: %w", err)
+		}
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(text), "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// SendStructured sends req in JSON mode and validates the reply, retrying up to retries
+// times with the validation errors fed back to the model. It returns the clean JSON.
+func SendStructured(ctx context.Context, provider Provider, req AskRequest, retries int) (string, error) {
+	if req.Format == nil {
+		req.Format = &ResponseFormat{}
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	req.SystemPrompt = appendJSONInstruction(req.SystemPrompt, req.Format.Schema)
+	if strings.TrimSpace(req.Message) != "" {
+		req.Messages = append(req.Messages, ChatMessage{Role: "user", Content: req.Message})
+		req.Message = ""
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		resp, err := provider.Send(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		out, err := ValidateJSON(ExtractJSON(resp.Text), req.Format.Schema)
+		if err == nil {
+			return out, nil
+		}
+		lastErr = err
+		req.Messages = append(req.Messages,
+			ChatMessage{Role: "assistant", Content: resp.Text},
+			ChatMessage{Role: "user", Content: fmt.Sprintf("Your previous reply was rejected: %v\nReply again with only the corrected JSON, no markdown or explanation.", err)},
+		)
+	}
+	return "", fmt.Errorf("no valid JSON after %d attempts: %w", retries+1, lastErr)
+}
+
+func appendJSONInstruction(systemPrompt string, schema json.RawMessage) string {
+	instruction := "Respond only with valid JSON, without markdown fences or explanation."
+	if len(schema) > 0 {
+		instruction += "\nThe JSON must match this JSON Schema:\n" + string(schema)
+	}
+	if strings.TrimSpace(systemPrompt) == "" {
+		return instruction
+	}
+	return strings.TrimRight(systemPrompt, "\n") + "\n\n" + instruction
+}
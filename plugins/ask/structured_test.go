@@ -0,0 +1,173 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scriptedProvider struct {
+	replies []string
+	reqs    []AskRequest
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) Send(_ context.Context, req AskRequest) (AskResponse, error) {
+	p.reqs = append(p.reqs, req)
+	i := len(p.reqs) - 1
+	if i >= len(p.replies) {
+		i = len(p.replies) - 1
+	}
+	return AskResponse{Text: p.replies[i]}, nil
+}
+
+func (p *scriptedProvider) SendStream(ctx context.Context, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	resp, err := p.Send(ctx, req)
+	if err == nil {
+		onChunk(resp.Text)
+	}
+	return resp, err
+}
+
+const personSchema = `{"type":"object","required":["name","age"],"properties":{"name":{"type":"string"},"age":{"type":"integer"}}}`
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"a":1}`, `{"a":1}`},
+		{"```json\n{\"a\":1}\n```", `{"a":1}`},
+		{`Sure: {"a":"}"} done`, `{"a":"}"}`},
+		{`list: [1,[2]] end`, `[1,[2]]`},
+	}
+	for _, tt := range tests {
+		if got := ExtractJSON(tt.in); got != tt.want {
+			t.Errorf("ExtractJSON(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExtractJSONObject(t *testing.T) {
+	if got := ExtractJSONObject(`Step [1]: {"a":[2]}`); got != `{"a":[2]}` {
+		t.Errorf("ExtractJSONObject = %q", got)
+	}
+	if got := ExtractJSON(`Step [1]: {"a":[2]}`); got != `[1]` {
+		t.Errorf("ExtractJSON = %q", got)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	if _, err := ValidateJSON(`{"name":"Ada","age":36}`, json.RawMessage(personSchema)); err != nil {
+		t.Fatalf("valid instance rejected: %v", err)
+	}
+	if _, err := ValidateJSON(`{"name":"Ada"}`, json.RawMessage(personSchema)); err == nil {
+		t.Fatal("expected missing property to fail validation")
+	}
+	if _, err := ValidateJSON(`not json`, nil); err == nil {
+		t.Fatal("expected invalid JSON to fail")
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte("{\n  \"type\": \"object\"\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	if string(schema) != `{"type":"object"}` {
+		t.Errorf("schema = %s", schema)
+	}
+}
+
+func TestSendStructured_RetriesWithValidationErrors(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		"```json\n{\"name\":\"Ada\"}\n```",
+		`{"name":"Ada","age":36}`,
+	}}
+	req := AskRequest{Message: "who?", Format: &ResponseFormat{Schema: json.RawMessage(personSchema)}}
+	out, err := SendStructured(context.Background(), provider, req, 2)
+	if err != nil {
+		t.Fatalf("SendStructured: %v", err)
+	}
+	if !strings.Contains(out, `"age": 36`) {
+		t.Errorf("out = %s", out)
+	}
+	if len(provider.reqs) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(provider.reqs))
+	}
+	retry := provider.reqs[1].Messages
+	last := retry[len(retry)-1]
+	if last.Role != "user" || !strings.Contains(last.Content, "rejected") {
+		t.Errorf("retry feedback = %+v", last)
+	}
+	if !strings.Contains(provider.reqs[0].SystemPrompt, personSchema) {
+		t.Errorf("system prompt should carry the schema: %q", provider.reqs[0].SystemPrompt)
+	}
+}
+
+func TestSendStructured_GivesUp(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{"nope"}}
+	_, err := SendStructured(context.Background(), provider, AskRequest{Message: "x"}, 1)
+	if err == nil {
+		t.Fatal("expected error after retries")
+	}
+	if len(provider.reqs) != 2 {
+		t.Errorf("attempts = %d, want 2", len(provider.reqs))
+	}
+}
+
+func TestOllamaSend_SetsFormat(t *testing.T) {
+	var payload map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3:latest"}]}`))
+		case "/api/chat":
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			_, _ = w.Write([]byte(`{"message":{"content":"{}"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Host: host, Port: port, Model: "llama3:latest", Timeout: time.Second, Message: "hi",
+		Format: &ResponseFormat{Schema: json.RawMessage(personSchema)}}
+	if _, err := NewOllamaProvider().Send(context.Background(), req); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if string(payload["format"]) != personSchema {
+		t.Errorf("format = %s", payload["format"])
+	}
+
+	req.Format = &ResponseFormat{}
+	if _, err := NewOllamaProvider().Send(context.Background(), req); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if string(payload["format"]) != `"json"` {
+		t.Errorf("format = %s", payload["format"])
+	}
+}
+
+func TestOpenAIFormat(t *testing.T) {
+	if openAIFormat(nil) != nil {
+		t.Error("nil format should be omitted")
+	}
+	if f := openAIFormat(&ResponseFormat{}); f.Type != "json_object" {
+		t.Errorf("type = %q", f.Type)
+	}
+	f := openAIFormat(&ResponseFormat{Schema: json.RawMessage(personSchema)})
+	if f.Type != "json_schema" || f.JSONSchema == nil || string(f.JSONSchema.Schema) != personSchema {
+		t.Errorf("format = %+v", f)
+	}
+}
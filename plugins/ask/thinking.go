@@ -0,0 +1,110 @@
+package ask
+
+import "strings"
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// SplitThinking separates <think>...</think> reasoning blocks from the answer text.
+func SplitThinking(text string) (answer, thinking string) {
+	s := newThinkSplitter(nil, nil)
+	s.Write(text)
+	resp := s.Response()
+	return resp.Text, resp.Thinking
+}
+
+// thinkSplitter routes streamed text to onAnswer or onThinking, following <think> tags
+// even when a tag is split across chunks. Either callback may be nil.
+type thinkSplitter struct {
+	onAnswer   func(string)
+	onThinking func(string)
+
+	inThink    bool
+	afterThink bool // trim the blank lines that follow a closing tag
+	pending    string
+	answer     strings.Builder
+	thinking   strings.Builder
+	usage      Usage
+}
+
+func newThinkSplitter(onAnswer, onThinking func(string)) *thinkSplitter {
+	return &thinkSplitter{onAnswer: onAnswer, onThinking: onThinking}
+}
+
+// newStreamSplitter sends the answer to onChunk and reasoning to req.OnThinking.
+func newStreamSplitter(req AskRequest, onChunk func(string)) *thinkSplitter {
+	return newThinkSplitter(onChunk, req.OnThinking)
+}
+
+func (s *thinkSplitter) Write(chunk string) {
+	s.pending += chunk
+	for s.pending != "" {
+		tag := thinkOpen
+		if s.inThink {
+			tag = thinkClose
+		}
+		if idx := strings.Index(s.pending, tag); idx >= 0 {
+			s.emit(s.pending[:idx])
+			s.pending = s.pending[idx+len(tag):]
+			s.afterThink = s.inThink
+			s.inThink = !s.inThink
+			continue
+		}
+		// Hold back a suffix that may be the start of the tag.
+		keep := partialTagSuffix(s.pending, tag)
+		s.emit(s.pending[:len(s.pending)-keep])
+		s.pending = s.pending[len(s.pending)-keep:]
+		return
+	}
+}
+
+// Thinking records reasoning delivered outside the text, such as Ollama's thinking field.
+func (s *thinkSplitter) Thinking(chunk string) {
+	if chunk == "" {
+		return
+	}
+	s.thinking.WriteString(chunk)
+	if s.onThinking != nil {
+		s.onThinking(chunk)
+	}
+}
+
+// Response flushes held-back text and returns the accumulated answer, reasoning and usage.
+func (s *thinkSplitter) Response() AskResponse {
+	s.emit(s.pending)
+	s.pending = ""
+	return AskResponse{Text: s.answer.String(), Thinking: strings.TrimSpace(s.thinking.String()), Usage: s.usage}
+}
+
+func (s *thinkSplitter) emit(text string) {
+	if text == "" {
+		return
+	}
+	if s.inThink {
+		s.Thinking(text)
+		return
+	}
+	if s.afterThink {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		s.afterThink = false
+	}
+	s.answer.WriteString(text)
+	if s.onAnswer != nil {
+		s.onAnswer(text)
+	}
+}
+
+// partialTagSuffix returns the length of the longest suffix of text that is a proper prefix of tag.
+func partialTagSuffix(text, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package ask
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		in, answer, thinking string
+	}{
+		{"plain answer", "plain answer", ""},
+		{"<think>\nweigh options\n</think>\n\nThe answer.", "The answer.", "weigh options"},
+		{"Before <think>aside</think> after", "Before after", "aside"},
+		{"<think>never closed", "", "never closed"},
+		{"a < b and c <th", "a < b and c <th", ""},
+	}
+	for _, tt := range tests {
+		answer, thinking := SplitThinking(tt.in)
+		if answer != tt.answer || thinking != tt.thinking {
+			t.Errorf("SplitThinking(%q) = (%q, %q), want (%q, %q)", tt.in, answer, thinking, tt.answer, tt.thinking)
+		}
+	}
+}
+
+func TestThinkSplitterHandlesTagsAcrossChunks(t *testing.T) {
+	var answer, thinking strings.Builder
+	s := newThinkSplitter(func(c string) { answer.WriteString(c) }, func(c string) { thinking.WriteString(c) })
+	for _, chunk := range []string{"<thi", "nk>step one", " step two</th", "ink>", "\n\nDone", "."} {
+		s.Write(chunk)
+	}
+	resp := s.Response()
+	if answer.String() != "Done." || resp.Text != "Done." {
+		t.Errorf("answer = %q (resp %q), want %q", answer.String(), resp.Text, "Done.")
+	}
+	if thinking.String() != "step one step two" || resp.Thinking != "step one step two" {
+		t.Errorf("thinking = %q (resp %q)", thinking.String(), resp.Thinking)
+	}
+}
+
+func TestOllamaSendStream_ThinkingField(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"qwen3:latest"}]}`))
+		case "/api/chat":
+			_, _ = w.Write([]byte(`{"message":{"thinking":"let me see"}}` + "\n"))
+			_, _ = w.Write([]byte(`{"message":{"content":"42"}}` + "\n"))
+			_, _ = w.Write([]byte(`{"done":true,"prompt_eval_count":5,"eval_count":2}` + "\n"))
+		}
+	}))
+	defer srv.Close()
+
+	host, port := parseHostPort(t, srv.URL)
+	var thinking, answer strings.Builder
+	req := AskRequest{Host: host, Port: port, Model: "qwen3:latest", Timeout: time.Second, Message: "?",
+		OnThinking: func(c string) { thinking.WriteString(c) }}
+	resp, err := NewOllamaProvider().SendStream(context.Background(), req, func(c string) { answer.WriteString(c) })
+	if err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+	if resp.Text != "42" || answer.String() != "42" {
+		t.Errorf("answer = %q (streamed %q), want 42", resp.Text, answer.String())
+	}
+	if resp.Thinking != "let me see" || thinking.String() != "let me see" {
+		t.Errorf("thinking = %q (streamed %q)", resp.Thinking, thinking.String())
+	}
+	if resp.Usage != (Usage{PromptTokens: 5, CompletionTokens: 2}) {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
@@ -0,0 +1,151 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrToolsUnsupported is returned when the model rejects a request carrying tool definitions.
+var ErrToolsUnsupported = errors.New("model does not support tools")
+
+// ToolDefinition describes a function the model may call.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	// Parameters is the JSON Schema of the arguments object.
+	Parameters json.RawMessage
+}
+
+// ToolCall is a function call requested by the model. Arguments is always a JSON object.
+type ToolCall struct {
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// functionTool is the {"type":"function","function":{...}} shape shared by Ollama, OpenAI and Mistral.
+type functionTool struct {
+	Type     string       `json:"type"`
+	Function functionSpec `json:"function"`
+}
+
+type functionSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+func toolSpecs(defs []ToolDefinition) []functionTool {
+	if len(defs) == 0 {
+		return nil
+	}
+	out := make([]functionTool, 0, len(defs))
+	for _, d := range defs {
+		params := d.Parameters
+		if len(params) == 0 {
+			params = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		out = append(out, functionTool{
+			Type:     "function",
+			Function: functionSpec{Name: d.Name, Description: d.Description, Parameters: params},
+		})
+	}
+	return out
+}
+
+// remoteToolCall is the OpenAI/Mistral tool_calls entry, where arguments is a JSON-encoded string.
+type remoteToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+func toRemoteToolCalls(calls []ToolCall) []remoteToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]remoteToolCall, 0, len(calls))
+	for _, c := range calls {
+		rc := remoteToolCall{ID: c.ID, Type: "function"}
+		rc.Function.Name = c.Name
+		rc.Function.Arguments = string(normalizeArguments(c.Arguments))
+		out = append(out, rc)
+	}
+	return out
+}
+
+func fromRemoteToolCalls(calls []remoteToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, 0, len(calls))
+	for i, c := range calls {
+		id := c.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		out = append(out, ToolCall{ID: id, Name: c.Function.Name, Arguments: normalizeArguments(json.RawMessage(c.Function.Arguments))})
+	}
+	return out
+}
+
+// normalizeArguments returns args as a JSON object, unwrapping a JSON-encoded string when needed.
+func normalizeArguments(args json.RawMessage) json.RawMessage {
+	trimmed := strings.TrimSpace(string(args))
+	if trimmed == "" || trimmed == "null" {
+		return json.RawMessage(`{}`)
+	}
+	var s string
+	if err := json.Unmarshal([]byte(trimmed), &s); err == nil {
+		trimmed = strings.TrimSpace(s)
+	}
+	if !json.Valid([]byte(trimmed)) {
+		return json.RawMessage(`{}`)
+	}
+	return json.RawMessage(trimmed)
+}
+
+// hasToolMetadata reports whether any message carries tool calls or a tool result.
+func hasToolMetadata(messages []ChatMessage) bool {
+	for _, m := range messages {
+		if len(m.ToolCalls) > 0 || m.ToolCallID != "" || m.Role == "tool" {
+			return true
+		}
+	}
+	return false
+}
+
+// toolsUnsupportedError maps a provider's rejection of tool definitions to ErrToolsUnsupported.
+func toolsUnsupportedError(status int, body string) error {
+	if status != http.StatusBadRequest {
+		return nil
+	}
+	lower := strings.ToLower(body)
+	if strings.Contains(lower, "does not support tools") || strings.Contains(lower, "does not support function calling") {
+		return fmt.Errorf("%w: %s", ErrToolsUnsupported, strings.TrimSpace(body))
+	}
+	return nil
+}
+
+// sendToolsAsStream serves a streaming request with tools through Send, since tool calls
+// arrive fragmented in streams; the text is delivered to onChunk in one piece.
+func sendToolsAsStream(ctx context.Context, p Provider, req AskRequest, onChunk func(string)) (AskResponse, error) {
+	resp, err := p.Send(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	if resp.Thinking != "" && req.OnThinking != nil {
+		req.OnThinking(resp.Thinking)
+	}
+	if resp.Text != "" {
+		onChunk(resp.Text)
+	}
+	return resp, nil
+}
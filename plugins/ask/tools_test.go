@@ -0,0 +1,173 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/viper"
+)
+
+var runCmdTool = ToolDefinition{
+	Name:        "run_cmd",
+	Description: "Execute a shell command",
+	Parameters:  json.RawMessage(`{"type":"object","properties":{"cmd":{"type":"string"}},"required":["cmd"]}`),
+}
+
+func TestOllamaSend_ToolCalls(t *testing.T) {
+	var payload struct {
+		Tools    []functionTool   `json:"tools"`
+		Messages []map[string]any `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3:latest"}]}`))
+		case "/api/chat":
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			_, _ = w.Write([]byte(`{"message":{"content":"","tool_calls":[{"function":{"name":"run_cmd","arguments":{"cmd":"df -h"}}}]}}`))
+		}
+	}))
+	defer srv.Close()
+
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Host: host, Port: port, Model: "llama3:latest", Timeout: time.Second, Tools: []ToolDefinition{runCmdTool},
+		Messages: []ChatMessage{
+			{Role: "user", Content: "disk?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Name: "run_cmd", Arguments: json.RawMessage(`{"cmd":"du"}`)}}},
+			{Role: "tool", Content: "1G", ToolCallID: "call_0", Name: "run_cmd"},
+		}}
+	resp, err := NewOllamaProvider().Send(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(payload.Tools) != 1 || payload.Tools[0].Function.Name != "run_cmd" {
+		t.Errorf("tools payload = %+v", payload.Tools)
+	}
+	if len(payload.Messages) != 3 || payload.Messages[1]["tool_calls"] == nil || payload.Messages[2]["tool_name"] != "run_cmd" {
+		t.Errorf("messages payload = %+v", payload.Messages)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "run_cmd" || string(resp.ToolCalls[0].Arguments) != `{"cmd":"df -h"}` {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+}
+
+func TestOllamaSend_ToolsUnsupported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"gemma:2b"}]}`))
+		case "/api/chat":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"registry.ollama.ai/library/gemma:2b does not support tools"}`))
+		}
+	}))
+	defer srv.Close()
+
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Host: host, Port: port, Model: "gemma:2b", Timeout: time.Second, Message: "hi", Tools: []ToolDefinition{runCmdTool}}
+	_, err := NewOllamaProvider().Send(context.Background(), req)
+	if !errors.Is(err, ErrToolsUnsupported) {
+		t.Fatalf("err = %v, want ErrToolsUnsupported", err)
+	}
+}
+
+func TestOpenAISend_ToolCalls(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test")
+	var payload struct {
+		Tools    []functionTool  `json:"tools"`
+		Messages []openAIMessage `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_abc","type":"function","function":{"name":"run_cmd","arguments":"{\"cmd\":\"uptime\"}"}}]}}]}`))
+	}))
+	defer srv.Close()
+
+	host, port := parseHostPort(t, srv.URL)
+	req := AskRequest{Host: host, Port: port, Model: "gpt-4o", Timeout: time.Second, Tools: []ToolDefinition{runCmdTool},
+		Messages: []ChatMessage{
+			{Role: "user", Content: "load?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "run_cmd", Arguments: json.RawMessage(`{"cmd":"w"}`)}}},
+			{Role: "tool", Content: "ok", ToolCallID: "call_1"},
+		}}
+	resp, err := NewOpenAIProvider().Send(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(payload.Tools) != 1 {
+		t.Errorf("tools payload = %+v", payload.Tools)
+	}
+	if got := payload.Messages[1].ToolCalls; len(got) != 1 || got[0].Function.Arguments != `{"cmd":"w"}` {
+		t.Errorf("assistant tool_calls = %+v", got)
+	}
+	if payload.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("tool message = %+v", payload.Messages[2])
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_abc" || string(resp.ToolCalls[0].Arguments) != `{"cmd":"uptime"}` {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+}
+
+func TestApplySanitize_PreservesToolMetadata(t *testing.T) {
+	viper.Set("sanitize.enabled", true)
+	viper.Set("sanitize.level", "light")
+	viper.Set("sanitize.max_tokens_after", 5)
+	t.Cleanup(func() {
+		viper.Set("sanitize.enabled", false)
+		viper.Set("sanitize.level", "")
+		viper.Set("sanitize.max_tokens_after", 0)
+	})
+	req := AskRequest{SystemPrompt: "sys", Messages: []ChatMessage{
+		{Role: "user", Content: "disk?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Name: "run_cmd", Arguments: json.RawMessage(`{}`)}}},
+		{Role: "tool", Content: "a long tool output that would otherwise be trimmed away by the cap", ToolCallID: "call_0", Name: "run_cmd"},
+	}}
+	out := ApplySanitize(nil, req)
+	if len(out.Messages) != 4 {
+		t.Fatalf("messages = %+v", out.Messages)
+	}
+	if len(out.Messages[2].ToolCalls) != 1 || out.Messages[3].ToolCallID != "call_0" || out.Messages[3].Name != "run_cmd" {
+		t.Errorf("tool metadata lost: %+v", out.Messages)
+	}
+}
+
+func TestMergeSanitized_NeverDropsToolMessages(t *testing.T) {
+	source := []ChatMessage{
+		{Role: "user", Content: "disk?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Name: "run_cmd", Arguments: json.RawMessage(`{}`)}}},
+		{Role: "tool", Content: "output", ToolCallID: "call_0", Name: "run_cmd"},
+	}
+	if _, ok := mergeSanitized(source, []sanitizepkg.Message{{Role: "user", Content: "disk?"}, {Role: "tool", Content: "output"}}); ok {
+		t.Error("expected a shortened tool conversation to be rejected")
+	}
+	out, ok := mergeSanitized(source, []sanitizepkg.Message{{Role: "user", Content: "disk"}, {Role: "assistant"}, {Role: "tool", Content: "out"}})
+	if !ok || out[0].Content != "disk" || len(out[1].ToolCalls) != 1 || out[2].ToolCallID != "call_0" || out[2].Content != "out" {
+		t.Errorf("mergeSanitized = %+v, %v", out, ok)
+	}
+	plain := []ChatMessage{{Role: "user", Content: "a"}, {Role: "assistant", Content: "b"}, {Role: "user", Content: "c"}}
+	if out, ok := mergeSanitized(plain, []sanitizepkg.Message{{Role: "user", Content: "c"}}); !ok || len(out) != 1 {
+		t.Errorf("mergeSanitized without tools = %+v, %v", out, ok)
+	}
+}
+
+func TestNormalizeArguments(t *testing.T) {
+	tests := map[string]string{
+		``:                  `{}`,
+		`null`:              `{}`,
+		`{"a":1}`:           `{"a":1}`,
+		`"{\"a\":1}"`:       `{"a":1}`,
+		`"not json at all"`: `{}`,
+	}
+	for in, want := range tests {
+		if got := string(normalizeArguments(json.RawMessage(in))); got != want {
+			t.Errorf("normalizeArguments(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -15,17 +15,27 @@ import (
 	"github.com/spf13/viper"
 )
 
+// KeyVersion is the version of the key layout. Entries written under another version are
+// dropped when read, since no key can match them anymore.
+const KeyVersion = 3
+
 type Entry struct {
-	Key       string    `json:"key"`
-	Label     string    `json:"label"`
-	PluginID  string    `json:"plugin_id"`
-	Provider  string    `json:"provider"`
-	Host      string    `json:"host"`
-	Port      int       `json:"port"`
-	Model     string    `json:"model"`
-	Messages  []Message `json:"messages"`
-	Response  string    `json:"response"`
-	CreatedAt time.Time `json:"created_at"`
+	Version  int    `json:"version"`
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	PluginID string `json:"plugin_id"`
+	Provider string `json:"provider"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Model    string `json:"model"`
+	Role     string `json:"role,omitempty"`
+	// SystemHash and ParamsHash identify the system prompt and generation settings of the
+	// answer, so a semantic lookup only reuses answers produced under the same ones.
+	SystemHash string    `json:"system_hash,omitempty"`
+	ParamsHash string    `json:"params_hash,omitempty"`
+	Messages   []Message `json:"messages"`
+	Response   string    `json:"response"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type Message struct {
@@ -38,6 +48,7 @@ type EntryInfo struct {
 	Label     string
 	PluginID  string
 	Model     string
+	Role      string
 	CreatedAt time.Time
 	SizeBytes int64
 }
@@ -47,14 +58,21 @@ type Stats struct {
 	SizeBytes int64
 }
 
+// KeyPayload is everything that shapes an answer. The system prompt is hashed rather than
+// stored, and Params holds generation settings such as sanitization or a response schema.
 type KeyPayload struct {
-	PluginID string    `json:"plugin_id"`
-	Provider string    `json:"provider"`
-	Host     string    `json:"host"`
-	Port     int       `json:"port"`
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Label    string    `json:"label"`
+	Version      int               `json:"version"`
+	PluginID     string            `json:"plugin_id"`
+	Provider     string            `json:"provider"`
+	Host         string            `json:"host"`
+	Port         int               `json:"port"`
+	Model        string            `json:"model"`
+	Role         string            `json:"role"`
+	SystemPrompt string            `json:"-"`
+	SystemHash   string            `json:"system_hash"`
+	Params       map[string]string `json:"params,omitempty"`
+	Messages     []Message         `json:"messages"`
+	Label        string            `json:"label"`
 }
 
 func Enabled() bool {
@@ -69,7 +87,29 @@ func TTL() time.Duration {
 	return time.Duration(ttlSeconds) * time.Second
 }
 
+// SystemHash returns the hash of a system prompt, or "" for none.
+func SystemHash(prompt string) string {
+	if prompt == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParamsHash returns the hash of generation settings, or "" for none.
+func ParamsHash(params map[string]string) string {
+	if len(params) == 0 {
+		return ""
+	}
+	// Maps are encoded with sorted keys, so the hash does not depend on insertion order.
+	data, _ := json.Marshal(params)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func BuildKey(payload KeyPayload) (string, error) {
+	payload.Version = KeyVersion
+	payload.SystemHash = SystemHash(payload.SystemPrompt)
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	if err := enc.Encode(payload); err != nil {
@@ -95,8 +135,8 @@ func Get(key string) (Entry, bool, error) {
 	if err := json.Unmarshal(data, &entry); err != nil {
 		return Entry{}, false, err
 	}
-	if expired(entry) {
-		_ = os.Remove(path)
+	if stale(entry) {
+		removeEntryFiles(path)
 		return Entry{}, false, nil
 	}
 	return entry, true, nil
@@ -110,6 +150,7 @@ func Set(entry Entry) error {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
+	entry.Version = KeyVersion
 	if entry.CreatedAt.IsZero() {
 		entry.CreatedAt = time.Now().UTC()
 	}
@@ -117,7 +158,11 @@ func Set(entry Entry) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(dir, entry.Key+".json"), data, 0o600)
+	if err := os.WriteFile(filepath.Join(dir, entry.Key+".json"), data, 0o600); err != nil {
+		return err
+	}
+	index.reset()
+	return nil
 }
 
 func List() ([]EntryInfo, error) {
@@ -153,8 +198,8 @@ func List() ([]EntryInfo, error) {
 		if err := json.Unmarshal(data, &cached); err != nil {
 			return nil, err
 		}
-		if expired(cached) {
-			_ = os.Remove(path)
+		if stale(cached) {
+			removeEntryFiles(path)
 			continue
 		}
 		fileInfo, err := entry.Info()
@@ -166,6 +211,7 @@ func List() ([]EntryInfo, error) {
 			Label:     cached.Label,
 			PluginID:  cached.PluginID,
 			Model:     cached.Model,
+			Role:      cached.Role,
 			CreatedAt: cached.CreatedAt,
 			SizeBytes: fileInfo.Size(),
 		})
@@ -206,8 +252,8 @@ func StatsInfo() (Stats, error) {
 		if err := json.Unmarshal(data, &cached); err != nil {
 			return Stats{}, err
 		}
-		if expired(cached) {
-			_ = os.Remove(path)
+		if stale(cached) {
+			removeEntryFiles(path)
 			continue
 		}
 		fileInfo, err := entry.Info()
@@ -239,9 +285,17 @@ func ClearAll() (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	defer index.reset()
 	removed := 0
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), embeddingSuffix) {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), ".json") {
 			continue
 		}
 		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
@@ -260,9 +314,18 @@ func Delete(key string) error {
 	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
+	_ = os.Remove(strings.TrimSuffix(path, ".json") + embeddingSuffix)
+	index.reset()
 	return nil
 }
 
+// removeEntryFiles deletes a stale entry and its embedding.
+func removeEntryFiles(path string) {
+	_ = os.Remove(path)
+	_ = os.Remove(strings.TrimSuffix(path, ".json") + embeddingSuffix)
+	index.reset()
+}
+
 func entryPath(key string) (string, error) {
 	if strings.TrimSpace(key) == "" {
 		return "", fmt.Errorf("cache key is required")
@@ -286,6 +349,11 @@ func getCacheDir() (string, error) {
 	return filepath.Join(homeDir, ".config", "gaia", "cache"), nil
 }
 
+// stale reports whether entry is expired or was written under an older key layout.
+func stale(entry Entry) bool {
+	return entry.Version != KeyVersion || expired(entry)
+}
+
 func expired(entry Entry) bool {
 	ttl := TTL()
 	if ttl == 0 {
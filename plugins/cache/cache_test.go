@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBuildKeyCoversPromptRoleAndParams(t *testing.T) {
+	base := KeyPayload{
+		PluginID:     "ask",
+		Provider:     "ollama",
+		Model:        "llama3",
+		Role:         "code",
+		SystemPrompt: "You are a programmer.",
+		Messages:     []Message{{Role: "user", Content: "Explain this"}},
+	}
+	baseKey, err := BuildKey(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := BuildKey(base); again != baseKey {
+		t.Errorf("BuildKey is not stable: %s != %s", again, baseKey)
+	}
+	variants := map[string]func(*KeyPayload){
+		"role":          func(p *KeyPayload) { p.Role = "describe" },
+		"system prompt": func(p *KeyPayload) { p.SystemPrompt += "\n\nRelevant memory: auth" },
+		"params":        func(p *KeyPayload) { p.Params = map[string]string{"sanitize.level": "light"} },
+	}
+	for name, change := range variants {
+		payload := base
+		change(&payload)
+		key, err := BuildKey(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key == baseKey {
+			t.Errorf("changing the %s kept the key", name)
+		}
+	}
+}
+
+func TestOldEntriesAreDropped(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("cache.dir", dir)
+	viper.Set("cache.ttl_seconds", 0)
+	t.Cleanup(func() { viper.Set("cache.dir", "") })
+
+	if err := Set(Entry{Key: "current", Role: "code", Response: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := json.Marshal(Entry{Key: "old", Response: "stale"})
+	if err := os.WriteFile(filepath.Join(dir, "old.json"), old, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "current" || entries[0].Role != "code" {
+		t.Errorf("List = %+v, want only the current entry", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.json")); !os.IsNotExist(err) {
+		t.Errorf("old entry was not removed: %v", err)
+	}
+	if _, ok, err := Get("current"); err != nil || !ok {
+		t.Errorf("Get(current) = %v, %v", ok, err)
+	}
+}
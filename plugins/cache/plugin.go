@@ -8,8 +8,10 @@ import (
 
 	"gaia/kernel"
 	"gaia/plugins/shared"
+	"gaia/plugins/shared/export"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 type CachePlugin struct{}
@@ -24,6 +26,8 @@ func (p *CachePlugin) ConfigSchema() []string {
 		"cache.enabled",
 		"cache.dir",
 		"cache.ttl_seconds",
+		"cache.semantic.enabled",
+		"cache.semantic.min_similarity",
 	}
 }
 
@@ -77,10 +81,30 @@ func (p *CachePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 			if !ok {
 				return shared.PrintError(cmd.ErrOrStderr(), "Cache entry not found")
 			}
-			body := fmt.Sprintf("Key: %s\nPlugin: %s\nModel: %s\nCreated: %s\nLabel: %s\n\nResponse:\n%s",
+			if name, _ := cmd.Flags().GetString("format"); name != "" {
+				format, err := export.ParseFormat(name)
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				data, err := export.Render(entryDocument(entry), format)
+				if err != nil {
+					return err
+				}
+				return shared.PrintRaw(cmd.OutOrStdout(), string(data))
+			}
+			if raw := viper.GetBool("raw"); shared.MarkdownEnabled(cmd.OutOrStdout(), raw) {
+				meta := fmt.Sprintf("Key: %s\nPlugin: %s\nModel: %s\nRole: %s\nCreated: %s\nLabel: %s",
+					entry.Key, entry.PluginID, entry.Model, roleLabel(entry.Role), entry.CreatedAt.Format(time.RFC3339), entry.Label)
+				if err := shared.PrintBox(cmd.OutOrStdout(), "Cache", meta); err != nil {
+					return err
+				}
+				return shared.PrintAnswer(cmd.OutOrStdout(), "Response", entry.Response, raw)
+			}
+			body := fmt.Sprintf("Key: %s\nPlugin: %s\nModel: %s\nRole: %s\nCreated: %s\nLabel: %s\n\nResponse:\n%s",
 				entry.Key,
 				entry.PluginID,
 				entry.Model,
+				roleLabel(entry.Role),
 				entry.CreatedAt.Format(time.RFC3339),
 				entry.Label,
 				entry.Response,
@@ -89,6 +113,8 @@ func (p *CachePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 		},
 	}
 
+	showCmd.Flags().String("format", "", "Print the entry as md, json or html instead of a box")
+
 	statsCmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Show cache statistics",
@@ -129,3 +155,33 @@ func (p *CachePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 	root.AddCommand(listCmd, showCmd, statsCmd, clearCmd, deleteCmd)
 	return []*cobra.Command{root}, nil
 }
+
+// roleLabel names the role of an entry; answers without a role used no role prompt or a
+// MemPalace context instead.
+func roleLabel(role string) string {
+	if role == "" {
+		return "(none)"
+	}
+	return role
+}
+
+// entryDocument describes a cached exchange for export.
+func entryDocument(entry Entry) export.Document {
+	doc := export.Document{
+		Title:     entry.Label,
+		Source:    entry.PluginID,
+		Provider:  entry.Provider,
+		Model:     entry.Model,
+		Role:      entry.Role,
+		CreatedAt: entry.CreatedAt,
+		Metadata:  map[string]string{"Cache key": entry.Key},
+	}
+	for _, m := range entry.Messages {
+		doc.Messages = append(doc.Messages, export.Message{Role: m.Role, Content: m.Content})
+	}
+	// ask entries store only the question; chat entries already end with the answer.
+	if n := len(doc.Messages); n == 0 || doc.Messages[n-1].Role != "assistant" || doc.Messages[n-1].Content != entry.Response {
+		doc.Messages = append(doc.Messages, export.Message{Role: "assistant", Content: entry.Response})
+	}
+	return doc
+}
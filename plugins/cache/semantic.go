@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultMinSimilarity is the cosine similarity a semantic hit needs when
+// cache.semantic.min_similarity is not set.
+const DefaultMinSimilarity = 0.92
+
+// embeddingSuffix names the file that stores the prompt embedding of an entry, next to it.
+const embeddingSuffix = ".emb"
+
+// SemanticEnabled reports whether lookups may fall back to similar prompts.
+func SemanticEnabled() bool {
+	return Enabled() && viper.GetBool("cache.semantic.enabled")
+}
+
+// MinSimilarity returns the similarity threshold for semantic hits.
+func MinSimilarity() float64 {
+	v := viper.GetFloat64("cache.semantic.min_similarity")
+	if v <= 0 || v > 1 {
+		return DefaultMinSimilarity
+	}
+	return v
+}
+
+// storedEmbedding is the content of an .emb file.
+type storedEmbedding struct {
+	Model  string    `json:"model"`
+	Vector []float32 `json:"vector"`
+}
+
+// SemanticQuery describes the answers a semantic lookup may reuse: entries produced by the
+// same plugin, endpoint, model, role, system prompt and parameters, whose prompt was
+// embedded with EmbedModel.
+type SemanticQuery struct {
+	PluginID   string
+	Provider   string
+	Host       string
+	Port       int
+	Model      string
+	Role       string
+	SystemHash string
+	ParamsHash string
+	EmbedModel string
+	Vector     []float32
+}
+
+// matches reports whether the answer of e was produced under the settings of q.
+func (q SemanticQuery) matches(e Entry) bool {
+	return e.PluginID == q.PluginID && e.Provider == q.Provider && e.Host == q.Host && e.Port == q.Port &&
+		e.Model == q.Model && e.Role == q.Role && e.SystemHash == q.SystemHash && e.ParamsHash == q.ParamsHash
+}
+
+// SetEmbedding stores the prompt embedding of the entry key.
+func SetEmbedding(key, embedModel string, vector []float32) error {
+	path, err := embeddingPath(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(storedEmbedding{Model: embedModel, Vector: vector})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	index.reset()
+	return nil
+}
+
+// FindSimilar returns the cached entry whose prompt is closest to q.Vector, when its
+// similarity reaches minSimilarity.
+func FindSimilar(q SemanticQuery, minSimilarity float64) (Entry, float64, bool, error) {
+	items, err := index.load()
+	if err != nil {
+		return Entry{}, 0, false, err
+	}
+	best, bestScore := -1, 0.0
+	for i, item := range items {
+		if !q.matches(item.entry) || item.embedModel != q.EmbedModel {
+			continue
+		}
+		if score := CosineSimilarity(q.Vector, item.vector); score >= minSimilarity && score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return Entry{}, 0, false, nil
+	}
+	// Re-read the entry so expiry applies as for exact hits.
+	entry, ok, err := Get(items[best].entry.Key)
+	if err != nil || !ok {
+		return Entry{}, 0, false, err
+	}
+	return entry, bestScore, true, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0 when they differ
+// in length or one is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// indexItem is one embedded entry of the semantic index.
+type indexItem struct {
+	entry      Entry
+	embedModel string
+	vector     []float32
+}
+
+// semanticIndex holds the embedded entries of the cache directory, loaded once per process
+// and dropped whenever the cache changes.
+type semanticIndex struct {
+	mu     sync.Mutex
+	loaded bool
+	dir    string
+	items  []indexItem
+}
+
+var index = &semanticIndex{}
+
+func (ix *semanticIndex) reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.loaded = false
+	ix.items = nil
+}
+
+func (ix *semanticIndex) load() ([]indexItem, error) {
+	dir, err := getCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.loaded && ix.dir == dir {
+		return ix.items, nil
+	}
+	names, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var items []indexItem
+	for _, name := range names {
+		if name.IsDir() || !strings.HasSuffix(name.Name(), embeddingSuffix) {
+			continue
+		}
+		key := strings.TrimSuffix(name.Name(), embeddingSuffix)
+		var emb storedEmbedding
+		data, err := os.ReadFile(filepath.Join(dir, name.Name()))
+		if err != nil || json.Unmarshal(data, &emb) != nil || len(emb.Vector) == 0 {
+			continue
+		}
+		data, err = os.ReadFile(filepath.Join(dir, key+".json"))
+		if err != nil {
+			// The entry is gone; its embedding is of no use.
+			_ = os.Remove(filepath.Join(dir, name.Name()))
+			continue
+		}
+		var entry Entry
+		if json.Unmarshal(data, &entry) != nil || stale(entry) {
+			continue
+		}
+		items = append(items, indexItem{entry: entry, embedModel: emb.Model, vector: emb.Vector})
+	}
+	ix.dir, ix.items, ix.loaded = dir, items, true
+	return items, nil
+}
+
+func embeddingPath(key string) (string, error) {
+	path, err := entryPath(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".json") + embeddingSuffix, nil
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{1, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 1}, []float32{2, 2}, 1},
+		{[]float32{1, 0}, []float32{-1, 0}, -1},
+		{[]float32{1, 0}, []float32{1, 0, 0}, 0},
+		{[]float32{0, 0}, []float32{1, 0}, 0},
+	}
+	for _, c := range cases {
+		if got := CosineSimilarity(c.a, c.b); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("CosineSimilarity(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("cache.dir", dir)
+	viper.Set("cache.ttl_seconds", 0)
+	t.Cleanup(func() { viper.Set("cache.dir", "") })
+
+	store := func(key, role string, vector []float32) {
+		t.Helper()
+		if err := Set(Entry{Key: key, PluginID: "ask", Provider: "ollama", Host: "localhost", Port: 11434, Model: "llama3", Role: role,
+			SystemHash: SystemHash("You are a programmer."), Response: key}); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetEmbedding(key, "nomic-embed-text", vector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store("close", "code", []float32{1, 0.1, 0})
+	store("far", "code", []float32{0, 1, 0})
+	store("other-role", "describe", []float32{1, 0, 0})
+
+	query := SemanticQuery{PluginID: "ask", Provider: "ollama", Host: "localhost", Port: 11434, Model: "llama3", Role: "code",
+		SystemHash: SystemHash("You are a programmer."), EmbedModel: "nomic-embed-text", Vector: []float32{1, 0, 0}}
+	entry, score, ok, err := FindSimilar(query, 0.9)
+	if err != nil || !ok {
+		t.Fatalf("FindSimilar = %v, %v", ok, err)
+	}
+	if entry.Key != "close" || score < 0.99 {
+		t.Errorf("FindSimilar = %s (%.3f), want close", entry.Key, score)
+	}
+
+	if _, _, ok, _ := FindSimilar(query, 0.999); ok {
+		t.Error("FindSimilar ignored the threshold")
+	}
+	other := query
+	other.Model = "qwen3"
+	if _, _, ok, _ := FindSimilar(other, 0.5); ok {
+		t.Error("FindSimilar matched another model")
+	}
+	// Answers produced under another system prompt, such as MemPalace context, another
+	// endpoint or other parameters are never reused.
+	for name, change := range map[string]func(*SemanticQuery){
+		"system prompt": func(q *SemanticQuery) { q.SystemHash = SystemHash("You are a programmer.\n\nRelevant memory: auth") },
+		"host":          func(q *SemanticQuery) { q.Host = "gpu-box" },
+		"params":        func(q *SemanticQuery) { q.ParamsHash = ParamsHash(map[string]string{"sanitize.level": "light"}) },
+	} {
+		other = query
+		change(&other)
+		if _, _, ok, _ := FindSimilar(other, 0.5); ok {
+			t.Errorf("FindSimilar matched another %s", name)
+		}
+	}
+	other = query
+	other.EmbedModel = "mxbai-embed-large"
+	if _, _, ok, _ := FindSimilar(other, 0.5); ok {
+		t.Error("FindSimilar compared vectors of another embedding model")
+	}
+
+	if err := Delete("close"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "close"+embeddingSuffix)); !os.IsNotExist(err) {
+		t.Errorf("Delete kept the embedding: %v", err)
+	}
+	if _, _, ok, _ := FindSimilar(query, 0.9); ok {
+		t.Error("FindSimilar returned a deleted entry")
+	}
+}
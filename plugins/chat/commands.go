@@ -0,0 +1,419 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gaia/plugins/ask"
+	"gaia/plugins/cache"
+	"gaia/plugins/shared"
+	"gaia/plugins/shared/export"
+)
+
+// Command is a slash command typed at the chat prompt, such as "/model llama3".
+type Command struct {
+	Name    string // without the leading slash
+	Args    string // argument synopsis shown by /help, e.g. "<path>"
+	Summary string
+	Run     func(ctx context.Context, env *CommandEnv, args string) error
+}
+
+// CommandProvider is implemented by plugins that add chat slash commands. The chat plugin
+// collects the commands of every enabled plugin when a session starts.
+type CommandProvider interface {
+	ChatCommands() []Command
+}
+
+// CommandEnv is the running chat as seen by a command.
+type CommandEnv struct {
+	Session *Session
+	Out     io.Writer
+	ErrOut  io.Writer
+	// Terminal receives escape sequences meant for the terminal itself, such as OSC 52.
+	// It defaults to Out.
+	Terminal io.Writer
+	r        *runner
+}
+
+// Send submits text as the next user message, as if it had been typed at the prompt.
+func (e *CommandEnv) Send(ctx context.Context, text string) { e.r.turn(ctx, text) }
+
+// LastAnswer returns the latest assistant message, or "" before the first reply.
+func (e *CommandEnv) LastAnswer() string {
+	for i := len(e.Session.Messages) - 1; i >= 0; i-- {
+		if e.Session.Messages[i].Role == "assistant" {
+			return e.Session.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// errExitChat is returned by /exit to end the chat loop.
+var errExitChat = errors.New("exit chat")
+
+const maxAttachmentBytes = 256 << 10
+
+type commandRegistry struct {
+	commands map[string]Command
+}
+
+// newCommandRegistry returns a registry holding the built-in commands.
+func newCommandRegistry() *commandRegistry {
+	reg := &commandRegistry{commands: map[string]Command{}}
+	for _, c := range builtinCommands(reg) {
+		_ = reg.Register(c)
+	}
+	return reg
+}
+
+// Register adds c; names are unique, so a plugin cannot replace a built-in command.
+func (reg *commandRegistry) Register(c Command) error {
+	c.Name = strings.TrimPrefix(strings.TrimSpace(c.Name), "/")
+	if c.Name == "" || strings.ContainsAny(c.Name, " \t") {
+		return fmt.Errorf("invalid chat command name %q", c.Name)
+	}
+	if c.Run == nil {
+		return fmt.Errorf("chat command /%s has no handler", c.Name)
+	}
+	if _, exists := reg.commands[c.Name]; exists {
+		return fmt.Errorf("chat command /%s is already registered", c.Name)
+	}
+	reg.commands[c.Name] = c
+	return nil
+}
+
+// Dispatch runs the command on line and reports whether line was a command. A line starting
+// with "//" is a message that begins with a slash, so it is not a command.
+func (reg *commandRegistry) Dispatch(ctx context.Context, env *CommandEnv, line string) (bool, error) {
+	if !strings.HasPrefix(line, "/") || strings.HasPrefix(line, "//") {
+		return false, nil
+	}
+	name, args, _ := strings.Cut(strings.TrimPrefix(line, "/"), " ")
+	c, ok := reg.commands[name]
+	if !ok {
+		return true, fmt.Errorf("unknown command /%s (type /help for a list)", name)
+	}
+	return true, c.Run(ctx, env, strings.TrimSpace(args))
+}
+
+// Help lists the commands in name order.
+func (reg *commandRegistry) Help() string {
+	names := make([]string, 0, len(reg.commands))
+	for name := range reg.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	usages := make([]string, len(names))
+	width := 0
+	for i, name := range names {
+		c := reg.commands[name]
+		usages[i] = "/" + c.Name
+		if c.Args != "" {
+			usages[i] += " " + c.Args
+		}
+		width = max(width, len(usages[i]))
+	}
+	var b strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&b, "%-*s  %s\n", width, usages[i], reg.commands[name].Summary)
+	}
+	b.WriteString("\nStart a message with // to send a literal leading slash.")
+	return b.String()
+}
+
+func builtinCommands(reg *commandRegistry) []Command {
+	return []Command{
+		{Name: "help", Summary: "List chat commands", Run: func(_ context.Context, env *CommandEnv, _ string) error {
+			return shared.PrintBox(env.Out, "Chat Commands", reg.Help())
+		}},
+		{Name: "exit", Summary: "End the chat session", Run: func(context.Context, *CommandEnv, string) error {
+			return errExitChat
+		}},
+		{Name: "model", Args: "[provider:]<model>", Summary: "Show or switch the model", Run: cmdModel},
+		{Name: "role", Args: "[name|none]", Summary: "Show or switch the role", Run: cmdRole},
+		{Name: "system", Args: "[prompt|reset]", Summary: "Show or replace the system prompt", Run: cmdSystem},
+		{Name: "clear", Summary: "Start a new, empty session", Run: cmdClear},
+		{Name: "undo", Summary: "Drop the last question and answer", Run: cmdUndo},
+		{Name: "retry", Summary: "Regenerate the last answer", Run: cmdRetry},
+		{Name: "save", Args: "[path]", Summary: "Save the session, or export it to a file", Run: cmdSave},
+		{Name: "export", Args: "[md|json|html] [path]", Summary: "Export the session as Markdown, JSON or HTML", Run: cmdExport},
+		{Name: "copy", Summary: "Copy the last answer to the clipboard (OSC 52)", Run: cmdCopy},
+		{Name: "file", Args: "<path>", Summary: "Attach a file to the next message", Run: cmdFile},
+		{Name: "cache", Args: "[on|off]", Summary: "Show or toggle the response cache", Run: cmdCache},
+		{Name: "history", Summary: "Show the summary of compacted turns and the history size", Run: cmdHistory},
+		{Name: "compact", Args: "[turns]", Summary: "Summarize all but the last turns now", Run: cmdCompact},
+	}
+}
+
+func cmdModel(ctx context.Context, env *CommandEnv, args string) error {
+	r := env.r
+	if args == "" {
+		return shared.PrintBox(env.Out, "Model", fmt.Sprintf("%s (%s)", r.req.Model, r.req.Provider))
+	}
+	req := ask.TargetRequest(r.req, ask.ParseCompareTarget(args, r.providers, r.req.Provider))
+	req.ProgressClearer = r.req.ProgressClearer
+	if err := validateChatConfig(req); err != nil {
+		return err
+	}
+	provider, err := ask.SelectProvider(r.providers, req.Provider)
+	if err != nil {
+		return err
+	}
+	r.req, r.provider = req, provider
+	env.Session.Provider, env.Session.Model = req.Provider, req.Model
+	return shared.PrintBox(env.Out, "Model", fmt.Sprintf("Switched to %s (%s)", req.Model, req.Provider))
+}
+
+func cmdRole(ctx context.Context, env *CommandEnv, args string) error {
+	switch args {
+	case "":
+		role := env.Session.Role
+		if role == "" {
+			role = "none"
+		}
+		return shared.PrintBox(env.Out, "Role", role)
+	case "none", "off":
+		env.Session.Role = ""
+		return shared.PrintBox(env.Out, "Role", "Role cleared")
+	}
+	if _, err := loadRole(args); err != nil {
+		return err
+	}
+	env.Session.Role = args
+	return shared.PrintBox(env.Out, "Role", fmt.Sprintf("Switched to %s", args))
+}
+
+func cmdSystem(ctx context.Context, env *CommandEnv, args string) error {
+	switch args {
+	case "":
+		prompt := env.Session.System
+		if prompt == "" {
+			prompt = env.r.lastSystem
+		}
+		if prompt == "" {
+			prompt = "(none)"
+		}
+		return shared.PrintBox(env.Out, "System Prompt", prompt)
+	case "reset":
+		env.Session.System = ""
+		return shared.PrintBox(env.Out, "System Prompt", "Using the role prompt again")
+	}
+	env.Session.System = args
+	return shared.PrintBox(env.Out, "System Prompt", "System prompt replaced for this session")
+}
+
+func cmdClear(ctx context.Context, env *CommandEnv, _ string) error {
+	old := env.Session
+	fresh := NewSession(old.Provider, old.Model, old.Role)
+	fresh.System = old.System
+	env.r.session, env.Session = fresh, fresh
+	env.r.attachments = nil
+	msg := "Started a new session"
+	if env.r.persist && len(old.Messages) > 0 {
+		msg += fmt.Sprintf("; the previous one is saved as %s", old.ID)
+	}
+	return shared.PrintBox(env.Out, "Chat", msg)
+}
+
+// lastExchange returns the index of the latest user message, or -1.
+func lastExchange(messages []ask.ChatMessage) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
+
+func cmdUndo(ctx context.Context, env *CommandEnv, _ string) error {
+	i := lastExchange(env.Session.Messages)
+	if i < 0 {
+		return errors.New("nothing to undo")
+	}
+	env.Session.Messages = env.Session.Messages[:i]
+	env.r.save(ctx)
+	return shared.PrintBox(env.Out, "Chat", "Removed the last exchange")
+}
+
+func cmdRetry(ctx context.Context, env *CommandEnv, _ string) error {
+	i := lastExchange(env.Session.Messages)
+	if i < 0 {
+		return errors.New("nothing to retry")
+	}
+	content := env.Session.Messages[i].Content
+	previous := append([]ask.ChatMessage(nil), env.Session.Messages[i:]...)
+	env.Session.Messages = env.Session.Messages[:i]
+	// A cached answer would just repeat itself.
+	canRead := env.r.canRead
+	env.r.canRead = false
+	ok := env.r.exchange(ctx, typedLine(content), content)
+	env.r.canRead = canRead
+	if !ok {
+		// Put the old exchange back so a failed retry loses nothing.
+		env.Session.Messages = append(env.Session.Messages, previous...)
+		return shared.PrintBox(env.Out, "Chat", "Retry failed; kept the previous answer")
+	}
+	return nil
+}
+
+// typedLine returns the text the user typed in content, without the file blocks added by /file.
+func typedLine(content string) string {
+	if !strings.HasPrefix(content, "File ") {
+		return content
+	}
+	if i := strings.LastIndex(content, "\n```\n\n"); i >= 0 {
+		return content[i+len("\n```\n\n"):]
+	}
+	return content
+}
+
+func cmdSave(ctx context.Context, env *CommandEnv, path string) error {
+	if path == "" {
+		env.r.persist = true
+		env.r.save(ctx)
+		return shared.PrintBox(env.Out, "Chat", fmt.Sprintf("Session saved as %s", env.Session.ID))
+	}
+	return exportSession(env, path, "")
+}
+
+// cmdExport takes a path, a format, or "<format> <path>"; without a path the file is
+// named after the session in the current directory.
+func cmdExport(ctx context.Context, env *CommandEnv, args string) error {
+	fields := strings.Fields(args)
+	var format export.Format
+	if len(fields) > 0 {
+		if f, err := export.ParseFormat(fields[0]); err == nil {
+			format, fields = f, fields[1:]
+		}
+	}
+	if len(fields) > 1 {
+		return errors.New("usage: /export [md|json|html] [path]")
+	}
+	path := ""
+	if len(fields) == 1 {
+		path = fields[0]
+	} else {
+		if format == "" {
+			format = export.FormatMarkdown
+		}
+		path = fmt.Sprintf("gaia-chat-%s.%s", env.Session.ID, format)
+	}
+	return exportSession(env, path, format)
+}
+
+func exportSession(env *CommandEnv, path string, format export.Format) error {
+	if err := export.WriteFile(path, sessionDocument(env.Session, env.r), format); err != nil {
+		return err
+	}
+	return shared.PrintBox(env.Out, "Chat", fmt.Sprintf("Session written to %s", path))
+}
+
+// sessionDocument describes s for export; token usage covers the current run only.
+func sessionDocument(s *Session, r *runner) export.Document {
+	doc := export.Document{
+		Title:     s.Title,
+		Source:    "chat",
+		Provider:  s.Provider,
+		Model:     s.Model,
+		Role:      s.Role,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		Summary:   s.Summary,
+		Metadata:  map[string]string{"Session": s.ID},
+	}
+	if r != nil && r.usage != (ask.Usage{}) {
+		doc.Usage = &export.Usage{PromptTokens: r.usage.PromptTokens, CompletionTokens: r.usage.CompletionTokens, Estimated: r.estimated}
+	}
+	for _, m := range s.Messages {
+		doc.Messages = append(doc.Messages, export.Message{Role: m.Role, Content: m.Content})
+	}
+	return doc
+}
+
+func cmdCopy(ctx context.Context, env *CommandEnv, _ string) error {
+	answer := env.LastAnswer()
+	if answer == "" {
+		return errors.New("no answer to copy yet")
+	}
+	terminal := env.Terminal
+	if terminal == nil {
+		terminal = env.Out
+	}
+	if err := shared.CopyOSC52(terminal, answer); err != nil {
+		return err
+	}
+	return shared.PrintBox(env.Out, "Chat", fmt.Sprintf("Copied %d characters to the clipboard", len([]rune(answer))))
+}
+
+func cmdFile(ctx context.Context, env *CommandEnv, path string) error {
+	if path == "" {
+		return errors.New("usage: /file <path>")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxAttachmentBytes {
+		return fmt.Errorf("%s is too large to attach (%d bytes, limit %d)", path, info.Size(), maxAttachmentBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	env.r.attachments = append(env.r.attachments, fmt.Sprintf("File %s:\n```\n%s\n```", path, strings.TrimRight(string(data), "\n")))
+	return shared.PrintBox(env.Out, "Chat", fmt.Sprintf("Attached %s (%d bytes); it is sent with your next message", path, len(data)))
+}
+
+func cmdCache(ctx context.Context, env *CommandEnv, args string) error {
+	r := env.r
+	switch args {
+	case "off":
+		r.canRead, r.canWrite = false, false
+	case "on":
+		if !cache.Enabled() {
+			return errors.New("the cache is disabled in the configuration (cache.enabled)")
+		}
+		r.canRead, r.canWrite = true, true
+	case "":
+	default:
+		return errors.New("usage: /cache [on|off]")
+	}
+	status := "off"
+	if r.canRead || r.canWrite {
+		status = "on"
+	}
+	return shared.PrintBox(env.Out, "Cache", "Cache is "+status+" for this session")
+}
+
+func cmdHistory(ctx context.Context, env *CommandEnv, _ string) error {
+	return shared.PrintBox(env.Out, "History", FormatHistory(env.Session, env.r.lastSystem))
+}
+
+func cmdCompact(ctx context.Context, env *CommandEnv, args string) error {
+	keep := keepTurns()
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 {
+			return errors.New("usage: /compact [turns to keep, at least 1]")
+		}
+		keep = n
+	}
+	n, err := env.r.compact(ctx, keep)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return shared.PrintBox(env.Out, "History", "Nothing to compact")
+	}
+	env.r.save(ctx)
+	return shared.PrintBox(env.Out, "History", fmt.Sprintf("Summarized %d turns; %d messages kept verbatim", n, len(env.Session.Messages)))
+}
@@ -0,0 +1,230 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gaia/plugins/ask"
+)
+
+// countingProvider answers "answer N" and records the messages it was sent.
+type countingProvider struct {
+	calls  int
+	last   []ask.ChatMessage
+	system string
+	err    error // returned instead of an answer when set
+}
+
+func (p *countingProvider) Name() string { return "ollama" }
+
+func (p *countingProvider) Send(_ context.Context, req ask.AskRequest) (ask.AskResponse, error) {
+	p.calls++
+	p.last = append([]ask.ChatMessage(nil), req.Messages...)
+	p.system = req.SystemPrompt
+	if p.err != nil {
+		return ask.AskResponse{}, p.err
+	}
+	return ask.AskResponse{Text: fmt.Sprintf("answer %d", p.calls)}, nil
+}
+
+func (p *countingProvider) SendStream(ctx context.Context, req ask.AskRequest, onChunk func(string)) (ask.AskResponse, error) {
+	resp, err := p.Send(ctx, req)
+	onChunk(resp.Text)
+	return resp, err
+}
+
+func newTestEnv(t *testing.T) (*CommandEnv, *countingProvider, *bytes.Buffer) {
+	t.Helper()
+	provider := &countingProvider{}
+	var out bytes.Buffer
+	session := NewSession("ollama", "llama3", "")
+	r := &runner{
+		provider:  provider,
+		providers: map[string]ask.Provider{"ollama": provider},
+		req:       ask.AskRequest{Provider: "ollama", Host: "localhost", Port: 11434, Model: "llama3", Timeout: time.Second},
+		session:   session,
+		out:       &out,
+		errOut:    &out,
+	}
+	return &CommandEnv{Session: session, Out: &out, ErrOut: &out, r: r}, provider, &out
+}
+
+func TestDispatch(t *testing.T) {
+	env, _, _ := newTestEnv(t)
+	reg := newCommandRegistry()
+	if handled, err := reg.Dispatch(context.Background(), env, "hello"); handled || err != nil {
+		t.Errorf("plain message: handled=%t err=%v", handled, err)
+	}
+	if handled, _ := reg.Dispatch(context.Background(), env, "//etc/hosts?"); handled {
+		t.Errorf("a // line is a message, not a command")
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/nope"); err == nil || !strings.Contains(err.Error(), "unknown command /nope") {
+		t.Errorf("unknown command error = %v", err)
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/exit"); !errors.Is(err, errExitChat) {
+		t.Errorf("/exit should end the chat, got %v", err)
+	}
+}
+
+func TestRegisterPluginCommand(t *testing.T) {
+	env, _, out := newTestEnv(t)
+	reg := newCommandRegistry()
+	got := ""
+	if err := reg.Register(Command{Name: "/echo", Summary: "Echo", Run: func(_ context.Context, _ *CommandEnv, args string) error {
+		got = args
+		return nil
+	}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := reg.Register(Command{Name: "help", Run: func(context.Context, *CommandEnv, string) error { return nil }}); err == nil {
+		t.Errorf("a plugin must not replace a built-in command")
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/echo  hi there "); err != nil || got != "hi there" {
+		t.Errorf("/echo got %q, err %v", got, err)
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/help"); err != nil || !strings.Contains(out.String(), "/echo") {
+		t.Errorf("/help should list plugin commands:\n%s", out.String())
+	}
+}
+
+func TestUndoAndRetry(t *testing.T) {
+	env, provider, _ := newTestEnv(t)
+	reg := newCommandRegistry()
+	ctx := context.Background()
+	env.r.turn(ctx, "first")
+	env.r.turn(ctx, "second")
+
+	if _, err := reg.Dispatch(ctx, env, "/retry"); err != nil {
+		t.Fatalf("/retry: %v", err)
+	}
+	msgs := env.Session.Messages
+	if len(msgs) != 4 || msgs[2].Content != "second" || msgs[3].Content != "answer 3" {
+		t.Fatalf("after /retry messages = %+v", msgs)
+	}
+	if len(provider.last) != 3 {
+		t.Errorf("retry should resend the history without the old answer, got %+v", provider.last)
+	}
+
+	if _, err := reg.Dispatch(ctx, env, "/undo"); err != nil {
+		t.Fatalf("/undo: %v", err)
+	}
+	if len(env.Session.Messages) != 2 || env.Session.Messages[1].Content != "answer 1" {
+		t.Errorf("after /undo messages = %+v", env.Session.Messages)
+	}
+	env.Session.Messages = nil
+	if _, err := reg.Dispatch(ctx, env, "/undo"); err == nil {
+		t.Errorf("/undo on an empty session should fail")
+	}
+}
+
+func TestRetryKeepsExchangeOnError(t *testing.T) {
+	env, provider, out := newTestEnv(t)
+	reg := newCommandRegistry()
+	ctx := context.Background()
+	env.r.turn(ctx, "first")
+	env.r.turn(ctx, "second")
+
+	provider.err = errors.New("connection refused")
+	if _, err := reg.Dispatch(ctx, env, "/retry"); err != nil {
+		t.Fatalf("/retry: %v", err)
+	}
+	msgs := env.Session.Messages
+	if len(msgs) != 4 || msgs[2].Content != "second" || msgs[3].Content != "answer 2" {
+		t.Errorf("a failed retry should keep the previous exchange, got %+v", msgs)
+	}
+	if !strings.Contains(out.String(), "kept the previous answer") {
+		t.Errorf("a failed retry should say so:\n%s", out.String())
+	}
+}
+
+func TestTypedLine(t *testing.T) {
+	content := "File a.txt:\n```\nx\n```\n\nFile b.txt:\n```\ny\n```\n\nwhat is wrong?"
+	if got := typedLine(content); got != "what is wrong?" {
+		t.Errorf("typedLine = %q", got)
+	}
+	if got := typedLine("File names matter"); got != "File names matter" {
+		t.Errorf("typedLine without attachments = %q", got)
+	}
+}
+
+func TestFileAttachment(t *testing.T) {
+	env, provider, _ := newTestEnv(t)
+	reg := newCommandRegistry()
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("disk is full\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/file "+path); err != nil {
+		t.Fatalf("/file: %v", err)
+	}
+	env.r.turn(context.Background(), "what now?")
+	sent := provider.last[0].Content
+	if !strings.Contains(sent, "disk is full") || !strings.HasSuffix(sent, "what now?") {
+		t.Errorf("attachment not sent with the message: %q", sent)
+	}
+	env.r.turn(context.Background(), "and then?")
+	if got := provider.last[2].Content; got != "and then?" {
+		t.Errorf("attachment should only go with one message, got %q", got)
+	}
+}
+
+func TestModelAndCopy(t *testing.T) {
+	t.Setenv("TMUX", "")
+	env, _, out := newTestEnv(t)
+	reg := newCommandRegistry()
+	if _, err := reg.Dispatch(context.Background(), env, "/model qwen3"); err != nil {
+		t.Fatalf("/model: %v", err)
+	}
+	if env.r.req.Model != "qwen3" || env.Session.Model != "qwen3" {
+		t.Errorf("model not switched: req %q, session %q", env.r.req.Model, env.Session.Model)
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/copy"); err == nil {
+		t.Errorf("/copy before any answer should fail")
+	}
+	env.r.turn(context.Background(), "hi")
+	out.Reset()
+	if _, err := reg.Dispatch(context.Background(), env, "/copy"); err != nil {
+		t.Fatalf("/copy: %v", err)
+	}
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("answer 1")) + "\a"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("/copy output = %q, want OSC 52 prefix %q", out.String(), want)
+	}
+}
+
+func TestExport(t *testing.T) {
+	env, _, _ := newTestEnv(t)
+	reg := newCommandRegistry()
+	env.r.turn(context.Background(), "hi")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.html")
+	if _, err := reg.Dispatch(context.Background(), env, "/export "+path); err != nil {
+		t.Fatalf("/export: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<!DOCTYPE html>") || !strings.Contains(string(data), "answer 1") {
+		t.Errorf("/export wrote an unexpected HTML file:\n%s", data)
+	}
+	path = filepath.Join(dir, "chat.txt")
+	if _, err := reg.Dispatch(context.Background(), env, "/export json "+path); err != nil {
+		t.Fatalf("/export json: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), `"content": "hi"`) {
+		t.Errorf("/export json did not honour the format:\n%s", data)
+	}
+	if _, err := reg.Dispatch(context.Background(), env, "/export md a b"); err == nil {
+		t.Errorf("/export with two paths should fail")
+	}
+}
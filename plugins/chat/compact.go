@@ -0,0 +1,146 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gaia/plugins/ask"
+	"gaia/plugins/shared"
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/viper"
+)
+
+const compactPrompt = "You maintain the memory of a conversation between a user and an assistant. " +
+	"Write a compact summary of the conversation below, merged with the earlier summary if there is one. " +
+	"Keep facts, decisions, names, file paths, commands and open questions; drop pleasantries. " +
+	"Reply with the summary only, as short bullet points."
+
+// compactBudget returns the token count past which the history is compacted, or 0 when
+// compaction is off. It stays under the sanitize cap so messages are summarized before
+// they would be cut.
+func compactBudget() int {
+	budget := viper.GetInt("chat.compact.max_tokens")
+	if budget <= 0 {
+		return 0
+	}
+	if limit := viper.GetInt("sanitize.max_tokens_after"); viper.GetBool("sanitize.enabled") && limit > 0 && limit*3/4 < budget {
+		budget = limit * 3 / 4
+	}
+	return budget
+}
+
+func keepTurns() int {
+	if n := viper.GetInt("chat.compact.keep_turns"); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// historyTokens estimates the tokens the next request carries besides the new message.
+func historyTokens(s *Session, systemPrompt string) int {
+	total := sanitizepkg.EstimateTokens(systemPrompt) + sanitizepkg.EstimateTokens(s.Summary)
+	for _, m := range s.Messages {
+		total += sanitizepkg.EstimateTokens(m.Content)
+	}
+	return total
+}
+
+// compactCut returns the index of the first message kept verbatim when the last keep turns
+// stay, or 0 when there is nothing older to summarize.
+func compactCut(messages []ask.ChatMessage, keep int) int {
+	turns := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			turns++
+			if turns == keep {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// maybeCompact summarizes older turns once the history passes the budget.
+func (r *runner) maybeCompact(ctx context.Context) {
+	budget := compactBudget()
+	if budget == 0 || historyTokens(r.session, r.lastSystem) <= budget {
+		return
+	}
+	n, err := r.compact(ctx, keepTurns())
+	if err != nil {
+		_ = shared.PrintError(r.errOut, fmt.Sprintf("Compacting chat history failed: %v", err))
+		return
+	}
+	if n > 0 {
+		_ = shared.PrintRaw(r.errOut, fmt.Sprintf("[chat] summarized %d earlier turns to stay under %d tokens (/history shows the summary)\n", n, budget))
+	}
+}
+
+// compact replaces all but the last keep turns with a model-written summary, merged into
+// the session summary. It returns the number of turns summarized.
+func (r *runner) compact(ctx context.Context, keep int) (int, error) {
+	cut := compactCut(r.session.Messages, keep)
+	if cut == 0 {
+		return 0, nil
+	}
+	older := r.session.Messages[:cut]
+	var convo strings.Builder
+	if r.session.Summary != "" {
+		fmt.Fprintf(&convo, "Earlier summary:\n%s\n\n", r.session.Summary)
+	}
+	turns := 0
+	for _, m := range older {
+		if m.Role == "user" {
+			turns++
+		}
+		fmt.Fprintf(&convo, "%s: %s\n", m.Role, m.Content)
+	}
+	req := r.req
+	req.SystemPrompt = compactPrompt
+	req.Messages = nil
+	req.Message = convo.String()
+	req.ProgressOut = nil
+	resp, err := r.provider.Send(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	summary := strings.TrimSpace(resp.Text)
+	if summary == "" {
+		return 0, errors.New("the model returned an empty summary")
+	}
+	r.session.Summary = summary
+	r.session.Compacted += turns
+	r.session.Messages = append([]ask.ChatMessage(nil), r.session.Messages[cut:]...)
+	return turns, nil
+}
+
+// withSummary adds the session summary to the system prompt of a turn.
+func withSummary(systemPrompt, summary string) string {
+	if summary == "" {
+		return systemPrompt
+	}
+	block := "Summary of the earlier conversation:\n" + summary
+	if systemPrompt == "" {
+		return block
+	}
+	return systemPrompt + "\n\n" + block
+}
+
+// FormatHistory describes the session history: the summary of compacted turns and what is
+// kept verbatim.
+func FormatHistory(s *Session, systemPrompt string) string {
+	var b strings.Builder
+	if s.Summary != "" {
+		fmt.Fprintf(&b, "Summary of %d earlier turns:\n%s\n\n", s.Compacted, s.Summary)
+	} else {
+		b.WriteString("No earlier turns have been summarized.\n\n")
+	}
+	fmt.Fprintf(&b, "Kept verbatim: %d messages, about %d tokens in total", len(s.Messages), historyTokens(s, systemPrompt))
+	if budget := compactBudget(); budget > 0 {
+		fmt.Fprintf(&b, " (compaction at %d)", budget)
+	}
+	return b.String()
+}
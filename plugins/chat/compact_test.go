@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gaia/plugins/ask"
+
+	"github.com/spf13/viper"
+)
+
+func TestCompactCut(t *testing.T) {
+	msgs := []ask.ChatMessage{
+		{Role: "user", Content: "1"}, {Role: "assistant", Content: "a"},
+		{Role: "user", Content: "2"}, {Role: "assistant", Content: "b"},
+		{Role: "user", Content: "3"}, {Role: "assistant", Content: "c"},
+	}
+	if got := compactCut(msgs, 2); got != 2 {
+		t.Errorf("keep 2 turns: cut = %d, want 2", got)
+	}
+	if got := compactCut(msgs, 3); got != 0 {
+		t.Errorf("keep all turns: cut = %d, want 0", got)
+	}
+}
+
+func TestMaybeCompact(t *testing.T) {
+	viper.Set("chat.compact.max_tokens", 20)
+	viper.Set("chat.compact.keep_turns", 1)
+	t.Cleanup(func() {
+		viper.Set("chat.compact.max_tokens", nil)
+		viper.Set("chat.compact.keep_turns", nil)
+	})
+
+	env, provider, _ := newTestEnv(t)
+	r := env.r
+	long := strings.Repeat("word ", 20)
+	r.turn(context.Background(), long)
+	if r.session.Summary != "" {
+		t.Fatalf("a single turn has nothing older to summarize, got summary %q", r.session.Summary)
+	}
+	r.turn(context.Background(), long)
+	if r.session.Summary != "answer 3" || r.session.Compacted != 1 {
+		t.Fatalf("summary = %q, compacted = %d", r.session.Summary, r.session.Compacted)
+	}
+	if len(r.session.Messages) != 2 || r.session.Messages[1].Content != "answer 2" {
+		t.Errorf("last turn should stay verbatim, got %+v", r.session.Messages)
+	}
+
+	viper.Set("chat.compact.max_tokens", 1000)
+	r.turn(context.Background(), "next")
+	if !strings.Contains(provider.system, "Summary of the earlier conversation:\nanswer 3") {
+		t.Errorf("summary not sent with the next turn, system prompt = %q", provider.system)
+	}
+	if got := FormatHistory(r.session, ""); !strings.Contains(got, "Summary of 1 earlier turns") {
+		t.Errorf("FormatHistory = %q", got)
+	}
+}
+
+func TestCompactBudgetFollowsSanitizeCap(t *testing.T) {
+	viper.Set("chat.compact.max_tokens", 6000)
+	viper.Set("sanitize.enabled", true)
+	viper.Set("sanitize.max_tokens_after", 2000)
+	t.Cleanup(func() {
+		viper.Set("chat.compact.max_tokens", nil)
+		viper.Set("sanitize.enabled", nil)
+		viper.Set("sanitize.max_tokens_after", nil)
+	})
+	if got := compactBudget(); got != 1500 {
+		t.Errorf("compactBudget = %d, want 1500", got)
+	}
+}
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -10,9 +11,8 @@ import (
 	"gaia/kernel"
 	"gaia/plugins/ask"
 	"gaia/plugins/cache"
-	"gaia/plugins/mempalace"
-	"gaia/plugins/roles"
 	"gaia/plugins/shared"
+	"gaia/plugins/shared/export"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -43,6 +43,10 @@ func (p *ChatPlugin) ConfigSchema() []string {
 		"chat.model",
 		"chat.timeout_seconds",
 		"chat.role",
+		"chat.sessions_dir",
+		"chat.auto_title",
+		"chat.compact.max_tokens",
+		"chat.compact.keep_turns",
 	}
 }
 
@@ -57,8 +61,9 @@ func (p *ChatPlugin) RegisterProvider(provider ask.Provider) {
 
 func (p *ChatPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 	cmd := &cobra.Command{
-		Use:   "chat",
+		Use:   "chat [--resume [id|last]]",
 		Short: "Start a chat session",
+		Args:  resumeArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			req := ask.AskRequest{
 				Provider:        ask.FirstNonEmpty(viper.GetString("chat.provider"), viper.GetString("provider")),
@@ -77,24 +82,22 @@ func (p *ChatPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 			if strings.TrimSpace(req.Provider) == "" {
 				req.Provider = ask.ResolveProviderFromModel(req.Model)
 			}
+			session, err := p.openSession(cmd, req, firstArg(args))
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			req.Provider, req.Model = session.Provider, session.Model
 			if err := validateChatConfig(req); err != nil {
 				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
 			}
 
-			provider, ok := p.providers[req.Provider]
-			if !ok {
-				if fallback, hasFallback := p.providers["ollama"]; hasFallback {
-					provider = fallback
-				} else {
-					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Unknown provider %q", req.Provider))
-				}
+			provider, err := ask.SelectProvider(p.providers, req.Provider)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if pull, _ := cmd.Flags().GetBool("pull"); pull {
+				req.Pull = true
 			}
-
-			_ = shared.PrintBox(cmd.OutOrStdout(), "Chat", "Starting chat session. Type 'exit' to end.")
-			reader := bufio.NewReader(cmd.InOrStdin())
-			history := []ask.ChatMessage{}
-			sessionID := time.Now().UTC().Format("20060102T150405.000000000Z")
-			assistantTurns := 0
 			noCache, _ := cmd.Flags().GetBool("no-cache")
 			refreshCache, _ := cmd.Flags().GetBool("refresh-cache")
 			if !cmd.Flags().Lookup("refresh-cache").Changed {
@@ -103,13 +106,49 @@ func (p *ChatPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 			if noCache {
 				refreshCache = false
 			}
-			canRead := cache.Enabled() && !noCache && !refreshCache
-			canWrite := cache.Enabled() && !noCache
-			baseRole := strings.TrimSpace(viper.GetString("chat.role"))
-			if pull, _ := cmd.Flags().GetBool("pull"); pull {
-				req.Pull = true
+			showThinking, _ := cmd.Flags().GetBool("show-thinking")
+			noSave, _ := cmd.Flags().GetBool("no-save")
+			r := &runner{
+				provider:   provider,
+				providers:  p.providers,
+				req:        req,
+				session:    session,
+				canRead:    cache.Enabled() && !noCache && !refreshCache,
+				canWrite:   cache.Enabled() && !noCache,
+				persist:    !noSave,
+				streamOpts: shared.StreamOptions{ShowThinking: showThinking, Markdown: !viper.GetBool("raw"), ThinkingOut: cmd.ErrOrStderr()},
+				out:        cmd.OutOrStdout(),
+				errOut:     cmd.ErrOrStderr(),
 			}
 
+			commands := newCommandRegistry()
+			for _, pl := range k.EnabledPlugins() {
+				if cp, ok := pl.(CommandProvider); ok {
+					for _, c := range cp.ChatCommands() {
+						if err := commands.Register(c); err != nil {
+							_ = shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("plugin %s: %v", pl.ID(), err))
+						}
+					}
+				}
+			}
+			env := &CommandEnv{Session: session, Out: cmd.OutOrStdout(), ErrOut: cmd.ErrOrStderr(), r: r}
+
+			if useTUI, _ := cmd.Flags().GetBool("tui"); useTUI {
+				if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
+					return shared.PrintError(cmd.ErrOrStderr(), "--tui needs an interactive terminal")
+				}
+				if err := runTUI(cmd.Context(), r, commands, env); err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Chat interface failed: %v", err))
+				}
+				return nil
+			}
+
+			if len(session.Messages) > 0 {
+				_ = shared.PrintBox(cmd.OutOrStdout(), "Chat", fmt.Sprintf("Resumed %q (%s, %d messages). Type /help for commands, 'exit' to end.", session.Title, session.ID, len(session.Messages)))
+			} else {
+				_ = shared.PrintBox(cmd.OutOrStdout(), "Chat", "Starting chat session. Type /help for commands, 'exit' to end.")
+			}
+			reader := bufio.NewReader(cmd.InOrStdin())
 			for {
 				_ = shared.PrintPrompt(cmd.OutOrStdout(), "You: ")
 				line, err := reader.ReadString('\n')
@@ -128,140 +167,18 @@ func (p *ChatPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 					_ = shared.PrintBox(cmd.OutOrStdout(), "Chat", "Chat session ended.")
 					return nil
 				}
-
-				history = append(history, ask.ChatMessage{Role: "user", Content: line})
-				req.Messages = history
-				req.SystemPrompt = ""
-				if ctxPrompt, err := mempalace.SearchContextIfEnabled(cmd.Context(), line); err != nil {
-					_ = shared.PrintError(cmd.ErrOrStderr(), err.Error())
-					continue
-				} else if ctxPrompt != "" {
-					req.SystemPrompt = ctxPrompt
-				} else {
-					roleName := baseRole
-					if roleName == "" && viper.GetBool("roles.auto_select") {
-						kw := roles.LoadKeywordConfig()
-						weight := viper.GetFloat64("roles.scoring.weight")
-						if weight == 0 {
-							weight = 1.0
-						}
-						threshold := viper.GetFloat64("roles.scoring.min_threshold")
-						defaultRole := viper.GetString("roles.default_role")
-						res := roles.SelectRoleForText(line, kw, weight, threshold, defaultRole)
-						roleName = res.RoleName
-						if viper.GetBool("roles.debug") {
-							roles.SetDebugWriter(cmd.ErrOrStderr())
-							roles.LogScores(res.AllScores, res.Threshold, res.RoleName)
-						}
-					}
-					if roleName != "" {
-						rolesList, err := roles.LoadRolesWithDefaults()
-						if err != nil {
-							_ = shared.PrintError(cmd.ErrOrStderr(), err.Error())
-							continue
-						}
-						resolved, err := roles.ResolveInheritance(rolesList)
-						if err != nil {
-							_ = shared.PrintError(cmd.ErrOrStderr(), err.Error())
-							continue
-						}
-						role, ok := resolved[roleName]
-						if !ok {
-							_ = shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("role %q not found", roleName))
-							continue
-						}
-						req.SystemPrompt = roles.ResolveSystemPrompt(role, req.Provider, req.Model)
-					}
-				}
-				if memCtx, err := mempalace.InjectIfEnabled(cmd.Context(), line); err != nil {
-					_ = shared.PrintError(cmd.ErrOrStderr(), err.Error())
-					continue
-				} else if memCtx != "" {
-					req.SystemPrompt = mempalace.AppendMemory(req.SystemPrompt, memCtx)
-				}
-
-				cacheKey := ""
-				if canWrite {
-					label := ask.BuildLabel("chat", line)
-					keyPayload := cache.KeyPayload{
-						PluginID: "chat",
-						Provider: provider.Name(),
-						Host:     req.Host,
-						Port:     req.Port,
-						Model:    req.Model,
-						Messages: toCacheMessages(history),
-						Label:    label,
-					}
-					key, err := cache.BuildKey(keyPayload)
-					if err == nil {
-						cacheKey = key
-						if canRead {
-							if entry, ok, err := cache.Get(cacheKey); err == nil && ok {
-								history = append(history, ask.ChatMessage{Role: "assistant", Content: entry.Response})
-								assistantTurns++
-								if err := mempalace.PersistChatTurn(cmd.Context(), sessionID, assistantTurns, line, entry.Response); err != nil && viper.GetBool("debug") {
-									_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
-								}
-								_ = shared.PrintBox(cmd.OutOrStdout(), "Assistant", entry.Response)
-								continue
-							}
-						}
-					}
+				handled, err := commands.Dispatch(cmd.Context(), env, line)
+				if errors.Is(err, errExitChat) {
+					_ = shared.PrintBox(cmd.OutOrStdout(), "Chat", "Chat session ended.")
+					return nil
 				}
-
-				sreq := ask.ApplySanitize(cmd.ErrOrStderr(), req)
-				finalText, err := shared.DisplayStreamedAnswer(cmd.Context(), cmd.OutOrStdout(), "Assistant", func(send func(string)) (string, error) {
-					var streamed strings.Builder
-					cleared := false
-					resp, streamErr := provider.SendStream(cmd.Context(), sreq, func(chunk string) {
-						if strings.TrimSpace(chunk) == "" {
-							return
-						}
-						if !cleared {
-							sreq.ProgressClearer.ClearOnce(cmd.ErrOrStderr())
-							cleared = true
-						}
-						send(chunk)
-						streamed.WriteString(chunk)
-					})
-					if streamErr != nil {
-						return "", streamErr
-					}
-					if resp.Text == "" {
-						resp.Text = streamed.String()
-					}
-					return resp.Text, nil
-				})
 				if err != nil {
-					_ = shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Ask failed: %v", err))
-					continue
+					_ = shared.PrintError(cmd.ErrOrStderr(), err.Error())
 				}
-				if strings.TrimSpace(finalText) == "" {
-					_ = shared.PrintError(cmd.ErrOrStderr(), "Ask returned an empty response")
+				if handled {
 					continue
 				}
-				history = append(history, ask.ChatMessage{Role: "assistant", Content: finalText})
-				assistantTurns++
-				if err := mempalace.PersistChatTurn(cmd.Context(), sessionID, assistantTurns, line, finalText); err != nil && viper.GetBool("debug") {
-					_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
-				}
-				if err := mempalace.DiaryWriteIfEnabled(cmd.Context(), line, finalText); err != nil && viper.GetBool("debug") {
-					_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace diary write failed: %v\n", err))
-				}
-				if canWrite && cacheKey != "" {
-					_ = cache.Set(cache.Entry{
-						Key:       cacheKey,
-						Label:     ask.BuildLabel("chat", line),
-						PluginID:  "chat",
-						Provider:  provider.Name(),
-						Host:      req.Host,
-						Port:      req.Port,
-						Model:     req.Model,
-						Messages:  toCacheMessages(history),
-						Response:  finalText,
-						CreatedAt: time.Now().UTC(),
-					})
-				}
+				r.turn(cmd.Context(), strings.TrimPrefix(line, "/"))
 			}
 		},
 	}
@@ -271,9 +188,13 @@ func (p *ChatPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 	cmd.Flags().String("model", "", "Model name (overrides chat.model)")
 	cmd.Flags().Int("timeout", 0, "Request timeout in seconds (overrides chat.timeout_seconds)")
 	cmd.Flags().Bool("no-cache", false, "Disable cache for this session")
+	cmd.Flags().Bool("show-thinking", false, "Show model reasoning in a dimmed pane above each reply")
 	cmd.Flags().Bool("refresh-cache", false, "Refresh cache for this session")
 	cmd.Flags().String("role", "", "Role name to apply to the session")
 	cmd.Flags().Bool("pull", false, "Pull model from Ollama if available (force refresh)")
+	cmd.Flags().Bool("resume", false, "Resume the saved session named by the argument (ID, unique prefix or 'last'), or the most recent one")
+	cmd.Flags().Bool("no-save", false, "Do not save this session to disk")
+	cmd.Flags().Bool("tui", false, "Use the full-screen chat interface")
 
 	_ = viper.BindPFlag("chat.host", cmd.Flags().Lookup("host"))
 	_ = viper.BindPFlag("chat.port", cmd.Flags().Lookup("port"))
@@ -282,19 +203,157 @@ func (p *ChatPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 	_ = viper.BindPFlag("cache.refresh", cmd.Flags().Lookup("refresh-cache"))
 	_ = viper.BindPFlag("chat.role", cmd.Flags().Lookup("role"))
 
+	cmd.AddCommand(sessionsCommand())
 	return []*cobra.Command{cmd}, nil
 }
 
+// resumeArgs accepts a session ID only with --resume, so a stray word fails instead of
+// starting a session the user did not ask for.
+func resumeArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+		return err
+	}
+	if resume, _ := cmd.Flags().GetBool("resume"); len(args) == 1 && !resume {
+		return fmt.Errorf("unexpected argument %q (use --resume %s to continue a saved session)", args[0], args[0])
+	}
+	return nil
+}
+
+// openSession resumes the session named by id with --resume (the most recent one when id is
+// empty), or starts a new one. A resumed session keeps its model and role unless --model or
+// --role is given.
+func (p *ChatPlugin) openSession(cmd *cobra.Command, req ask.AskRequest, id string) (*Session, error) {
+	role := strings.TrimSpace(viper.GetString("chat.role"))
+	if resume, _ := cmd.Flags().GetBool("resume"); !resume {
+		return NewSession(req.Provider, req.Model, role), nil
+	}
+	session, err := LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Flags().Lookup("model").Changed {
+		session.Model = req.Model
+		session.Provider = req.Provider
+	}
+	if cmd.Flags().Lookup("role").Changed {
+		session.Role = role
+	}
+	if strings.TrimSpace(session.Provider) == "" {
+		session.Provider = req.Provider
+	}
+	if strings.TrimSpace(session.Model) == "" {
+		session.Model = req.Model
+	}
+	return session, nil
+}
+
+func sessionsCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "sessions",
+		Short: "Manage saved chat sessions",
+	}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved chat sessions, most recent first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sessions, err := ListSessions()
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if len(sessions) == 0 {
+				return shared.PrintBox(cmd.OutOrStdout(), "Chat Sessions", "No saved sessions")
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Chat Sessions", FormatSessionList(sessions))
+		},
+	}
+	showCmd := &cobra.Command{
+		Use:   "show [id|last]",
+		Short: "Show a saved chat session",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			session, err := LoadSession(firstArg(args))
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if name, _ := cmd.Flags().GetString("format"); name != "" {
+				format, err := export.ParseFormat(name)
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				data, err := export.Render(sessionDocument(session, nil), format)
+				if err != nil {
+					return err
+				}
+				return shared.PrintRaw(cmd.OutOrStdout(), string(data))
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), session.Title, FormatTranscript(session))
+		},
+	}
+	showCmd.Flags().String("format", "", "Print the session as md, json or html instead of a box")
+	rmCmd := &cobra.Command{
+		Use:   "rm [id]",
+		Short: "Delete a saved chat session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			session, err := LoadSession(args[0])
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			// "last" and ID prefixes name a session indirectly; make sure it is the intended one.
+			if yes, _ := cmd.Flags().GetBool("yes"); session.ID != args[0] && !yes {
+				if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
+					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("%q matches session %s; pass its full ID or --yes to delete it", args[0], session.ID))
+				}
+				ok, err := shared.RunConfirmationPromptTUI(fmt.Sprintf("Delete session %s (%s)?", session.ID, session.Title), "Chat Sessions", cmd.InOrStdin(), cmd.OutOrStdout())
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				if !ok {
+					return shared.PrintBox(cmd.OutOrStdout(), "Chat Sessions", fmt.Sprintf("Kept %s", session.ID))
+				}
+			}
+			if err := DeleteSession(session.ID); err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Chat Sessions", fmt.Sprintf("Deleted %s", session.ID))
+		},
+	}
+	rmCmd.Flags().BoolP("yes", "y", false, "Delete the session named by \"last\" or an ID prefix without asking")
+	renameCmd := &cobra.Command{
+		Use:   "rename [id] [title]",
+		Short: "Rename a saved chat session",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			session, err := RenameSession(args[0], strings.Join(args[1:], " "))
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Chat Sessions", fmt.Sprintf("Renamed %s to %q", session.ID, session.Title))
+		},
+	}
+	root.AddCommand(listCmd, showCmd, rmCmd, renameCmd)
+	return root
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
 func validateChatConfig(req ask.AskRequest) error {
 	missing := []string{}
 	if strings.TrimSpace(req.Provider) == "" {
 		missing = append(missing, "chat.provider")
 	}
-	if strings.TrimSpace(req.Host) == "" {
-		missing = append(missing, "host")
-	}
-	if req.Port == 0 {
-		missing = append(missing, "port")
+	if ask.NeedsEndpoint(req.Provider) {
+		if strings.TrimSpace(req.Host) == "" {
+			missing = append(missing, "host")
+		}
+		if req.Port == 0 {
+			missing = append(missing, "port")
+		}
 	}
 	if strings.TrimSpace(req.Model) == "" {
 		missing = append(missing, "model")
@@ -0,0 +1,326 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gaia/plugins/ask"
+	"gaia/plugins/cache"
+	"gaia/plugins/mempalace"
+	"gaia/plugins/roles"
+	"gaia/plugins/shared"
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/viper"
+)
+
+// runner holds the state of one chat session and executes its turns.
+type runner struct {
+	provider    ask.Provider
+	providers   map[string]ask.Provider
+	req         ask.AskRequest // connection settings; Messages and SystemPrompt are set per turn
+	session     *Session
+	canRead     bool
+	canWrite    bool
+	persist     bool
+	streamOpts  shared.StreamOptions
+	out         io.Writer
+	errOut      io.Writer
+	attachments []string // file blocks added with /file, sent with the next message
+	lastSystem  string   // system prompt of the latest turn, shown by /system
+	display     displayFunc
+	usage       ask.Usage // tokens used by this chat run
+	estimated   bool      // usage includes estimates for providers that report none
+}
+
+// displayFunc shows a streamed reply; it has the signature of shared.DisplayStreamedAnswerWithOptions
+// without the writer. The TUI replaces it to stream into its transcript.
+type displayFunc func(ctx context.Context, title string, opts shared.StreamOptions, runStream func(ctx context.Context, send, think func(string)) (string, error)) (string, error)
+
+func (r *runner) displayStream(ctx context.Context, title string, opts shared.StreamOptions, runStream func(ctx context.Context, send, think func(string)) (string, error)) (string, error) {
+	if r.display != nil {
+		return r.display(ctx, title, opts, runStream)
+	}
+	return shared.DisplayStreamedAnswerWithOptions(ctx, r.out, title, opts, runStream)
+}
+
+// turn sends line, with any pending attachments, as the next user message.
+func (r *runner) turn(ctx context.Context, line string) {
+	content := line
+	if len(r.attachments) > 0 {
+		content = strings.Join(append(r.attachments, line), "\n\n")
+		r.attachments = nil
+	}
+	r.exchange(ctx, line, content)
+}
+
+// exchange appends content as a user message, displays the reply and saves the session.
+// line is the text typed by the user; it drives MemPalace search and role selection.
+// It reports whether the turn produced an answer.
+func (r *runner) exchange(ctx context.Context, line, content string) bool {
+	r.session.Messages = append(r.session.Messages, ask.ChatMessage{Role: "user", Content: content})
+	reply, ok := r.reply(ctx, line)
+	if !ok {
+		// Keep the user message only when the turn produced an answer, so a retry is clean.
+		r.session.Messages = r.session.Messages[:len(r.session.Messages)-1]
+		return false
+	}
+	r.session.Messages = append(r.session.Messages, ask.ChatMessage{Role: "assistant", Content: reply})
+	r.maybeCompact(ctx)
+	r.save(ctx)
+	return true
+}
+
+// reply gets the assistant answer to the conversation so far, from the cache or the provider.
+// It returns false when no answer should be recorded.
+func (r *runner) reply(ctx context.Context, line string) (string, bool) {
+	req := r.req
+	req.Messages = r.session.Messages
+	systemPrompt, role, err := r.systemPrompt(ctx, line)
+	if err != nil {
+		_ = shared.PrintError(r.errOut, err.Error())
+		return "", false
+	}
+	r.lastSystem = systemPrompt
+	req.SystemPrompt = withSummary(systemPrompt, r.session.Summary)
+	req.Role = role
+
+	cacheKey := ""
+	if r.canWrite {
+		keyPayload := cache.KeyPayload{
+			PluginID:     "chat",
+			Provider:     r.provider.Name(),
+			Host:         req.Host,
+			Port:         req.Port,
+			Model:        req.Model,
+			Role:         req.Role,
+			SystemPrompt: req.SystemPrompt,
+			Params:       ask.CacheParams(req),
+			Messages:     toCacheMessages(req.Messages),
+			Label:        ask.BuildLabel("chat", line),
+		}
+		if key, err := cache.BuildKey(keyPayload); err == nil {
+			cacheKey = key
+			if r.canRead {
+				if entry, ok, err := cache.Get(cacheKey); err == nil && ok {
+					r.persistTurn(ctx, line, entry.Response)
+					if r.display == nil {
+						// The TUI shows the reply from the session instead.
+						_ = shared.PrintAnswer(r.out, "Assistant", entry.Response, !r.streamOpts.Markdown)
+					}
+					return entry.Response, true
+				}
+			}
+		}
+	}
+
+	sreq := ask.ApplySanitize(r.errOut, req)
+	var usage ask.Usage
+	finalText, err := r.displayStream(ctx, "Assistant", r.streamOpts, func(ctx context.Context, send, think func(string)) (string, error) {
+		sreq.OnThinking = think
+		var streamed strings.Builder
+		cleared := false
+		resp, streamErr := r.provider.SendStream(ctx, sreq, func(chunk string) {
+			if strings.TrimSpace(chunk) == "" {
+				return
+			}
+			if !cleared {
+				sreq.ProgressClearer.ClearOnce(r.errOut)
+				cleared = true
+			}
+			send(chunk)
+			streamed.WriteString(chunk)
+		})
+		if streamErr != nil {
+			return "", streamErr
+		}
+		if resp.Text == "" {
+			resp.Text = streamed.String()
+		}
+		usage = resp.Usage
+		return resp.Text, nil
+	})
+	if errors.Is(err, shared.ErrStreamInterrupted) {
+		// Keep the partial reply so the conversation stays coherent, but do not cache it.
+		return finalText, strings.TrimSpace(finalText) != ""
+	}
+	if err != nil {
+		_ = shared.PrintError(r.errOut, fmt.Sprintf("Ask failed: %v", err))
+		return "", false
+	}
+	if strings.TrimSpace(finalText) == "" {
+		_ = shared.PrintError(r.errOut, "Ask returned an empty response")
+		return "", false
+	}
+	r.addUsage(sreq, usage, finalText)
+	r.persistTurn(ctx, line, finalText)
+	if err := mempalace.DiaryWriteIfEnabled(ctx, line, finalText); err != nil && viper.GetBool("debug") {
+		_ = shared.PrintRaw(r.errOut, fmt.Sprintf("[DEBUG] mempalace diary write failed: %v\n", err))
+	}
+	if r.canWrite && cacheKey != "" {
+		_ = cache.Set(cache.Entry{
+			Key:        cacheKey,
+			Label:      ask.BuildLabel("chat", line),
+			PluginID:   "chat",
+			Provider:   r.provider.Name(),
+			Host:       req.Host,
+			Port:       req.Port,
+			Model:      req.Model,
+			Role:       req.Role,
+			SystemHash: cache.SystemHash(req.SystemPrompt),
+			ParamsHash: cache.ParamsHash(ask.CacheParams(req)),
+			Messages:   append(toCacheMessages(req.Messages), cache.Message{Role: "assistant", Content: finalText}),
+			Response:   finalText,
+			CreatedAt:  time.Now().UTC(),
+		})
+	}
+	return finalText, true
+}
+
+// systemPrompt resolves the /system override, MemPalace context or the session role for line,
+// plus injected memory. It also returns the name of the role used, if any.
+func (r *runner) systemPrompt(ctx context.Context, line string) (string, string, error) {
+	prompt, roleName := "", ""
+	if r.session.System != "" {
+		prompt = r.session.System
+	} else if ctxPrompt, err := mempalace.SearchContextIfEnabled(ctx, line); err != nil {
+		return "", "", err
+	} else if ctxPrompt != "" {
+		prompt = ctxPrompt
+	} else {
+		roleName = r.session.Role
+		if roleName == "" && viper.GetBool("roles.auto_select") {
+			kw := roles.LoadKeywordConfig()
+			weight := viper.GetFloat64("roles.scoring.weight")
+			if weight == 0 {
+				weight = 1.0
+			}
+			threshold := viper.GetFloat64("roles.scoring.min_threshold")
+			defaultRole := viper.GetString("roles.default_role")
+			res := roles.SelectRoleForText(line, kw, weight, threshold, defaultRole)
+			roleName = res.RoleName
+			if viper.GetBool("roles.debug") {
+				roles.SetDebugWriter(r.errOut)
+				roles.LogScores(res.AllScores, res.Threshold, res.RoleName)
+			}
+		}
+		if roleName != "" {
+			role, err := loadRole(roleName)
+			if err != nil {
+				return "", "", err
+			}
+			prompt = roles.ResolveSystemPrompt(role, r.req.Provider, r.req.Model)
+		}
+	}
+	if memCtx, err := mempalace.InjectIfEnabled(ctx, line); err != nil {
+		return "", "", err
+	} else if memCtx != "" {
+		prompt = mempalace.AppendMemory(prompt, memCtx)
+	}
+	return prompt, roleName, nil
+}
+
+// addUsage counts the tokens of one reply, estimating them when the provider reports none.
+func (r *runner) addUsage(req ask.AskRequest, usage ask.Usage, answer string) {
+	if usage == (ask.Usage{}) {
+		prompt := req.SystemPrompt
+		for _, m := range req.Messages {
+			prompt += m.Content
+		}
+		usage = ask.Usage{PromptTokens: sanitizepkg.EstimateTokens(prompt), CompletionTokens: sanitizepkg.EstimateTokens(answer)}
+		r.estimated = true
+	}
+	r.usage.PromptTokens += usage.PromptTokens
+	r.usage.CompletionTokens += usage.CompletionTokens
+}
+
+func loadRole(name string) (roles.ResolvedRole, error) {
+	rolesList, err := roles.LoadRolesWithDefaults()
+	if err != nil {
+		return roles.ResolvedRole{}, err
+	}
+	resolved, err := roles.ResolveInheritance(rolesList)
+	if err != nil {
+		return roles.ResolvedRole{}, err
+	}
+	role, ok := resolved[name]
+	if !ok {
+		return roles.ResolvedRole{}, fmt.Errorf("role %q not found", name)
+	}
+	return role, nil
+}
+
+func (r *runner) persistTurn(ctx context.Context, line, answer string) {
+	turn := r.session.AssistantTurns() + 1
+	if err := mempalace.PersistChatTurn(ctx, r.session.ID, turn, line, answer); err != nil && viper.GetBool("debug") {
+		_ = shared.PrintRaw(r.errOut, fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
+	}
+}
+
+// save titles the session after its first exchange and writes it to disk.
+func (r *runner) save(ctx context.Context) {
+	if !r.persist {
+		return
+	}
+	if r.session.Title == "" {
+		r.session.Title = r.title(ctx)
+	}
+	forkedFrom := r.session.ForkedFrom
+	if err := SaveSession(r.session); err != nil {
+		_ = shared.PrintError(r.errOut, fmt.Sprintf("Saving chat session failed: %v", err))
+		return
+	}
+	if r.session.ForkedFrom != forkedFrom {
+		_ = shared.PrintRaw(r.errOut, fmt.Sprintf("Session %s was updated by another gaia process; continuing as %s\n", r.session.ForkedFrom, r.session.ID))
+	}
+}
+
+const titlePrompt = "Write a title of at most six words for the conversation below. " +
+	"Reply with the title only, without quotes or punctuation at the end."
+
+// title asks the model for a short session title, falling back to the first user message.
+func (r *runner) title(ctx context.Context) string {
+	fallback := fallbackTitle(r.session.Messages)
+	if !viper.GetBool("chat.auto_title") {
+		return fallback
+	}
+	var convo strings.Builder
+	for _, m := range r.session.Messages {
+		fmt.Fprintf(&convo, "%s: %s\n", m.Role, m.Content)
+	}
+	req := r.req
+	req.SystemPrompt = titlePrompt
+	req.Messages = nil
+	req.Message = convo.String()
+	req.ProgressOut = nil
+	resp, err := r.provider.Send(ctx, req)
+	if err != nil {
+		return fallback
+	}
+	if title := cleanTitle(resp.Text); title != "" {
+		return title
+	}
+	return fallback
+}
+
+func cleanTitle(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	line = strings.Trim(strings.TrimSpace(line), "\"'`*#. ")
+	if len([]rune(line)) > 60 {
+		line = string([]rune(line)[:60])
+	}
+	return strings.TrimSpace(line)
+}
+
+func fallbackTitle(messages []ask.ChatMessage) string {
+	for _, m := range messages {
+		if m.Role == "user" {
+			return cleanTitle(m.Content)
+		}
+	}
+	return "Untitled chat"
+}
@@ -0,0 +1,283 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gaia/plugins/ask"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/viper"
+)
+
+// Session is a chat conversation persisted as <sessions_dir>/<id>.json.
+type Session struct {
+	ID        string            `json:"id"`
+	Title     string            `json:"title"`
+	Provider  string            `json:"provider"`
+	Model     string            `json:"model"`
+	Role      string            `json:"role,omitempty"`
+	System    string            `json:"system,omitempty"`    // set with /system; replaces the role prompt
+	Summary   string            `json:"summary,omitempty"`   // older turns, summarized by compaction
+	Compacted int               `json:"compacted,omitempty"` // number of turns in Summary
+	Messages  []ask.ChatMessage `json:"messages"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	// ForkedFrom is the session this one split from when another process saved it first.
+	ForkedFrom string `json:"forked_from,omitempty"`
+}
+
+// ErrSessionNotFound is returned when no stored session matches an ID.
+var ErrSessionNotFound = errors.New("chat session not found")
+
+const sessionLockTimeout = 2 * time.Second
+
+// NewSession starts an unsaved session with a time-based ID.
+func NewSession(provider, model, role string) *Session {
+	now := time.Now().UTC()
+	return &Session{
+		ID:        now.Format("20060102-150405"),
+		Provider:  provider,
+		Model:     model,
+		Role:      role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// AssistantTurns counts the assistant replies in the session.
+func (s *Session) AssistantTurns() int {
+	n := 0
+	for _, m := range s.Messages {
+		if m.Role == "assistant" {
+			n++
+		}
+	}
+	return n
+}
+
+func sessionsDir() (string, error) {
+	dir := strings.TrimSpace(viper.GetString("chat.sessions_dir"))
+	if dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory for chat sessions: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "gaia", "chat", "sessions"), nil
+}
+
+// withSessionsLock runs fn while holding the sessions directory lock.
+func withSessionsLock(mode shared.Mode, fn func(dir string) error) error {
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return shared.With(filepath.Join(dir, ".lock"), mode, sessionLockTimeout, func() error {
+		return fn(dir)
+	})
+}
+
+// SaveSession writes the session, giving it a fresh ID if another session already owns it.
+// When another process saved the same session since it was loaded, the session is forked
+// under a new ID (see ForkedFrom) instead of overwriting those turns.
+func SaveSession(s *Session) error {
+	loaded := s.UpdatedAt
+	return withSessionsLock(shared.Exclusive, func(dir string) error {
+		existing, err := readSession(filepath.Join(dir, s.ID+".json"))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil || !existing.CreatedAt.Equal(s.CreatedAt):
+			s.ID = uniqueSessionID(dir, s.ID)
+		case !existing.UpdatedAt.Equal(loaded):
+			s.ForkedFrom = s.ID
+			s.ID = uniqueSessionID(dir, s.ID)
+			s.CreatedAt = time.Now().UTC()
+		}
+		s.UpdatedAt = time.Now().UTC()
+		return writeSession(dir, s)
+	})
+}
+
+func uniqueSessionID(dir, id string) string {
+	candidate := id
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, candidate+".json")); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", id, i)
+	}
+}
+
+func writeSession(dir string, s *Session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, s.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readSession(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("chat session %s: %w", filepath.Base(path), err)
+	}
+	return &s, nil
+}
+
+// ListSessions returns all sessions, most recently updated first.
+func ListSessions() ([]*Session, error) {
+	var sessions []*Session
+	err := withSessionsLock(shared.Shared, func(dir string) error {
+		var err error
+		sessions, err = listSessions(dir)
+		return err
+	})
+	return sessions, err
+}
+
+// listSessions reads the sessions of dir, most recently updated first. The caller holds the lock.
+func listSessions(dir string) ([]*Session, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*Session
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		s, err := readSession(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt) })
+	return sessions, nil
+}
+
+// LoadSession finds a session by ID, unique ID prefix, or "last" for the most recent one.
+func LoadSession(id string) (*Session, error) {
+	var s *Session
+	err := withSessionsLock(shared.Shared, func(dir string) error {
+		var err error
+		s, err = findSession(dir, id)
+		return err
+	})
+	return s, err
+}
+
+// findSession resolves id like LoadSession. The caller holds the lock.
+func findSession(dir, id string) (*Session, error) {
+	id = strings.TrimSpace(id)
+	sessions, err := listSessions(dir)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || id == "last" {
+		if len(sessions) == 0 {
+			return nil, ErrSessionNotFound
+		}
+		return sessions[0], nil
+	}
+	var matches []*Session
+	for _, s := range sessions {
+		if s.ID == id {
+			return s, nil
+		}
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("session ID %q is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// DeleteSession removes the stored session with exactly this ID; "last" and prefixes are
+// not accepted, so resolve them with LoadSession and confirm first.
+func DeleteSession(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, id)
+	}
+	return withSessionsLock(shared.Exclusive, func(dir string) error {
+		err := os.Remove(filepath.Join(dir, id+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s (use the full session ID)", ErrSessionNotFound, id)
+		}
+		return err
+	})
+}
+
+// RenameSession sets a session's title.
+func RenameSession(id, title string) (*Session, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	var s *Session
+	err := withSessionsLock(shared.Exclusive, func(dir string) error {
+		var err error
+		if s, err = findSession(dir, id); err != nil {
+			return err
+		}
+		s.Title = title
+		return writeSession(dir, s)
+	})
+	return s, err
+}
+
+// FormatSessionList renders one line per session: ID, update time, message count and title.
+func FormatSessionList(sessions []*Session) string {
+	var b strings.Builder
+	for _, s := range sessions {
+		fmt.Fprintf(&b, "%s\t%s\t%d msgs\t%s\n", s.ID, s.UpdatedAt.Local().Format("2006-01-02 15:04"), len(s.Messages), s.Title)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatTranscript renders the session's messages for display.
+func FormatTranscript(s *Session) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Model: %s (%s)\n", s.Model, s.Provider)
+	if s.Role != "" {
+		fmt.Fprintf(&b, "Role: %s\n", s.Role)
+	}
+	if s.Summary != "" {
+		fmt.Fprintf(&b, "\nSummary of %d earlier turns:\n%s\n", s.Compacted, s.Summary)
+	}
+	for _, m := range s.Messages {
+		label := "You"
+		if m.Role == "assistant" {
+			label = "Assistant"
+		}
+		fmt.Fprintf(&b, "\n%s: %s\n", label, strings.TrimSpace(m.Content))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,218 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gaia/kernel"
+	"gaia/plugins/ask"
+
+	"github.com/spf13/viper"
+)
+
+func useSessionsDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	viper.Set("chat.sessions_dir", dir)
+	t.Cleanup(func() { viper.Set("chat.sessions_dir", "") })
+	return dir
+}
+
+func TestSaveAndLoadSession(t *testing.T) {
+	useSessionsDir(t)
+	s := NewSession("ollama", "llama3", "default")
+	s.Title = "Greetings"
+	s.Messages = []ask.ChatMessage{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}
+	if err := SaveSession(s); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+
+	got, err := LoadSession("last")
+	if err != nil {
+		t.Fatalf("LoadSession(last): %v", err)
+	}
+	if got.ID != s.ID || got.Model != "llama3" || got.Role != "default" || len(got.Messages) != 2 {
+		t.Errorf("loaded session = %+v", got)
+	}
+	if got, err := LoadSession(s.ID[:8]); err != nil || got.ID != s.ID {
+		t.Errorf("LoadSession by prefix = %v, %v", got, err)
+	}
+	if _, err := LoadSession("nope"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("unknown ID should fail with ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestSaveSessionAvoidsIDCollision(t *testing.T) {
+	useSessionsDir(t)
+	first := NewSession("ollama", "llama3", "")
+	second := *first
+	second.CreatedAt = first.CreatedAt.Add(time.Millisecond)
+	if err := SaveSession(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSession(&second); err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == first.ID {
+		t.Fatalf("second session reused ID %s", first.ID)
+	}
+	id := first.ID
+	if err := SaveSession(first); err != nil || first.ID != id {
+		t.Errorf("resaving the first session should keep ID %s, got %s (%v)", id, first.ID, err)
+	}
+	sessions, err := ListSessions()
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ListSessions = %d sessions, %v", len(sessions), err)
+	}
+	if sessions[0].ID != first.ID {
+		t.Errorf("most recently saved session should be listed first, got %s", sessions[0].ID)
+	}
+}
+
+func TestRenameAndDeleteSession(t *testing.T) {
+	useSessionsDir(t)
+	s := NewSession("ollama", "llama3", "")
+	if err := SaveSession(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RenameSession(s.ID, "  New title "); err != nil {
+		t.Fatalf("RenameSession: %v", err)
+	}
+	if got, _ := LoadSession(s.ID); got.Title != "New title" {
+		t.Errorf("title = %q", got.Title)
+	}
+	for _, indirect := range []string{"last", s.ID[:8]} {
+		if err := DeleteSession(indirect); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("DeleteSession(%q) should require the exact ID, got %v", indirect, err)
+		}
+	}
+	if err := DeleteSession(s.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := LoadSession(s.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("deleted session still loads: %v", err)
+	}
+}
+
+// Two processes resuming the same session must not overwrite each other's turns.
+func TestSaveSessionForksOnConcurrentUpdate(t *testing.T) {
+	useSessionsDir(t)
+	s := NewSession("ollama", "llama3", "")
+	if err := SaveSession(s); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := LoadSession(s.ID)
+	second, _ := LoadSession(s.ID)
+
+	first.Messages = append(first.Messages, ask.ChatMessage{Role: "user", Content: "from first"})
+	if err := SaveSession(first); err != nil || first.ID != s.ID {
+		t.Fatalf("first save: %v, id %s", err, first.ID)
+	}
+	second.Messages = append(second.Messages, ask.ChatMessage{Role: "user", Content: "from second"})
+	if err := SaveSession(second); err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == s.ID || second.ForkedFrom != s.ID {
+		t.Errorf("second save should fork, got id %s forked from %q", second.ID, second.ForkedFrom)
+	}
+	if got, _ := LoadSession(s.ID); len(got.Messages) != 1 || got.Messages[0].Content != "from first" {
+		t.Errorf("original session overwritten: %+v", got.Messages)
+	}
+	// Later saves of each copy go to its own file.
+	if err := SaveSession(second); err != nil || second.ForkedFrom != s.ID {
+		t.Errorf("saving the fork again: %v, forked from %q", err, second.ForkedFrom)
+	}
+	if err := SaveSession(first); err != nil || first.ID != s.ID {
+		t.Errorf("saving the original again: %v, id %s", err, first.ID)
+	}
+}
+
+// titleProvider answers every request with a fixed text.
+type titleProvider struct{ text string }
+
+func (p titleProvider) Name() string { return "ollama" }
+
+func (p titleProvider) Send(context.Context, ask.AskRequest) (ask.AskResponse, error) {
+	return ask.AskResponse{Text: p.text}, nil
+}
+
+func (p titleProvider) SendStream(ctx context.Context, req ask.AskRequest, onChunk func(string)) (ask.AskResponse, error) {
+	return p.Send(ctx, req)
+}
+
+func TestRunnerSaveTitlesSession(t *testing.T) {
+	useSessionsDir(t)
+	viper.Set("chat.auto_title", true)
+	t.Cleanup(func() { viper.Set("chat.auto_title", nil) })
+
+	s := NewSession("ollama", "llama3", "")
+	s.Messages = []ask.ChatMessage{{Role: "user", Content: "how do I free disk space?"}, {Role: "assistant", Content: "..."}}
+	r := &runner{provider: titleProvider{text: "\"Freeing Disk Space.\"\nextra"}, session: s, persist: true}
+	r.save(context.Background())
+	if s.Title != "Freeing Disk Space" {
+		t.Errorf("title = %q", s.Title)
+	}
+	if got, err := LoadSession(s.ID); err != nil || got.Title != s.Title {
+		t.Errorf("saved session = %+v, %v", got, err)
+	}
+
+	viper.Set("chat.auto_title", false)
+	s2 := NewSession("ollama", "llama3", "")
+	s2.Messages = s.Messages
+	r = &runner{provider: titleProvider{text: "ignored"}, session: s2}
+	if got := r.title(context.Background()); !strings.HasPrefix(got, "how do I free disk space") {
+		t.Errorf("fallback title = %q", got)
+	}
+}
+
+func TestChatResumeTakesIDAsSeparateWord(t *testing.T) {
+	useSessionsDir(t)
+	viper.Set("chat.provider", ask.ReplayProviderName)
+	viper.Set("chat.model", "llama3")
+	viper.Set("fixtures_dir", t.TempDir())
+	t.Cleanup(func() {
+		viper.Set("chat.provider", nil)
+		viper.Set("chat.model", nil)
+		viper.Set("fixtures_dir", nil)
+	})
+	older := NewSession("replay", "llama3", "")
+	older.ID = "20260101-120000"
+	older.Messages = []ask.ChatMessage{{Role: "user", Content: "old question"}}
+	newer := NewSession("replay", "llama3", "")
+	newer.ID = "20260202-120000"
+	newer.Messages = []ask.ChatMessage{{Role: "user", Content: "new question"}}
+	for _, s := range []*Session{older, newer} {
+		if err := SaveSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		cmds, err := NewChatPlugin().Register(kernel.NewKernel())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		cmd := cmds[0]
+		cmd.SetArgs(args)
+		cmd.SetIn(strings.NewReader(""))
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err = cmd.Execute()
+		return out.String(), err
+	}
+	out, err := run("--resume", older.ID)
+	if err != nil || !strings.Contains(out, "Resumed") || !strings.Contains(out, older.ID) {
+		t.Errorf("--resume %s resumed the wrong session: %v\n%s", older.ID, err, out)
+	}
+	if out, err := run("--resume"); err != nil || !strings.Contains(out, newer.ID) {
+		t.Errorf("--resume without an ID should open the most recent session: %v\n%s", err, out)
+	}
+	if _, err := run(older.ID); err == nil {
+		t.Error("a session ID without --resume should be rejected")
+	}
+}
@@ -0,0 +1,406 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gaia/plugins/ask"
+	"gaia/plugins/shared"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	tuiInputHeight = 3
+	tuiHints       = "Enter send · Alt+Enter/Ctrl+J newline · Ctrl+R retry · Ctrl+Y copy · Esc stop · Ctrl+C quit"
+	tuiShortHints  = "/help · Ctrl+C quit"
+)
+
+var statusStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFFFFF")).
+	Background(lipgloss.Color("#7D56F4")).
+	Padding(0, 1)
+
+var summaryStyle = lipgloss.NewStyle().Faint(true)
+
+// runTUI turns on xterm modifyOtherKeys so Shift+Enter is reported; terminals without it
+// ignore the request. Reported keys (CSI u or modifyOtherKeys) reach the program as unknown
+// CSI sequences.
+const (
+	enableModifyOtherKeys  = "\x1b[>4;1m"
+	disableModifyOtherKeys = "\x1b[>4;0m"
+)
+
+var shiftEnterSequences = map[string]bool{
+	fmt.Sprintf("?CSI%+v?", []byte("13;2u")):    true,
+	fmt.Sprintf("?CSI%+v?", []byte("27;2;13~")): true,
+}
+
+type (
+	tuiNoticeMsg   string
+	tuiChunkMsg    string
+	tuiThinkingMsg string
+	tuiDoneMsg     struct{ err error }
+)
+
+// tuiNotice is command output shown after the message it followed.
+type tuiNotice struct {
+	after int // number of session messages when it was printed
+	text  string
+}
+
+// tuiModel is the full-screen chat: a transcript viewport, an input area and a status bar.
+// While a turn runs in the background (busy), only the copies below are read, never the runner.
+type tuiModel struct {
+	r        *runner
+	commands *commandRegistry
+	env      *CommandEnv
+	ctx      context.Context
+
+	viewport viewport.Model
+	input    textarea.Model
+	width    int
+	height   int
+
+	messages []ask.ChatMessage // snapshot of the session, refreshed after each turn
+	summary  string
+	notices  []tuiNotice
+	status   string
+
+	busy     bool
+	cancel   context.CancelFunc
+	firstNew int    // index of the first notice printed during the running turn
+	pending  string // user message being answered
+	answer   strings.Builder
+	thinking strings.Builder
+
+	rendered map[string]string // Markdown boxes of finished answers, by width and content
+}
+
+func newTUIModel(ctx context.Context, r *runner, commands *commandRegistry, env *CommandEnv) *tuiModel {
+	input := textarea.New()
+	input.Placeholder = "Message, or /help for commands"
+	input.ShowLineNumbers = false
+	input.SetHeight(tuiInputHeight)
+	input.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	input.Focus()
+
+	vp := viewport.New(0, 0)
+	// Only keys that cannot be typed scroll the transcript.
+	vp.KeyMap = viewport.KeyMap{
+		PageUp:       key.NewBinding(key.WithKeys("pgup")),
+		PageDown:     key.NewBinding(key.WithKeys("pgdown")),
+		HalfPageUp:   key.NewBinding(key.WithKeys("ctrl+u")),
+		HalfPageDown: key.NewBinding(key.WithKeys("ctrl+d")),
+	}
+
+	m := &tuiModel{r: r, commands: commands, env: env, ctx: ctx, viewport: vp, input: input}
+	m.refresh()
+	return m
+}
+
+func (m *tuiModel) Init() tea.Cmd { return textarea.Blink }
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.SetWidth(msg.Width)
+		m.viewport.Width = msg.Width
+		m.viewport.Height = max(msg.Height-tuiInputHeight-1, 1)
+		m.render(true)
+		return m, nil
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	case tuiNoticeMsg:
+		m.notices = append(m.notices, tuiNotice{after: len(m.messages), text: strings.TrimRight(string(msg), "\n")})
+		m.render(false)
+		return m, nil
+	case tuiChunkMsg:
+		m.answer.WriteString(string(msg))
+		m.render(false)
+		return m, nil
+	case tuiThinkingMsg:
+		m.thinking.WriteString(string(msg))
+		m.render(false)
+		return m, nil
+	case tuiDoneMsg:
+		m.busy, m.cancel, m.pending = false, nil, ""
+		m.answer.Reset()
+		m.thinking.Reset()
+		m.refresh()
+		// Output of the turn goes after the messages it added.
+		for i := m.firstNew; i < len(m.notices); i++ {
+			m.notices[i].after = len(m.messages)
+		}
+		if errors.Is(msg.err, errExitChat) {
+			return m, tea.Quit
+		}
+		if msg.err != nil {
+			m.notices = append(m.notices, tuiNotice{after: len(m.messages), text: shared.RenderBox("Error", msg.err.Error())})
+		}
+		m.render(true)
+		return m, nil
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	default:
+		if s, ok := msg.(fmt.Stringer); ok && shiftEnterSequences[s.String()] {
+			m.input.InsertString("\n")
+			return m, nil
+		}
+	}
+	return m, nil
+}
+
+func (m *tuiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		if m.busy && m.cancel != nil {
+			m.cancel()
+			m.cancel = nil
+			return m, nil
+		}
+		return m, tea.Quit
+	case "esc":
+		if m.busy && m.cancel != nil {
+			m.cancel()
+			m.cancel = nil
+		}
+		return m, nil
+	case "enter":
+		line := strings.TrimSpace(m.input.Value())
+		if m.busy || line == "" {
+			return m, nil
+		}
+		m.input.Reset()
+		if strings.EqualFold(line, "exit") {
+			return m, tea.Quit
+		}
+		return m, m.submit(line)
+	case "ctrl+r":
+		if m.busy {
+			return m, nil
+		}
+		return m, m.submit("/retry")
+	case "ctrl+y":
+		if m.busy {
+			return m, nil
+		}
+		return m, m.submit("/copy")
+	case "pgup", "pgdown", "ctrl+u", "ctrl+d":
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// submit runs line as a command or chat turn in the background; the runner reports progress
+// through the program and tuiDoneMsg ends the turn.
+func (m *tuiModel) submit(line string) tea.Cmd {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.busy, m.cancel, m.firstNew = true, cancel, len(m.notices)
+	if !strings.HasPrefix(line, "/") || strings.HasPrefix(line, "//") {
+		m.pending = strings.TrimPrefix(line, "/")
+	}
+	m.render(true)
+	return func() tea.Msg {
+		defer cancel()
+		handled, err := m.commands.Dispatch(ctx, m.env, line)
+		if !handled {
+			m.r.turn(ctx, strings.TrimPrefix(line, "/"))
+		}
+		return tuiDoneMsg{err: err}
+	}
+}
+
+// refresh copies what the view needs from the runner; it must not run while busy.
+func (m *tuiModel) refresh() {
+	m.messages = append(m.messages[:0], m.r.session.Messages...)
+	m.summary = ""
+	if m.r.session.Summary != "" {
+		m.summary = fmt.Sprintf("Summary of %d earlier turns", m.r.session.Compacted) + "\n" + m.r.session.Summary
+	}
+	parts := []string{fmt.Sprintf("%s (%s)", m.r.req.Model, m.r.req.Provider)}
+	if m.r.session.Role != "" {
+		parts = append(parts, "role "+m.r.session.Role)
+	}
+	tokens := m.r.usage.PromptTokens + m.r.usage.CompletionTokens
+	if m.r.estimated {
+		parts = append(parts, fmt.Sprintf("~%d tokens", tokens))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d tokens", tokens))
+	}
+	if !m.r.canRead && !m.r.canWrite {
+		parts = append(parts, "cache off")
+	}
+	if m.r.session.Title != "" {
+		parts = append(parts, m.r.session.Title)
+	}
+	m.status = strings.Join(parts, " · ")
+	for i := range m.notices {
+		m.notices[i].after = min(m.notices[i].after, len(m.messages))
+	}
+}
+
+// render rebuilds the transcript, following new output when the view is at the bottom.
+func (m *tuiModel) render(forceBottom bool) {
+	if m.width <= 0 {
+		return
+	}
+	follow := forceBottom || m.viewport.AtBottom()
+	m.viewport.SetContent(m.transcript())
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+func (m *tuiModel) transcript() string {
+	var blocks []string
+	if m.summary != "" {
+		blocks = append(blocks, summaryStyle.Render(shared.RenderWrappedBox("History", m.summary, m.width)))
+	}
+	notices := m.notices
+	flush := func(upTo int) {
+		for len(notices) > 0 && notices[0].after <= upTo {
+			blocks = append(blocks, notices[0].text)
+			notices = notices[1:]
+		}
+	}
+	for i, msg := range m.messages {
+		flush(i)
+		title := "You"
+		if msg.Role == "assistant" {
+			title = "Assistant"
+		}
+		if msg.Role == "assistant" {
+			blocks = append(blocks, m.answerBox(msg.Content, true))
+			continue
+		}
+		blocks = append(blocks, shared.RenderWrappedBox(title, msg.Content, m.width))
+	}
+	flush(len(m.messages))
+	if m.pending != "" {
+		blocks = append(blocks, shared.RenderWrappedBox("You", m.pending, m.width))
+	}
+	if thinking := strings.TrimSpace(m.thinking.String()); thinking != "" {
+		blocks = append(blocks, shared.RenderThinkingBox(thinking, m.width))
+	}
+	if m.busy && m.pending != "" {
+		answer := m.answer.String()
+		if strings.TrimSpace(answer) == "" {
+			answer = "Waiting for response..."
+		}
+		blocks = append(blocks, m.answerBox(answer, false))
+	}
+	if len(blocks) == 0 {
+		return "Type a message and press Enter. /help lists commands."
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// answerBox renders an assistant reply, as Markdown unless --raw was given. A streaming
+// reply stays plain text, since rendering it on every chunk is quadratic; finished replies
+// are rendered once per width.
+func (m *tuiModel) answerBox(content string, finished bool) string {
+	if !m.r.streamOpts.Markdown || !finished {
+		return shared.RenderWrappedBox("Assistant", content, m.width)
+	}
+	key := strconv.Itoa(m.width) + "\x00" + content
+	if box, ok := m.rendered[key]; ok {
+		return box
+	}
+	if m.rendered == nil {
+		m.rendered = map[string]string{}
+	}
+	box := shared.RenderMarkdownBox("Assistant", content, m.width)
+	m.rendered[key] = box
+	return box
+}
+
+func (m *tuiModel) View() string {
+	if m.width <= 0 {
+		return ""
+	}
+	status := m.status
+	if m.busy {
+		status = "… " + status
+	}
+	for _, hints := range []string{tuiHints, tuiShortHints} {
+		if line := status + "  │  " + hints; lipgloss.Width(line) <= m.width-2 {
+			status = line
+			break
+		}
+	}
+	if runes := []rune(status); len(runes) > m.width-2 {
+		status = string(runes[:max(m.width-3, 0)]) + "…"
+	}
+	bar := statusStyle.Width(m.width).Render(status)
+	return m.viewport.View() + "\n" + bar + "\n" + m.input.View()
+}
+
+// tuiWriter turns command and runner output into transcript notices.
+type tuiWriter struct{ send func(tea.Msg) }
+
+func (w tuiWriter) Write(p []byte) (int, error) {
+	if strings.TrimSpace(string(p)) != "" {
+		w.send(tuiNoticeMsg(p))
+	}
+	return len(p), nil
+}
+
+// runTUI runs the chat full screen until the user quits.
+func runTUI(ctx context.Context, r *runner, commands *commandRegistry, env *CommandEnv, opts ...tea.ProgramOption) error {
+	model := newTUIModel(ctx, r, commands, env)
+	p := tea.NewProgram(model, append([]tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithContext(ctx)}, opts...)...)
+
+	notices := tuiWriter{send: p.Send}
+	if env.Terminal == nil {
+		env.Terminal = env.Out
+	}
+	r.out, r.errOut = notices, notices
+	env.Out, env.ErrOut = notices, notices
+	r.req.ProgressOut = notices
+	r.display = func(ctx context.Context, _ string, opts shared.StreamOptions, runStream func(ctx context.Context, send, think func(string)) (string, error)) (string, error) {
+		var streamed strings.Builder
+		final, err := runStream(ctx, func(s string) {
+			streamed.WriteString(s)
+			p.Send(tuiChunkMsg(s))
+		}, func(s string) {
+			if opts.ShowThinking {
+				p.Send(tuiThinkingMsg(s))
+			}
+		})
+		if ctx.Err() != nil {
+			p.Send(tuiNoticeMsg(shared.TruncatedMarker))
+			return strings.TrimRight(streamed.String(), "\n"), shared.ErrStreamInterrupted
+		}
+		if err == nil && strings.TrimSpace(final) == "" {
+			final = streamed.String()
+		}
+		return final, err
+	}
+
+	if shared.HasTTYStdout() {
+		_, _ = io.WriteString(os.Stdout, enableModifyOtherKeys)
+		defer func() { _, _ = io.WriteString(os.Stdout, disableModifyOtherKeys) }()
+	}
+	_, err := p.Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
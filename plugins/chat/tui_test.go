@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// csiMsg stands in for Bubble Tea's unknown CSI sequence message.
+type csiMsg string
+
+func (c csiMsg) String() string { return fmt.Sprintf("?CSI%+v?", []byte(c)) }
+
+func newTestTUI(t *testing.T) (*tuiModel, *countingProvider) {
+	t.Helper()
+	env, provider, _ := newTestEnv(t)
+	m := newTUIModel(context.Background(), env.r, newCommandRegistry(), env)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return m, provider
+}
+
+func typeText(m *tuiModel, text string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+}
+
+func TestTUITurn(t *testing.T) {
+	m, provider := newTestTUI(t)
+	typeText(m, "hello")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || !m.busy {
+		t.Fatalf("Enter should start a turn")
+	}
+	if m.input.Value() != "" {
+		t.Errorf("input not cleared: %q", m.input.Value())
+	}
+	if !strings.Contains(m.transcript(), "Waiting for response") {
+		t.Errorf("pending turn not shown:\n%s", m.transcript())
+	}
+	m.Update(cmd())
+	if m.busy || provider.calls != 1 {
+		t.Fatalf("turn did not complete: busy=%t calls=%d", m.busy, provider.calls)
+	}
+	transcript := m.transcript()
+	if !strings.Contains(transcript, "hello") || !strings.Contains(transcript, "answer 1") {
+		t.Errorf("transcript missing the exchange:\n%s", transcript)
+	}
+	view := m.View()
+	if !strings.Contains(view, "llama3 (ollama)") || !strings.Contains(view, "tokens") {
+		t.Errorf("status bar missing model or tokens:\n%s", view)
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	m, provider := newTestTUI(t)
+	typeText(m, "line one")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	m.Update(csiMsg("13;2u"))
+	typeText(m, "line three")
+	if got := m.input.Value(); got != "line one\n\nline three" {
+		t.Errorf("newline keys: input = %q", got)
+	}
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR}); cmd != nil {
+		m.Update(cmd())
+	}
+	if provider.calls != 0 || !strings.Contains(m.transcript(), "nothing to retry") {
+		t.Errorf("Ctrl+R on an empty chat should report an error:\n%s", m.transcript())
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil {
+		t.Fatalf("Ctrl+C when idle should quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Ctrl+C when idle should quit")
+	}
+}
+
+func TestTUICtrlCCancelsTurn(t *testing.T) {
+	m, _ := newTestTUI(t)
+	typeText(m, "hello")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	cancelled := false
+	cancel := m.cancel
+	m.cancel = func() { cancelled = true; cancel() }
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd != nil || !cancelled {
+		t.Errorf("Ctrl+C during a turn should cancel it, not quit")
+	}
+}
@@ -0,0 +1,127 @@
+package embed
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gaia/kernel"
+	"gaia/plugins/ask"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type EmbedPlugin struct {
+	embedders map[string]ask.Embedder
+}
+
+func NewEmbedPlugin() *EmbedPlugin {
+	p := &EmbedPlugin{
+		embedders: map[string]ask.Embedder{},
+	}
+	p.RegisterEmbedder(ask.NewOllamaProvider())
+	p.RegisterEmbedder(ask.NewOpenAIProvider())
+	p.RegisterEmbedder(ask.NewMistralProvider())
+	return p
+}
+
+func (p *EmbedPlugin) ID() string           { return "embed" }
+func (p *EmbedPlugin) DefaultEnabled() bool { return true }
+func (p *EmbedPlugin) DependsOn() []string  { return nil }
+func (p *EmbedPlugin) ConfigSchema() []string {
+	return []string{
+		"embed.provider",
+		"embed.host",
+		"embed.port",
+		"embed.model",
+		"embed.timeout_seconds",
+		"embed.batch_size",
+		"embed.cache_dir",
+	}
+}
+
+func (p *EmbedPlugin) MCPTools() []kernel.MCPTool { return nil }
+
+func (p *EmbedPlugin) RegisterEmbedder(e ask.Embedder) {
+	if e == nil {
+		return
+	}
+	p.embedders[e.Name()] = e
+}
+
+// Embedding is one line of `gaia embed` output.
+type Embedding struct {
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"`
+}
+
+func (p *EmbedPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:   "embed [text...]",
+		Short: "Print embedding vectors as JSON (one input per argument or stdin line)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputs := args
+			if len(inputs) == 0 && !shared.HasTTYStdin() {
+				inputs = readLines(cmd.InOrStdin())
+			}
+			if len(inputs) == 0 {
+				return shared.PrintError(cmd.ErrOrStderr(), "Provide text as arguments or on stdin")
+			}
+
+			req := BuildRequest()
+			if model, _ := cmd.Flags().GetString("model"); strings.TrimSpace(model) != "" {
+				req.Model = model
+			}
+			req.Inputs = inputs
+			embedder, ok := p.embedders[req.Provider]
+			if !ok {
+				return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Provider %q does not support embeddings", req.Provider))
+			}
+			noCache, _ := cmd.Flags().GetBool("no-cache")
+			vectors, err := ask.EmbedTexts(cmd.Context(), embedder, req, ask.EmbedOptions{
+				BatchSize: viper.GetInt("embed.batch_size"),
+				NoCache:   noCache,
+			})
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			out := make([]Embedding, 0, len(vectors))
+			for i, vec := range vectors {
+				out = append(out, Embedding{Text: inputs[i], Embedding: vec})
+			}
+			data, err := json.Marshal(out)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintRaw(cmd.OutOrStdout(), string(data)+"\n")
+		},
+	}
+
+	cmd.Flags().String("model", "", "Embedding model (default: embed.model or the provider's default)")
+	cmd.Flags().Int("batch-size", ask.DefaultEmbedBatchSize, "Number of inputs per embedding request")
+	cmd.Flags().Bool("no-cache", false, "Bypass the on-disk embeddings cache")
+	_ = viper.BindPFlag("embed.batch_size", cmd.Flags().Lookup("batch-size"))
+	return []*cobra.Command{cmd}, nil
+}
+
+// BuildRequest resolves the embedding request from the embed.* settings; see
+// ask.EmbedRequestFromConfig.
+func BuildRequest() ask.EmbedRequest {
+	return ask.EmbedRequestFromConfig()
+}
+
+func readLines(r io.Reader) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
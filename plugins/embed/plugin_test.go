@@ -0,0 +1,30 @@
+package embed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBuildRequest_DefaultModelPerProvider(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set("embed.provider", "")
+		viper.Set("embed.model", "")
+	})
+	viper.Set("embed.provider", "mistral")
+	if req := BuildRequest(); req.Model != "mistral-embed" {
+		t.Errorf("model = %q", req.Model)
+	}
+	viper.Set("embed.model", "custom")
+	if req := BuildRequest(); req.Model != "custom" || req.Provider != "mistral" {
+		t.Errorf("req = %+v", req)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	got := readLines(strings.NewReader("one\n\n  two  \n"))
+	if len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("readLines = %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hook names managed by gaia.
+const (
+	PrepareCommitMsg = "prepare-commit-msg"
+	CommitMsg        = "commit-msg"
+)
+
+// Names lists the hooks gaia can install.
+var Names = []string{PrepareCommitMsg, CommitMsg}
+
+// marker identifies hook scripts written by gaia.
+const marker = "# gaia-hook:"
+
+// backupSuffix is appended to a hook replaced with --force; uninstall restores it.
+const backupSuffix = ".gaia-backup"
+
+// HooksDir returns the hooks directory of the repository at the working directory,
+// honouring core.hooksPath and worktrees.
+func HooksDir(ctx context.Context) (string, error) {
+	// nosemgrep
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--git-path", "hooks")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return filepath.Abs(strings.TrimSpace(stdout.String()))
+}
+
+// Script returns the hook script for name, calling the gaia executable bin. The script
+// exits quietly when gaia is no longer installed, so commits never depend on it.
+func Script(name, bin string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s %s
+# Installed by gaia hooks install; remove with gaia hooks uninstall.
+GAIA=%s
+[ -x "$GAIA" ] || GAIA=$(command -v gaia) || exit 0
+exec "$GAIA" hooks run %s "$@" </dev/null
+`, marker, name, shellQuote(bin), name)
+}
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// State describes a hook file.
+type State int
+
+const (
+	Absent State = iota
+	Installed
+	Foreign // a hook not written by gaia
+)
+
+func (s State) String() string {
+	switch s {
+	case Installed:
+		return "installed"
+	case Foreign:
+		return "other hook present"
+	default:
+		return "not installed"
+	}
+}
+
+// Inspect returns the state of hook name in dir.
+func Inspect(dir, name string) (State, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return Absent, nil
+	}
+	if err != nil {
+		return Absent, err
+	}
+	if strings.Contains(string(data), marker+" "+name) {
+		return Installed, nil
+	}
+	return Foreign, nil
+}
+
+// Install writes hook name to dir. A hook not written by gaia is kept unless force is set,
+// in which case it is moved aside and restored by Uninstall.
+func Install(dir, name, bin string, force bool) error {
+	state, err := Inspect(dir, name)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	if state == Foreign {
+		if !force {
+			return fmt.Errorf("%s already exists and was not installed by gaia (pass --force to replace it; it is kept as %s%s)", path, name, backupSuffix)
+		}
+		if err := os.Rename(path, path+backupSuffix); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(Script(name, bin)), 0o755)
+}
+
+// Uninstall removes hook name from dir when gaia installed it, and restores the hook it
+// replaced. It reports whether a hook was removed.
+func Uninstall(dir, name string) (bool, error) {
+	state, err := Inspect(dir, name)
+	if err != nil || state != Installed {
+		return false, err
+	}
+	path := filepath.Join(dir, name)
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path + backupSuffix); err == nil {
+		if err := os.Rename(path+backupSuffix, path); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,93 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallAndUninstall(t *testing.T) {
+	dir := t.TempDir()
+	if err := Install(dir, PrepareCommitMsg, "/opt/gaia bin/gaia", false); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, PrepareCommitMsg)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0o111 == 0 {
+		t.Errorf("hook is not executable: %v", info.Mode())
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"#!/bin/sh", "GAIA='/opt/gaia bin/gaia'", `hooks run prepare-commit-msg "$@" </dev/null`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("hook script missing %q:\n%s", want, data)
+		}
+	}
+	if state, _ := Inspect(dir, PrepareCommitMsg); state != Installed {
+		t.Errorf("state after install = %v", state)
+	}
+	// Reinstalling a gaia hook needs no --force.
+	if err := Install(dir, PrepareCommitMsg, "/usr/bin/gaia", false); err != nil {
+		t.Errorf("reinstall: %v", err)
+	}
+	removed, err := Uninstall(dir, PrepareCommitMsg)
+	if err != nil || !removed {
+		t.Fatalf("Uninstall = %v, %v", removed, err)
+	}
+	if state, _ := Inspect(dir, PrepareCommitMsg); state != Absent {
+		t.Errorf("state after uninstall = %v", state)
+	}
+	if removed, _ := Uninstall(dir, PrepareCommitMsg); removed {
+		t.Error("Uninstall removed a missing hook")
+	}
+}
+
+func TestInstallKeepsForeignHooks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, CommitMsg)
+	foreign := "#!/bin/sh\nexec lint-commit \"$1\"\n"
+	if err := os.WriteFile(path, []byte(foreign), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := Inspect(dir, CommitMsg); state != Foreign {
+		t.Errorf("state = %v, want foreign", state)
+	}
+	if err := Install(dir, CommitMsg, "/usr/bin/gaia", false); err == nil {
+		t.Fatal("expected an error without --force")
+	}
+	if removed, _ := Uninstall(dir, CommitMsg); removed {
+		t.Error("Uninstall removed a foreign hook")
+	}
+	if err := Install(dir, CommitMsg, "/usr/bin/gaia", true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path + backupSuffix); string(data) != foreign {
+		t.Errorf("backup = %q", data)
+	}
+	if _, err := Uninstall(dir, CommitMsg); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != foreign {
+		t.Errorf("restored hook = %q", data)
+	}
+}
+
+func TestScriptExitsQuietlyWithoutGaia(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, PrepareCommitMsg)
+	if err := os.WriteFile(path, []byte(Script(PrepareCommitMsg, filepath.Join(dir, "missing"))), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", path, filepath.Join(dir, "COMMIT_EDITMSG"))
+	cmd.Env = []string{"PATH=" + dir}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("hook failed without gaia: %v\n%s", err, out)
+	}
+}
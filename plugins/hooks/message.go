@@ -0,0 +1,104 @@
+package hooks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NeedsMessage reports whether prepare-commit-msg should generate a message. git passes the
+// source of the message; only a plain commit, without -m, -F, a template, a merge, a squash
+// or an amend, is filled in.
+func NeedsMessage(source, current string) bool {
+	if strings.TrimSpace(source) != "" {
+		return false
+	}
+	return strings.TrimSpace(StripComments(current)) == ""
+}
+
+// StripComments removes the lines git drops from a commit message: comments, and everything
+// below the scissors line of commit -v.
+func StripComments(message string) string {
+	var out []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "# ------------------------ >8 ------------------------") {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// FillMessage puts message at the top of the commit message buffer, above git's comments.
+func FillMessage(buffer, message string) string {
+	message = strings.TrimSpace(message)
+	rest := strings.TrimLeft(buffer, "\n")
+	if rest == "" {
+		return message + "\n"
+	}
+	return message + "\n\n" + rest
+}
+
+// CleanMessage strips code fences and surrounding quotes a model may add around a message.
+func CleanMessage(text string) string {
+	text = strings.TrimSpace(text)
+	lines := strings.Split(text, "\n")
+	if len(lines) >= 2 && strings.HasPrefix(lines[0], "```") && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
+		text = strings.TrimSpace(strings.Join(lines[1:len(lines)-1], "\n"))
+	}
+	if len(text) >= 2 && (text[0] == '"' && text[len(text)-1] == '"') {
+		text = strings.TrimSpace(text[1 : len(text)-1])
+	}
+	return text
+}
+
+// conventionalTypes are the commit types accepted by the commit-msg lint.
+var conventionalTypes = []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}
+
+var conventionalTitle = regexp.MustCompile(`^([a-z]+)(\([^()\s]+\))?!?: \S`)
+
+// maxTitleLength is the longest title the commit-msg lint accepts.
+const maxTitleLength = 72
+
+// LintMessage checks that a commit message follows the conventional commit format of the
+// commit role: a "type(scope): subject" title of at most 72 characters, then a blank line.
+// Merge, revert, fixup and squash commits are accepted as git writes them.
+func LintMessage(message string) []string {
+	lines := strings.Split(strings.TrimSpace(StripComments(message)), "\n")
+	title := strings.TrimSpace(lines[0])
+	if title == "" {
+		return []string{"the commit message is empty"}
+	}
+	for _, prefix := range []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "} {
+		if strings.HasPrefix(title, prefix) {
+			return nil
+		}
+	}
+	var problems []string
+	m := conventionalTitle.FindStringSubmatch(title)
+	switch {
+	case m == nil:
+		problems = append(problems, fmt.Sprintf("the title %q is not \"type(scope): subject\"", title))
+	case !isConventionalType(m[1]):
+		problems = append(problems, fmt.Sprintf("unknown type %q (use one of %s)", m[1], strings.Join(conventionalTypes, ", ")))
+	}
+	if n := len([]rune(title)); n > maxTitleLength {
+		problems = append(problems, fmt.Sprintf("the title has %d characters (at most %d)", n, maxTitleLength))
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "the title must be followed by a blank line")
+	}
+	return problems
+}
+
+func isConventionalType(t string) bool {
+	for _, c := range conventionalTypes {
+		if c == t {
+			return true
+		}
+	}
+	return false
+}
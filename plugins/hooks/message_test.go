@@ -0,0 +1,85 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+)
+
+const gitBuffer = "\n# Please enter the commit message for your changes.\n# On branch main\n"
+
+func TestNeedsMessage(t *testing.T) {
+	cases := []struct {
+		source, buffer string
+		want           bool
+	}{
+		{"", gitBuffer, true},
+		{"", "", true},
+		{"message", "fix: typo\n" + gitBuffer, false},
+		{"template", gitBuffer, false},
+		{"merge", "Merge branch 'x'\n", false},
+		{"commit", "feat: old\n", false},
+		{"", "wip\n" + gitBuffer, false},
+		{"", "\n# ------------------------ >8 ------------------------\ndiff --git a/x b/x\n+y\n", true},
+	}
+	for _, c := range cases {
+		if got := NeedsMessage(c.source, c.buffer); got != c.want {
+			t.Errorf("NeedsMessage(%q, %q) = %v, want %v", c.source, c.buffer, got, c.want)
+		}
+	}
+}
+
+func TestFillMessage(t *testing.T) {
+	got := FillMessage(gitBuffer, "feat: add hooks\n\nInstall git hooks.\n")
+	want := "feat: add hooks\n\nInstall git hooks.\n\n# Please enter the commit message for your changes.\n# On branch main\n"
+	if got != want {
+		t.Errorf("FillMessage = %q, want %q", got, want)
+	}
+	if got := FillMessage("", "fix: x"); got != "fix: x\n" {
+		t.Errorf("FillMessage empty = %q", got)
+	}
+}
+
+func TestCleanMessage(t *testing.T) {
+	cases := map[string]string{
+		"feat: add x":                        "feat: add x",
+		"```\nfeat: add x\n\nbody\n```":      "feat: add x\n\nbody",
+		"```text\nfix: y\n```\n":             "fix: y",
+		"\"chore: bump deps\"":               "chore: bump deps",
+		"  docs: readme  \n":                 "docs: readme",
+		"feat: keep \"quotes\" inside\n\nok": "feat: keep \"quotes\" inside\n\nok",
+	}
+	for in, want := range cases {
+		if got := CleanMessage(in); got != want {
+			t.Errorf("CleanMessage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLintMessage(t *testing.T) {
+	valid := []string{
+		"feat: add hooks",
+		"fix(parser): handle empty input\n\nDetails here.",
+		"refactor!: drop the v1 API",
+		"Merge branch 'main' into feature",
+		"fixup! feat: add hooks",
+		"docs: readme\n" + gitBuffer,
+	}
+	for _, msg := range valid {
+		if problems := LintMessage(msg); len(problems) != 0 {
+			t.Errorf("LintMessage(%q) = %v", msg, problems)
+		}
+	}
+	invalid := map[string]string{
+		"Add hooks":                        "is not",
+		"feature: add hooks":               "unknown type",
+		"feat: " + strings.Repeat("x", 80): "characters",
+		"feat: add hooks\nbody":            "blank line",
+		gitBuffer:                          "empty",
+	}
+	for msg, want := range invalid {
+		problems := LintMessage(msg)
+		if len(problems) == 0 || !strings.Contains(strings.Join(problems, "\n"), want) {
+			t.Errorf("LintMessage(%q) = %v, want a problem containing %q", msg, problems, want)
+		}
+	}
+}
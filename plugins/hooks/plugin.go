@@ -0,0 +1,225 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gaia/config"
+	"gaia/kernel"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// HooksPlugin installs git hooks that write commit messages with the git commit tool action.
+type HooksPlugin struct {
+	k *kernel.Kernel
+}
+
+func NewHooksPlugin() *HooksPlugin { return &HooksPlugin{} }
+
+func (p *HooksPlugin) ID() string                 { return "hooks" }
+func (p *HooksPlugin) DefaultEnabled() bool       { return true }
+func (p *HooksPlugin) DependsOn() []string        { return []string{"tools"} }
+func (p *HooksPlugin) ConfigSchema() []string     { return []string{"hooks.timeout_seconds"} }
+func (p *HooksPlugin) MCPTools() []kernel.MCPTool { return nil }
+
+func (p *HooksPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
+	p.k = k
+	root := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage git hooks that write commit messages",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the prepare-commit-msg hook (and the commit-msg lint with --commit-msg)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, err := HooksDir(cmd.Context())
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			bin, err := os.Executable()
+			if err != nil {
+				bin = "gaia"
+			}
+			force, _ := cmd.Flags().GetBool("force")
+			names := []string{PrepareCommitMsg}
+			if lint, _ := cmd.Flags().GetBool("commit-msg"); lint {
+				names = append(names, CommitMsg)
+			}
+			var lines []string
+			for _, name := range names {
+				if err := Install(dir, name, bin, force); err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				lines = append(lines, "Installed "+filepath.Join(dir, name))
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Hooks", strings.Join(lines, "\n"))
+		},
+	}
+	installCmd.Flags().Bool("commit-msg", false, "Also install the commit-msg hook that lints conventional commit messages")
+	installCmd.Flags().Bool("force", false, "Replace hooks not installed by gaia (they are restored on uninstall)")
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the hooks installed by gaia",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, err := HooksDir(cmd.Context())
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			var lines []string
+			for _, name := range Names {
+				removed, err := Uninstall(dir, name)
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				if removed {
+					lines = append(lines, "Removed "+filepath.Join(dir, name))
+				}
+			}
+			if len(lines) == 0 {
+				lines = append(lines, "No gaia hooks installed")
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Hooks", strings.Join(lines, "\n"))
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the git hooks of the current repository",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, err := HooksDir(cmd.Context())
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			lines := []string{"Directory: " + dir}
+			for _, name := range Names {
+				state, err := Inspect(dir, name)
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				lines = append(lines, fmt.Sprintf("%s: %s", name, state))
+			}
+			lines = append(lines, localConfigStatus())
+			return shared.PrintBox(cmd.OutOrStdout(), "Hooks", strings.Join(lines, "\n"))
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:    "run <hook> [args...]",
+		Short:  "Run a hook (called by the installed hook scripts)",
+		Hidden: true,
+		Args:   cobra.MinimumNArgs(2),
+		RunE:   p.run,
+	}
+
+	root.AddCommand(installCmd, uninstallCmd, statusCmd, runCmd)
+	return []*cobra.Command{root}, nil
+}
+
+// localConfigStatus tells whether the repository's .gaia.yaml is used by the hooks.
+func localConfigStatus() string {
+	root, err := config.ResolveRepositoryRootFromPath(".")
+	if err != nil {
+		return "Local config: unknown"
+	}
+	if _, err := os.Stat(filepath.Join(root, ".gaia.yaml")); err != nil {
+		return "Local config: none"
+	}
+	if trusted, err := config.IsRepositoryTrusted(root); err == nil && trusted {
+		return "Local config: .gaia.yaml (trusted, used by the hooks)"
+	}
+	return "Local config: .gaia.yaml (not trusted, ignored; see gaia config trust)"
+}
+
+func (p *HooksPlugin) run(cmd *cobra.Command, args []string) error {
+	if os.Getenv("GAIA_SKIP_HOOKS") != "" {
+		return nil
+	}
+	switch args[0] {
+	case PrepareCommitMsg:
+		source := ""
+		if len(args) > 2 {
+			source = args[2]
+		}
+		p.prepareCommitMsg(cmd, args[1], source)
+		return nil
+	case CommitMsg:
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+		}
+		problems := LintMessage(string(data))
+		if len(problems) == 0 {
+			return nil
+		}
+		_ = shared.PrintError(cmd.ErrOrStderr(), "Commit message rejected:\n- "+strings.Join(problems, "\n- ")+
+			"\nEdit it, or skip the check with git commit --no-verify.")
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		return errors.New("commit-msg hook failed")
+	default:
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("unknown hook %q", args[0]))
+	}
+}
+
+// prepareCommitMsg fills an empty commit message with the git commit tool action. Any
+// failure, such as an unavailable model, leaves the message untouched; --debug reports it.
+func (p *HooksPlugin) prepareCommitMsg(cmd *cobra.Command, path, source string) {
+	data, err := os.ReadFile(path)
+	if err != nil || !NeedsMessage(source, string(data)) {
+		return
+	}
+	message, err := p.generate(cmd.Context())
+	if err != nil {
+		if viper.GetBool("debug") {
+			_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] gaia prepare-commit-msg skipped: %v\n", err))
+		}
+		return
+	}
+	_ = os.WriteFile(path, []byte(FillMessage(string(data), message)), 0o644)
+}
+
+// generate runs gaia tool git commit --print and returns the message.
+func (p *HooksPlugin) generate(ctx context.Context) (string, error) {
+	toolCmd, _, err := p.k.RootCmd.Find([]string{"tool"})
+	if err != nil || toolCmd == p.k.RootCmd || toolCmd.RunE == nil {
+		return "", errors.New("the tools plugin is not enabled")
+	}
+	if err := toolCmd.Flags().Set("print", "true"); err != nil {
+		return "", err
+	}
+	timeout := time.Duration(viper.GetInt("hooks.timeout_seconds")) * time.Second
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var out, errOut bytes.Buffer
+	toolCmd.SetContext(ctx)
+	toolCmd.SetIn(strings.NewReader(""))
+	toolCmd.SetOut(&out)
+	toolCmd.SetErr(&errOut)
+	if err := toolCmd.RunE(toolCmd, []string{"git", "commit"}); err != nil {
+		return "", err
+	}
+	message := CleanMessage(out.String())
+	if message == "" {
+		if msg := strings.TrimSpace(errOut.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", errors.New("empty commit message")
+	}
+	return message, nil
+}
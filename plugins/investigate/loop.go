@@ -27,6 +27,8 @@ type RunOptions struct {
 	MaxOutputBytes    int
 	MaxParseFailures  int
 	SendReq           func(Request) (string, error)
+	SendNative        func(Request) (Reply, error)
+	NativeTools       string // auto, on or off; see NativeToolsAuto
 	Debugf            func(format string, args ...any)
 }
 
@@ -50,7 +52,13 @@ func Run(ctx context.Context, goal string, opts RunOptions) (finalAnswer string,
 
 	state := &State{Goal: goal, Steps: nil}
 	registry := DefaultToolRegistry(opts.ShellRunner)
-	planner := &Planner{Model: opts.Model, SendReq: opts.SendReq}
+	planner := &Planner{
+		Model:       opts.Model,
+		SendReq:     opts.SendReq,
+		SendNative:  opts.SendNative,
+		NativeTools: opts.NativeTools,
+		Debugf:      debugf,
+	}
 	executor := NewExecutor(opts.MaxOutputBytes)
 	guardOpts := GuardOptions{
 		Denylist:          opts.Denylist,
@@ -78,7 +86,11 @@ func Run(ctx context.Context, goal string, opts RunOptions) (finalAnswer string,
 		}
 		parseFailures = 0
 
-		state.AppendDecision(raw)
+		if decision.Call != nil {
+			state.AppendToolCall(*decision.Call)
+		} else {
+			state.AppendDecision(raw)
+		}
 
 		if opts.Debug {
 			msg := fmt.Sprintf("decision: action=%s", decision.Action)
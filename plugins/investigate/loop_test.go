@@ -2,9 +2,13 @@ package investigate
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+
+	"gaia/plugins/ask"
 )
 
 func TestRun_emptyGoal(t *testing.T) {
@@ -50,3 +54,86 @@ func Test_extractJSON_braceMatch(t *testing.T) {
 		t.Errorf("extractJSON nested = %q", got)
 	}
 }
+
+func TestRun_nativeToolCalls(t *testing.T) {
+	var ran string
+	runner := &mockShellRunner{run: func(ctx context.Context, cmd string) (string, string, error) {
+		ran = cmd
+		return "42G used", "", nil
+	}}
+	var requests []Request
+	sendNative := func(r Request) (Reply, error) {
+		requests = append(requests, r)
+		if len(requests) == 1 {
+			return Reply{Calls: []ask.ToolCall{{ID: "call_1", Name: RunCmdName, Arguments: json.RawMessage(`{"cmd":"df -h"}`)}}}, nil
+		}
+		return Reply{Text: "Disk is full."}, nil
+	}
+	answer, err := Run(context.Background(), "why is disk full?", RunOptions{
+		MaxSteps:    3,
+		Yes:         true,
+		ShellRunner: runner,
+		SendNative:  sendNative,
+		NativeTools: NativeToolsAuto,
+		SendReq: func(Request) (string, error) {
+			t.Fatal("JSON protocol should not be used")
+			return "", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if answer != "Disk is full." || ran != "df -h" {
+		t.Errorf("answer = %q, ran = %q", answer, ran)
+	}
+	if len(requests[0].Tools) != 1 || requests[0].Tools[0].Name != RunCmdName {
+		t.Errorf("tools = %+v", requests[0].Tools)
+	}
+	msgs := requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != "tool" || last.ToolCallID != "call_1" || !strings.Contains(last.Content, "42G used") {
+		t.Errorf("tool result message = %+v", last)
+	}
+	if prev := msgs[len(msgs)-2]; prev.Role != "assistant" || len(prev.ToolCalls) != 1 {
+		t.Errorf("tool call message = %+v", prev)
+	}
+}
+
+func TestRun_nativeToolsFallbackToJSON(t *testing.T) {
+	nativeCalls, jsonCalls := 0, 0
+	answer, err := Run(context.Background(), "goal", RunOptions{
+		MaxSteps:    2,
+		ShellRunner: &mockShellRunner{},
+		NativeTools: NativeToolsAuto,
+		SendNative: func(Request) (Reply, error) {
+			nativeCalls++
+			return Reply{}, fmt.Errorf("%w: model gemma", ask.ErrToolsUnsupported)
+		},
+		SendReq: func(Request) (string, error) {
+			jsonCalls++
+			return `{"action":"answer","content":"done"}`, nil
+		},
+	})
+	if err != nil || answer != "done" {
+		t.Fatalf("Run = %q, %v", answer, err)
+	}
+	if nativeCalls != 1 || jsonCalls != 1 {
+		t.Errorf("native=%d json=%d", nativeCalls, jsonCalls)
+	}
+}
+
+func TestRun_nativeToolsOnDoesNotFallBack(t *testing.T) {
+	_, err := Run(context.Background(), "goal", RunOptions{
+		ShellRunner: &mockShellRunner{},
+		NativeTools: NativeToolsOn,
+		SendNative: func(Request) (Reply, error) {
+			return Reply{}, ask.ErrToolsUnsupported
+		},
+		SendReq: func(Request) (string, error) {
+			return `{"action":"answer","content":"done"}`, nil
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error when native tools are forced on")
+	}
+}
@@ -5,32 +5,54 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
+
+	"gaia/plugins/ask"
 )
 
 // Message is a minimal chat message for operator planning.
 type Message struct {
-	Role    string
-	Content string
+	Role       string
+	Content    string
+	ToolCalls  []ask.ToolCall // native tool calls on an assistant message
+	ToolCallID string         // call answered by a "tool" message
+	Name       string         // tool name on a "tool" message
 }
 
 // Request is a planning request sent to the LLM.
 type Request struct {
 	Model    string
 	Messages []Message
+	Tools    []ask.ToolDefinition // set for native tool calling only
+}
+
+// Reply is the LLM answer to a native tool-calling request.
+type Reply struct {
+	Text  string
+	Calls []ask.ToolCall
 }
 
+// Native tool-calling modes (investigate.native_tools).
+const (
+	NativeToolsAuto = "auto" // use native tool calls, fall back to JSON when unsupported
+	NativeToolsOn   = "on"   // always use native tool calls
+	NativeToolsOff  = "off"  // always use the JSON protocol
+)
+
 // State holds the goal and conversation steps (assistant decision + user observation) for the operator loop.
 type State struct {
 	Goal  string
 	Steps []Step
 }
 
-// Step represents one turn: either assistant (decision) or user (observation).
+// Step represents one turn: either assistant (decision) or user/tool (observation).
 type Step struct {
-	Role    string // "assistant" or "user"
-	Content string
+	Role       string // "assistant", "user" or "tool"
+	Content    string
+	ToolCalls  []ask.ToolCall
+	ToolCallID string
+	Name       string
 }
 
 // Decision is the parsed LLM output for one turn: either answer (done) or tool call.
@@ -40,13 +62,25 @@ type Decision struct {
 	Name      string            `json:"name"`      // for tool
 	Args      map[string]string `json:"args"`      // for tool
 	Reasoning string            `json:"reasoning"` // optional, for debug only
+	Call      *ask.ToolCall     `json:"-"`         // set when the decision is a native tool call
 }
 
-// AppendObservation adds a user message (tool result or error) to state.
+// AppendObservation adds an observation (tool result or error) to state.
+// It answers the pending native tool call when there is one, else it is a user message.
 func (s *State) AppendObservation(content string) {
+	if n := len(s.Steps); n > 0 && len(s.Steps[n-1].ToolCalls) > 0 {
+		call := s.Steps[n-1].ToolCalls[0]
+		s.Steps = append(s.Steps, Step{Role: "tool", Content: content, ToolCallID: call.ID, Name: call.Name})
+		return
+	}
 	s.Steps = append(s.Steps, Step{Role: "user", Content: content})
 }
 
+// AppendToolCall adds an assistant message carrying a native tool call to state.
+func (s *State) AppendToolCall(call ask.ToolCall) {
+	s.Steps = append(s.Steps, Step{Role: "assistant", ToolCalls: []ask.ToolCall{call}})
+}
+
 // AppendDecision adds an assistant message (the raw JSON decision) to state.
 // Only the JSON is stored; reasoning is not re-fed to the model.
 func (s *State) AppendDecision(raw string) {
@@ -56,7 +90,7 @@ func (s *State) AppendDecision(raw string) {
 // LastAnswerOrPartial returns the last assistant content if any, else the goal.
 func (s *State) LastAnswerOrPartial() string {
 	for i := len(s.Steps) - 1; i >= 0; i-- {
-		if s.Steps[i].Role == "assistant" {
+		if s.Steps[i].Role == "assistant" && s.Steps[i].Content != "" {
 			return s.Steps[i].Content
 		}
 	}
@@ -65,12 +99,28 @@ func (s *State) LastAnswerOrPartial() string {
 
 // Planner builds the prompt and calls the LLM to get the next decision.
 type Planner struct {
-	Model   string
-	SendReq func(Request) (string, error)
+	Model      string
+	SendReq    func(Request) (string, error)
+	SendNative func(Request) (Reply, error)
+	// NativeTools selects native tool calling (auto, on, off); empty means off.
+	NativeTools string
+	Debugf      func(format string, args ...any)
 }
 
 // Decide builds messages from state + registry (tools list), sends to LLM, parses JSON into Decision.
+// With native tool calling the decision comes from the model's tool calls instead; in auto mode
+// a model that rejects tools switches the planner to the JSON protocol for the rest of the run.
 func (p *Planner) Decide(ctx context.Context, state *State, registry *Registry) (*Decision, string, error) {
+	if p.native() {
+		dec, raw, err := p.decideNative(state, registry)
+		if !errors.Is(err, ask.ErrToolsUnsupported) || p.NativeTools == NativeToolsOn {
+			return dec, raw, err
+		}
+		p.NativeTools = NativeToolsOff
+		if p.Debugf != nil {
+			p.Debugf("native tools unsupported, falling back to JSON protocol: %v\n", err)
+		}
+	}
 	_ = ctx
 	messages := p.buildMessages(state, registry)
 	model := p.Model
@@ -106,32 +156,97 @@ func (p *Planner) Decide(ctx context.Context, state *State, registry *Registry)
 	return &dec, raw, nil
 }
 
-var jsonBlockRe = regexp.MustCompile("(?s)```(?:json)?\\s*([^`]+)```")
 var ErrEmptyResponse = errors.New("empty response from model")
 
-// extractJSON returns the first JSON object from s, optionally inside ```json ... ```.
-func extractJSON(s string) string {
-	s = strings.TrimSpace(s)
-	if m := jsonBlockRe.FindStringSubmatch(s); len(m) >= 2 {
-		return strings.TrimSpace(m[1])
-	}
-	start := strings.Index(s, "{")
-	if start < 0 {
-		return s
-	}
-	depth := 0
-	for i := start; i < len(s); i++ {
-		switch s[i] {
-		case '{':
-			depth++
-		case '}':
-			depth--
-			if depth == 0 {
-				return s[start : i+1]
-			}
+func (p *Planner) native() bool {
+	if p.SendNative == nil {
+		return false
+	}
+	return p.NativeTools == NativeToolsAuto || p.NativeTools == NativeToolsOn
+}
+
+// decideNative sends the registry as tool definitions and maps the first tool call, if any,
+// to a tool decision; a plain reply is the answer. Extra calls in one reply are ignored.
+func (p *Planner) decideNative(state *State, registry *Registry) (*Decision, string, error) {
+	model := p.Model
+	if model == "" {
+		model = "default"
+	}
+	reply, err := p.SendNative(Request{
+		Model:    model,
+		Messages: p.buildNativeMessages(state),
+		Tools:    toolDefinitions(registry),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(reply.Calls) == 0 {
+		text := strings.TrimSpace(reply.Text)
+		if text == "" {
+			return nil, "", ErrEmptyResponse
+		}
+		return &Decision{Action: "answer", Content: text}, text, nil
+	}
+	call := reply.Calls[0]
+	raw := string(call.Arguments)
+	args, err := decodeToolArgs(call.Arguments)
+	if err != nil {
+		return nil, raw, fmt.Errorf("invalid tool arguments for %s: %w", call.Name, err)
+	}
+	return &Decision{Action: "tool", Name: call.Name, Args: args, Call: &call}, raw, nil
+}
+
+// decodeToolArgs flattens a JSON arguments object into string values.
+func decodeToolArgs(raw json.RawMessage) (map[string]string, error) {
+	var decoded map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	args := make(map[string]string, len(decoded))
+	for k, v := range decoded {
+		switch val := v.(type) {
+		case string:
+			args[k] = val
+		case nil:
+			args[k] = ""
+		default:
+			b, _ := json.Marshal(val)
+			args[k] = string(b)
+		}
+	}
+	return args, nil
+}
+
+// toolDefinitions describes the registry tools as functions taking string arguments.
+func toolDefinitions(registry *Registry) []ask.ToolDefinition {
+	names := registry.List()
+	sort.Strings(names)
+	defs := make([]ask.ToolDefinition, 0, len(names))
+	for _, name := range names {
+		tool := registry.Get(name)
+		if tool == nil {
+			continue
+		}
+		props := map[string]any{}
+		required := make([]string, 0, len(tool.Schema))
+		for arg, desc := range tool.Schema {
+			props[arg] = map[string]string{"type": "string", "description": desc}
+			required = append(required, arg)
 		}
+		sort.Strings(required)
+		params, _ := json.Marshal(map[string]any{
+			"type":       "object",
+			"properties": props,
+			"required":   required,
+		})
+		defs = append(defs, ask.ToolDefinition{Name: tool.Name, Description: tool.Description, Parameters: params})
 	}
-	return s[start:]
+	return defs
+}
+
+// extractJSON returns the first JSON object from s, optionally inside ```json ... ```.
+func extractJSON(s string) string {
+	return ask.ExtractJSONObject(s)
 }
 
 func (p *Planner) buildMessages(state *State, registry *Registry) []Message {
@@ -145,6 +260,21 @@ func (p *Planner) buildMessages(state *State, registry *Registry) []Message {
 	return msgs
 }
 
+func (p *Planner) buildNativeMessages(state *State) []Message {
+	msgs := make([]Message, 0, 2+len(state.Steps))
+	msgs = append(msgs, Message{Role: "system", Content: nativeSystemPrompt})
+	msgs = append(msgs, Message{Role: "user", Content: "Goal: " + state.Goal})
+	for _, step := range state.Steps {
+		msgs = append(msgs, Message(step))
+	}
+	return msgs
+}
+
+const nativeSystemPrompt = "You are an operator investigating a goal. Call one of the provided tools when you need more information, one call at a time. " +
+	"When you have enough information, reply with a plain-text summary and no tool call. " +
+	"Do not run destructive commands (e.g. rm -rf, sudo). Never use run_cmd to call HTTP endpoints (curl, wget, http). " +
+	"If external data is needed, answer with limitations instead of running network commands."
+
 func (p *Planner) systemPrompt(registry *Registry) string {
 	toolsDesc := "Available tools (respond with JSON only):\n"
 	for _, name := range registry.List() {
@@ -47,6 +47,14 @@ func Test_extractJSON(t *testing.T) {
 	}
 }
 
+// Regression: a bracket before the decision must not be taken for the JSON value.
+func Test_extractJSON_skipsLeadingArray(t *testing.T) {
+	in := `Step [1]: {"action":"answer","content":"done"}`
+	if got := extractJSON(in); got != `{"action":"answer","content":"done"}` {
+		t.Errorf("extractJSON(%q) = %q", in, got)
+	}
+}
+
 func TestPlanner_buildMessages(t *testing.T) {
 	r := NewRegistry()
 	r.Register(&Tool{Name: "run_cmd", Description: "Run command", Schema: map[string]string{"cmd": "cmd"}})
@@ -14,6 +14,7 @@ import (
 	"gaia/plugins/mempalace"
 	"gaia/plugins/roles"
 	"gaia/plugins/shared"
+	"gaia/plugins/shared/export"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -52,6 +53,7 @@ func (p *InvestigatePlugin) ConfigSchema() []string {
 		"investigate.denylist",
 		"investigate.allowlist",
 		"investigate.treat_exit_code_1_as_success",
+		"investigate.native_tools",
 	}
 }
 
@@ -70,6 +72,7 @@ func (p *InvestigatePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error)
 		Short: "Investigate a goal using an operator loop",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			started := time.Now().UTC()
 			goal := strings.TrimSpace(strings.Join(args, " "))
 			if goal == "" {
 				return shared.PrintError(cmd.ErrOrStderr(), "Goal cannot be empty")
@@ -99,19 +102,19 @@ func (p *InvestigatePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error)
 			if err := validateInvestigateConfig(req); err != nil {
 				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
 			}
+			nativeTools, err := parseNativeTools(viper.GetString("investigate.native_tools"))
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
 			if memCtx, err := mempalace.InjectIfEnabled(cmd.Context(), goal); err != nil {
 				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
 			} else if memCtx != "" {
 				req.SystemPrompt = mempalace.AppendMemory(req.SystemPrompt, memCtx)
 			}
 
-			provider, ok := p.providers[req.Provider]
-			if !ok {
-				if fallback, hasFallback := p.providers["ollama"]; hasFallback {
-					provider = fallback
-				} else {
-					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Unknown provider %q", req.Provider))
-				}
+			provider, err := ask.SelectProvider(p.providers, req.Provider)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
 			}
 
 			maxSteps := viper.GetInt("investigate.max_steps")
@@ -146,24 +149,28 @@ func (p *InvestigatePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error)
 				out:                     cmd.OutOrStdout(),
 			}
 
-			sendReq := func(r Request) (string, error) {
+			send := func(r Request) (ask.AskResponse, error) {
 				askReq := ask.AskRequest{
-					Provider:     req.Provider,
-					Host:         req.Host,
-					Port:         req.Port,
-					Model:        r.Model,
-					Timeout:      req.Timeout,
-					SystemPrompt: req.SystemPrompt,
-					Pull:         req.Pull,
-					ProgressOut:  cmd.ErrOrStderr(),
+					Provider:    req.Provider,
+					Host:        req.Host,
+					Port:        req.Port,
+					Model:       r.Model,
+					Timeout:     req.Timeout,
+					Pull:        req.Pull,
+					ProgressOut: cmd.ErrOrStderr(),
+					Tools:       r.Tools,
+					Messages:    withSystemPrompt(toChatMessages(r.Messages), req.SystemPrompt),
 				}
-				askReq.Messages = toChatMessages(r.Messages)
 				askReq = ask.ApplySanitize(cmd.ErrOrStderr(), askReq)
-				resp, err := provider.Send(cmd.Context(), askReq)
-				if err != nil {
-					return "", err
-				}
-				return resp.Text, nil
+				return provider.Send(cmd.Context(), askReq)
+			}
+			sendReq := func(r Request) (string, error) {
+				resp, err := send(r)
+				return resp.Text, err
+			}
+			sendNative := func(r Request) (Reply, error) {
+				resp, err := send(r)
+				return Reply{Text: resp.Text, Calls: resp.ToolCalls}, err
 			}
 
 			opts := RunOptions{
@@ -182,6 +189,8 @@ func (p *InvestigatePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error)
 				MaxOutputBytes:   maxOutputBytes,
 				MaxParseFailures: maxParseFailures,
 				SendReq:          sendReq,
+				SendNative:       sendNative,
+				NativeTools:      nativeTools,
 				Debugf: func(format string, args ...any) {
 					msg := fmt.Sprintf("[DEBUG] "+format, args...)
 					_ = shared.PrintRaw(cmd.ErrOrStderr(), msg)
@@ -203,7 +212,25 @@ func (p *InvestigatePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error)
 				_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
 			}
 
-			return shared.PrintBox(cmd.OutOrStdout(), "Investigate", finalAnswer)
+			if err := shared.PrintAnswer(cmd.OutOrStdout(), "Investigate", finalAnswer, viper.GetBool("raw")); err != nil {
+				return err
+			}
+			if path, _ := cmd.Flags().GetString("save"); strings.TrimSpace(path) != "" {
+				doc := export.Document{
+					Title:     goal,
+					Source:    "investigate",
+					Provider:  req.Provider,
+					Model:     req.Model,
+					Role:      viper.GetString("investigate.role"),
+					CreatedAt: started,
+					UpdatedAt: time.Now().UTC(),
+					Messages:  []export.Message{{Role: "user", Content: goal}, {Role: "assistant", Content: finalAnswer}},
+				}
+				if err := export.WriteFile(path, doc, ""); err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Saving the result failed: %v", err))
+				}
+			}
+			return nil
 		},
 	}
 
@@ -213,6 +240,7 @@ func (p *InvestigatePlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error)
 	cmd.Flags().Bool("debug", false, "Print debug output (decisions and observations)")
 	cmd.Flags().String("role", "", "Role name to apply to the planner")
 	cmd.Flags().Bool("pull", false, "Pull model from Ollama if available (force refresh)")
+	cmd.Flags().String("save", "", "Also write the goal and final answer to a file (.md, .json or .html)")
 
 	_ = viper.BindPFlag("investigate.role", cmd.Flags().Lookup("role"))
 	return []*cobra.Command{cmd}, nil
@@ -283,11 +311,13 @@ func promptConfirm(cmd *cobra.Command, message string) (bool, error) {
 
 func validateInvestigateConfig(req ask.AskRequest) error {
 	missing := []string{}
-	if strings.TrimSpace(req.Host) == "" {
-		missing = append(missing, "host")
-	}
-	if req.Port == 0 {
-		missing = append(missing, "port")
+	if ask.NeedsEndpoint(req.Provider) {
+		if strings.TrimSpace(req.Host) == "" {
+			missing = append(missing, "host")
+		}
+		if req.Port == 0 {
+			missing = append(missing, "port")
+		}
 	}
 	if strings.TrimSpace(req.Model) == "" {
 		missing = append(missing, "model")
@@ -301,6 +331,32 @@ func validateInvestigateConfig(req ask.AskRequest) error {
 	return nil
 }
 
+// parseNativeTools validates investigate.native_tools; empty means auto.
+func parseNativeTools(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "":
+		return NativeToolsAuto, nil
+	case NativeToolsAuto, NativeToolsOn, NativeToolsOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid investigate.native_tools %q: use %s, %s or %s", value, NativeToolsAuto, NativeToolsOn, NativeToolsOff)
+	}
+}
+
+// withSystemPrompt merges the role (or MemPalace) prompt into the planner's system message so
+// the model receives a single system message.
+func withSystemPrompt(messages []ask.ChatMessage, prompt string) []ask.ChatMessage {
+	if strings.TrimSpace(prompt) == "" {
+		return messages
+	}
+	if len(messages) > 0 && messages[0].Role == "system" {
+		out := append([]ask.ChatMessage(nil), messages...)
+		out[0].Content = prompt + "\n\n" + out[0].Content
+		return out
+	}
+	return append([]ask.ChatMessage{{Role: "system", Content: prompt}}, messages...)
+}
+
 func getStringSlice(key string) []string {
 	raw := viper.Get(key)
 	if raw == nil {
@@ -373,10 +429,19 @@ func applyInvestigateRole(cmd *cobra.Command, req *ask.AskRequest, goal string)
 func toChatMessages(messages []Message) []ask.ChatMessage {
 	out := make([]ask.ChatMessage, 0, len(messages))
 	for _, m := range messages {
-		if strings.TrimSpace(m.Role) == "" || strings.TrimSpace(m.Content) == "" {
+		if strings.TrimSpace(m.Role) == "" {
+			continue
+		}
+		if strings.TrimSpace(m.Content) == "" && len(m.ToolCalls) == 0 && m.Role != "tool" {
 			continue
 		}
-		out = append(out, ask.ChatMessage{Role: m.Role, Content: m.Content})
+		out = append(out, ask.ChatMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCalls:  m.ToolCalls,
+			ToolCallID: m.ToolCallID,
+			Name:       m.Name,
+		})
 	}
 	return out
 }
@@ -51,3 +51,31 @@ func TestApplyInvestigateRole_AutoSelect(t *testing.T) {
 		t.Fatal("expected system prompt")
 	}
 }
+
+func TestWithSystemPrompt_MergesIntoPlannerMessage(t *testing.T) {
+	msgs := []ask.ChatMessage{{Role: "system", Content: "protocol"}, {Role: "user", Content: "Goal: x"}}
+	out := withSystemPrompt(msgs, "role prompt")
+	if len(out) != 2 || out[0].Content != "role prompt\n\nprotocol" {
+		t.Errorf("withSystemPrompt = %+v", out)
+	}
+	if msgs[0].Content != "protocol" {
+		t.Error("withSystemPrompt modified its input")
+	}
+	if out := withSystemPrompt(msgs[1:], "role prompt"); len(out) != 2 || out[0].Role != "system" {
+		t.Errorf("withSystemPrompt without planner system = %+v", out)
+	}
+	if out := withSystemPrompt(msgs, " "); len(out) != 2 || out[0].Content != "protocol" {
+		t.Errorf("withSystemPrompt empty prompt = %+v", out)
+	}
+}
+
+func TestParseNativeTools(t *testing.T) {
+	for in, want := range map[string]string{"": NativeToolsAuto, " ON ": NativeToolsOn, "off": NativeToolsOff, "auto": NativeToolsAuto} {
+		if got, err := parseNativeTools(in); err != nil || got != want {
+			t.Errorf("parseNativeTools(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := parseNativeTools("yes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -180,6 +180,17 @@ func PersistToolExecution(ctx context.Context, command, outcome string, exitCode
 	return persistDrawer(ctx, "tool", content, command)
 }
 
+func PersistFixResult(ctx context.Context, command string, exitCode int, diagnosis, suggestion, outcome string) error {
+	command = strings.TrimSpace(command)
+	diagnosis = strings.TrimSpace(diagnosis)
+	if command == "" || diagnosis == "" {
+		return nil
+	}
+	content := fmt.Sprintf("command: %s\nexit_code: %d\ndiagnosis: %s\nsuggestion: %s\noutcome: %s",
+		command, exitCode, diagnosis, strings.TrimSpace(suggestion), strings.TrimSpace(outcome))
+	return persistDrawer(ctx, "fix", content, command)
+}
+
 func PersistRoleDecision(ctx context.Context, inputText, selectedRole, reason string) error {
 	inputText = strings.TrimSpace(inputText)
 	selectedRole = strings.TrimSpace(selectedRole)
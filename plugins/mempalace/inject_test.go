@@ -159,3 +159,24 @@ func TestPersistChatTurn_UsesChatRoom(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestPersistFixResult_UsesFixRoom(t *testing.T) {
+	prevCall := callToolFn
+	callToolFn = func(_ context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
+		if args["room"] != "fix" || args["query"] != "make test" {
+			t.Fatalf("unexpected room/query: %#v", args)
+		}
+		content := args["content"].(string)
+		if !strings.Contains(content, "exit_code: 2") || !strings.Contains(content, "suggestion: go mod tidy") || !strings.Contains(content, "outcome: skipped") {
+			t.Fatalf("unexpected content: %q", content)
+		}
+		return json.RawMessage(`{"ok":true}`), nil
+	}
+	t.Cleanup(func() {
+		callToolFn = prevCall
+	})
+
+	if err := PersistFixResult(context.Background(), "make test", 2, "missing go.sum entry", "go mod tidy", "skipped"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gaia/kernel"
+	"gaia/plugins/ask"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type ModelsPlugin struct {
+	providers map[string]ask.Provider
+}
+
+func NewModelsPlugin() *ModelsPlugin {
+	p := &ModelsPlugin{
+		providers: map[string]ask.Provider{},
+	}
+	p.RegisterProvider(ask.NewOllamaProvider())
+	p.RegisterProvider(ask.NewOpenAIProvider())
+	p.RegisterProvider(ask.NewMistralProvider())
+	return p
+}
+
+func (p *ModelsPlugin) ID() string           { return "models" }
+func (p *ModelsPlugin) DefaultEnabled() bool { return true }
+func (p *ModelsPlugin) DependsOn() []string  { return nil }
+func (p *ModelsPlugin) ConfigSchema() []string {
+	return []string{
+		"models.provider",
+		"models.host",
+		"models.port",
+		"models.timeout_seconds",
+		"models.capabilities",
+	}
+}
+
+func (p *ModelsPlugin) MCPTools() []kernel.MCPTool { return nil }
+
+func (p *ModelsPlugin) RegisterProvider(provider ask.Provider) {
+	if provider == nil {
+		return
+	}
+	p.providers[provider.Name()] = provider
+}
+
+func (p *ModelsPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
+	root := &cobra.Command{
+		Use:   "models",
+		Short: "List and manage provider models",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List available models",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := buildRequest(cmd, "")
+			lister, ok := p.providers[req.Provider].(ask.ModelLister)
+			if !ok {
+				return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Provider %q cannot list models", req.Provider))
+			}
+			models, err := lister.ListModels(cmd.Context(), req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if len(models) == 0 {
+				return shared.PrintBox(cmd.OutOrStdout(), "Models", "No models found")
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Models", formatModelList(models))
+		},
+	}
+
+	pullCmd := &cobra.Command{
+		Use:   "pull [model]",
+		Short: "Pull an Ollama model",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := buildRequest(cmd, args[0])
+			// Downloads can take far longer than a chat request; only the user can cancel them.
+			req.Timeout = 0
+			ollama, err := p.ollama(req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if err := ollama.PullModel(cmd.Context(), req); err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Models", fmt.Sprintf("Pulled %s", req.Model))
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show [model]",
+		Short: "Show an Ollama model's details, parameters and template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := buildRequest(cmd, args[0])
+			ollama, err := p.ollama(req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			details, err := ollama.ShowModel(cmd.Context(), req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Model", formatModelDetails(details))
+		},
+	}
+
+	rmCmd := &cobra.Command{
+		Use:   "rm [model]",
+		Short: "Remove an Ollama model",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := buildRequest(cmd, args[0])
+			ollama, err := p.ollama(req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
+					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("no TTY available to confirm removing %s (pass --yes to remove it)", req.Model))
+				}
+				ok, err := shared.RunConfirmationPromptTUI(fmt.Sprintf("Remove %s from %s:%d?", req.Model, req.Host, req.Port), "Models", cmd.InOrStdin(), cmd.OutOrStdout())
+				if err != nil {
+					return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+				}
+				if !ok {
+					return shared.PrintBox(cmd.OutOrStdout(), "Models", fmt.Sprintf("Kept %s", req.Model))
+				}
+			}
+			if err := ollama.DeleteModel(cmd.Context(), req); err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Models", fmt.Sprintf("Removed %s", req.Model))
+		},
+	}
+
+	rmCmd.Flags().BoolP("yes", "y", false, "Remove the model without asking")
+
+	psCmd := &cobra.Command{
+		Use:   "ps",
+		Short: "Show models loaded in Ollama",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := buildRequest(cmd, "")
+			ollama, err := p.ollama(req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			running, err := ollama.RunningModels(cmd.Context(), req)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if len(running) == 0 {
+				return shared.PrintBox(cmd.OutOrStdout(), "Models", "No models loaded")
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Models", formatRunning(running, time.Now()))
+		},
+	}
+
+	root.AddCommand(listCmd, pullCmd, showCmd, rmCmd, psCmd)
+	return []*cobra.Command{root}, nil
+}
+
+// ollamaManager is the Ollama-only model management API.
+type ollamaManager interface {
+	PullModel(ctx context.Context, req ask.AskRequest) error
+	ShowModel(ctx context.Context, req ask.AskRequest) (ask.ModelDetails, error)
+	DeleteModel(ctx context.Context, req ask.AskRequest) error
+	RunningModels(ctx context.Context, req ask.AskRequest) ([]ask.RunningModel, error)
+}
+
+func (p *ModelsPlugin) ollama(req ask.AskRequest) (ollamaManager, error) {
+	if req.Provider != "ollama" {
+		return nil, fmt.Errorf("this command is only supported for ollama (provider is %q)", req.Provider)
+	}
+	m, ok := p.providers["ollama"].(ollamaManager)
+	if !ok {
+		return nil, fmt.Errorf("ollama provider is not available")
+	}
+	return m, nil
+}
+
+// buildRequest resolves provider, host and port from models.* with kernel-level fallbacks.
+func buildRequest(cmd *cobra.Command, model string) ask.AskRequest {
+	req := ask.AskRequest{
+		Provider:    ask.FirstNonEmpty(viper.GetString("models.provider"), viper.GetString("provider")),
+		Host:        ask.FirstNonEmpty(viper.GetString("models.host"), viper.GetString("host")),
+		Port:        ask.FirstNonZero(viper.GetInt("models.port"), viper.GetInt("port")),
+		Model:       strings.TrimSpace(model),
+		Timeout:     time.Duration(ask.FirstNonZero(viper.GetInt("models.timeout_seconds"), viper.GetInt("timeout_seconds"))) * time.Second,
+		ProgressOut: cmd.ErrOrStderr(),
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 120 * time.Second
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = ask.ResolveProviderFromModel(viper.GetString("model"))
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = "ollama"
+	}
+	return req
+}
+
+func formatModelList(models []ask.ModelInfo) string {
+	var b strings.Builder
+	for _, m := range models {
+		fields := []string{m.Name}
+		if m.Size > 0 {
+			fields = append(fields, humanSize(m.Size))
+		}
+		if m.ParameterSize != "" || m.Quantization != "" {
+			fields = append(fields, strings.TrimSpace(m.ParameterSize+" "+m.Quantization))
+		}
+		if m.OwnedBy != "" {
+			fields = append(fields, m.OwnedBy)
+		}
+		if !m.ModifiedAt.IsZero() {
+			fields = append(fields, m.ModifiedAt.Format(time.RFC3339))
+		}
+		b.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatModelDetails(d ask.ModelDetails) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Name: %s\n", d.Name)
+	if d.Family != "" {
+		fmt.Fprintf(&b, "Family: %s\n", d.Family)
+	}
+	if d.ParameterSize != "" {
+		fmt.Fprintf(&b, "Parameters: %s\n", d.ParameterSize)
+	}
+	if d.Quantization != "" {
+		fmt.Fprintf(&b, "Quantization: %s\n", d.Quantization)
+	}
+	if d.ContextLength > 0 {
+		fmt.Fprintf(&b, "Context length: %d\n", d.ContextLength)
+	}
+	if len(d.Capabilities) > 0 {
+		fmt.Fprintf(&b, "Capabilities: %s\n", strings.Join(d.Capabilities, ", "))
+	}
+	if d.Parameters != "" {
+		fmt.Fprintf(&b, "\nModel parameters:\n%s\n", d.Parameters)
+	}
+	if d.Template != "" {
+		fmt.Fprintf(&b, "\nTemplate:\n%s\n", d.Template)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatRunning(running []ask.RunningModel, now time.Time) string {
+	var b strings.Builder
+	for _, m := range running {
+		fields := []string{m.Name, humanSize(m.Size)}
+		if m.Size > 0 {
+			fields = append(fields, fmt.Sprintf("%d%% GPU", m.SizeVRAM*100/m.Size))
+		}
+		if !m.ExpiresAt.IsZero() {
+			fields = append(fields, "expires in "+m.ExpiresAt.Sub(now).Round(time.Second).String())
+		}
+		b.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func humanSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
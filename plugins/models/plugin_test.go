@@ -0,0 +1,115 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gaia/plugins/ask"
+
+	"github.com/spf13/viper"
+)
+
+func TestHumanSize(t *testing.T) {
+	tests := map[int64]string{
+		512:        "512 B",
+		1500:       "1.5 kB",
+		4700000000: "4.7 GB",
+	}
+	for in, want := range tests {
+		if got := humanSize(in); got != want {
+			t.Errorf("humanSize(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatModelList(t *testing.T) {
+	out := formatModelList([]ask.ModelInfo{
+		{Name: "llama3:latest", Size: 4700000000, ParameterSize: "8B", Quantization: "Q4_0"},
+		{Name: "gpt-4o", OwnedBy: "system"},
+	})
+	lines := strings.Split(out, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if lines[0] != "llama3:latest\t4.7 GB\t8B Q4_0" || lines[1] != "gpt-4o\tsystem" {
+		t.Errorf("out = %q", out)
+	}
+}
+
+func TestFormatRunning(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	out := formatRunning([]ask.RunningModel{{
+		Name: "llama3:latest", Size: 6000000000, SizeVRAM: 3000000000, ExpiresAt: now.Add(5 * time.Minute),
+	}}, now)
+	if out != "llama3:latest\t6.0 GB\t50% GPU\texpires in 5m0s" {
+		t.Errorf("out = %q", out)
+	}
+}
+
+func TestOllamaOnlyCommands(t *testing.T) {
+	p := NewModelsPlugin()
+	if _, err := p.ollama(ask.AskRequest{Provider: "openai"}); err == nil {
+		t.Error("expected error for non-ollama provider")
+	}
+	if _, err := p.ollama(ask.AskRequest{Provider: "ollama"}); err != nil {
+		t.Errorf("ollama: %v", err)
+	}
+}
+
+// fakeOllama records the models it is asked to delete.
+type fakeOllama struct{ deleted []string }
+
+func (f *fakeOllama) Name() string { return "ollama" }
+func (f *fakeOllama) Send(context.Context, ask.AskRequest) (ask.AskResponse, error) {
+	return ask.AskResponse{}, nil
+}
+func (f *fakeOllama) SendStream(context.Context, ask.AskRequest, func(string)) (ask.AskResponse, error) {
+	return ask.AskResponse{}, nil
+}
+func (f *fakeOllama) PullModel(context.Context, ask.AskRequest) error { return nil }
+func (f *fakeOllama) ShowModel(context.Context, ask.AskRequest) (ask.ModelDetails, error) {
+	return ask.ModelDetails{}, nil
+}
+func (f *fakeOllama) DeleteModel(_ context.Context, req ask.AskRequest) error {
+	f.deleted = append(f.deleted, req.Model)
+	return nil
+}
+func (f *fakeOllama) RunningModels(context.Context, ask.AskRequest) ([]ask.RunningModel, error) {
+	return nil, nil
+}
+
+func TestRemoveRequiresConfirmation(t *testing.T) {
+	viper.Set("models.provider", "ollama")
+	t.Cleanup(func() { viper.Set("models.provider", nil) })
+	fake := &fakeOllama{}
+	p := NewModelsPlugin()
+	p.RegisterProvider(fake)
+	cmds, err := p.Register(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := cmds[0]
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+
+	// Tests run without a TTY, so the removal cannot be confirmed.
+	root.SetArgs([]string{"rm", "llama3"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.deleted) != 0 || !strings.Contains(out.String(), "--yes") {
+		t.Errorf("removed without confirmation: %v, output %q", fake.deleted, out.String())
+	}
+
+	root.SetArgs([]string{"rm", "--yes", "llama3"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != "llama3" {
+		t.Errorf("deleted = %v", fake.deleted)
+	}
+}
@@ -0,0 +1,216 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"gaia/kernel"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+)
+
+// PromptPlugin manages reusable prompt templates and runs them through ask.
+type PromptPlugin struct {
+	k *kernel.Kernel
+}
+
+func NewPromptPlugin() *PromptPlugin { return &PromptPlugin{} }
+
+func (p *PromptPlugin) ID() string                 { return "prompt" }
+func (p *PromptPlugin) DefaultEnabled() bool       { return true }
+func (p *PromptPlugin) DependsOn() []string        { return []string{"ask"} }
+func (p *PromptPlugin) ConfigSchema() []string     { return []string{"prompt.directory"} }
+func (p *PromptPlugin) MCPTools() []kernel.MCPTool { return nil }
+
+// askFlags are forwarded from prompt run to ask when given.
+var askFlags = []string{"provider", "model", "role", "no-cache", "save", "show-thinking", "json", "schema"}
+
+func (p *PromptPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
+	p.k = k
+	root := &cobra.Command{
+		Use:   "prompt",
+		Short: "Manage and run prompt templates",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List prompt templates",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			templates, err := LoadAll()
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			if dir, trusted := RepoPromptsDir(); dir != "" && !trusted {
+				_ = shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Ignoring %s: repository is not trusted (gaia config trust)", dir))
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Prompts", formatList(templates))
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a prompt template and its variables",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := Find(args[0])
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintBox(cmd.OutOrStdout(), "Prompt "+t.Name, formatTemplate(t))
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run <name>",
+		Short: "Render a prompt template and send it with ask",
+		Args:  cobra.ExactArgs(1),
+		RunE:  p.run,
+	}
+	runCmd.Flags().StringArray("var", nil, "Template variable as name=value, or name=@file to read a file (repeatable)")
+	runCmd.Flags().Bool("print", false, "Print the rendered prompt instead of sending it")
+	runCmd.Flags().String("provider", "", "Provider name (overrides the template)")
+	runCmd.Flags().String("model", "", "Model name (overrides the template)")
+	runCmd.Flags().String("role", "", "Role name (overrides the template)")
+	runCmd.Flags().Bool("no-cache", false, "Disable cache for this request")
+	runCmd.Flags().String("save", "", "Also write the question and answer to a file (.md, .json or .html)")
+	runCmd.Flags().Bool("show-thinking", false, "Show model reasoning in a dimmed pane above the answer")
+	runCmd.Flags().Bool("json", false, "Request JSON output and print only the validated JSON")
+	runCmd.Flags().String("schema", "", "Path to a JSON Schema the reply must satisfy (implies --json)")
+
+	root.AddCommand(listCmd, showCmd, runCmd)
+	return []*cobra.Command{root}, nil
+}
+
+func (p *PromptPlugin) run(cmd *cobra.Command, args []string) error {
+	t, err := Find(args[0])
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	pairs, _ := cmd.Flags().GetStringArray("var")
+	vars, err := ParseVars(pairs)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	if _, set := vars[t.StdinVariable()]; !set && shared.HasPipedStdin() {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		vars[t.StdinVariable()] = string(data)
+	}
+	text, err := t.Render(vars)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	if printOnly, _ := cmd.Flags().GetBool("print"); printOnly {
+		return shared.PrintRaw(cmd.OutOrStdout(), text+"\n")
+	}
+	if strings.TrimSpace(text) == "" {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Prompt %s rendered to an empty message", t.Name))
+	}
+
+	askCmd, _, err := p.k.RootCmd.Find([]string{"ask"})
+	if err != nil || askCmd == p.k.RootCmd || askCmd.RunE == nil {
+		return shared.PrintError(cmd.ErrOrStderr(), "prompt run needs the ask plugin")
+	}
+	defaults := map[string]string{"provider": t.Provider, "model": t.Model, "role": t.Role}
+	for _, name := range askFlags {
+		value := defaults[name]
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			value = f.Value.String()
+		}
+		if value == "" {
+			continue
+		}
+		if err := askCmd.Flags().Set(name, value); err != nil {
+			return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+		}
+	}
+	askCmd.SetContext(cmd.Context())
+	askCmd.SetIn(cmd.InOrStdin())
+	askCmd.SetOut(cmd.OutOrStdout())
+	askCmd.SetErr(cmd.ErrOrStderr())
+	return askCmd.RunE(askCmd, []string{text})
+}
+
+// MCPPrompts exposes every template as an MCP prompt; its variables are the arguments.
+func (p *PromptPlugin) MCPPrompts() []kernel.MCPPrompt {
+	templates, err := LoadAll()
+	if err != nil {
+		return nil
+	}
+	prompts := make([]kernel.MCPPrompt, 0, len(templates))
+	for _, t := range templates {
+		prompts = append(prompts, mcpPrompt(t))
+	}
+	return prompts
+}
+
+func mcpPrompt(t Template) kernel.MCPPrompt {
+	args := make([]kernel.MCPPromptArgument, 0, len(t.Variables)+1)
+	stdinDeclared := false
+	for _, v := range t.Variables {
+		args = append(args, kernel.MCPPromptArgument{Name: v.Name, Description: v.Description, Required: v.Required})
+		stdinDeclared = stdinDeclared || v.Name == t.StdinVariable()
+	}
+	if !stdinDeclared && strings.Contains(t.Template, "."+t.StdinVariable()) {
+		args = append(args, kernel.MCPPromptArgument{Name: t.StdinVariable(), Description: "Input text"})
+	}
+	return kernel.MCPPrompt{
+		Name:        t.Name,
+		Description: t.Description,
+		Arguments:   args,
+		Handler: func(_ context.Context, vars map[string]string) (string, error) {
+			return t.Render(vars)
+		},
+	}
+}
+
+func formatList(templates []Template) string {
+	if len(templates) == 0 {
+		return fmt.Sprintf("No prompts found. Add YAML templates to %s or .gaia/prompts in a trusted repository.", UserPromptsDir())
+	}
+	width := 0
+	for _, t := range templates {
+		width = max(width, len(t.Name))
+	}
+	var b strings.Builder
+	for _, t := range templates {
+		fmt.Fprintf(&b, "%-*s  %s\n", width, t.Name, t.Description)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatTemplate(t Template) string {
+	var b strings.Builder
+	if t.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", t.Description)
+	}
+	fmt.Fprintf(&b, "File: %s\n", t.Path)
+	for _, field := range [][2]string{{"Role", t.Role}, {"Provider", t.Provider}, {"Model", t.Model}} {
+		if field[1] != "" {
+			fmt.Fprintf(&b, "%s: %s\n", field[0], field[1])
+		}
+	}
+	fmt.Fprintf(&b, "Stdin: .%s\n", t.StdinVariable())
+	if len(t.Variables) > 0 {
+		b.WriteString("\nVariables:\n")
+		for _, v := range t.Variables {
+			line := "  " + v.Name
+			if v.Required {
+				line += " (required)"
+			} else if v.Default != "" {
+				line += fmt.Sprintf(" (default %q)", v.Default)
+			}
+			if v.Description != "" {
+				line += ": " + v.Description
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	fmt.Fprintf(&b, "\nTemplate:\n%s", strings.TrimRight(t.Template, "\n"))
+	return b.String()
+}
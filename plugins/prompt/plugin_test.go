@@ -0,0 +1,48 @@
+package prompt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gaia/kernel"
+	"gaia/plugins/ask"
+
+	"github.com/spf13/viper"
+)
+
+func TestRunForwardsTemplateProvider(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writePrompt(t, dir, "greet.yaml", "provider: replay\ntemplate: hi\n")
+	for key, value := range map[string]any{
+		"prompt.directory": dir,
+		"fixtures_dir":     t.TempDir(),
+		"ask.provider":     "ollama",
+		"ask.host":         "127.0.0.1",
+		"ask.port":         1,
+		"ask.model":        "llama3",
+	} {
+		viper.SetDefault(key, value)
+	}
+	t.Cleanup(viper.Reset)
+
+	k := kernel.NewKernel()
+	for _, p := range []kernel.Plugin{ask.NewAskPlugin(), NewPromptPlugin()} {
+		cmds, err := p.Register(k)
+		if err != nil {
+			t.Fatalf("Register %s: %v", p.ID(), err)
+		}
+		k.RootCmd.AddCommand(cmds...)
+	}
+	var out bytes.Buffer
+	k.RootCmd.SetArgs([]string{"prompt", "run", "greet", "--no-cache"})
+	k.RootCmd.SetIn(strings.NewReader(""))
+	k.RootCmd.SetOut(&out)
+	k.RootCmd.SetErr(&out)
+	_ = k.RootCmd.Execute()
+	// The empty fixtures directory proves the request went to the template's provider.
+	if !strings.Contains(out.String(), ask.ErrFixtureNotFound.Error()) {
+		t.Errorf("prompt run should send through provider: replay, got:\n%s", out.String())
+	}
+}
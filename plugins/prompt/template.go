@@ -0,0 +1,252 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gaia/config"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// stdinVariable receives piped input when a template does not name another variable.
+const stdinVariable = "input"
+
+// Variable is a value a template expects.
+type Variable struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Default     string `yaml:"default,omitempty"`
+}
+
+// Template is a reusable prompt loaded from YAML.
+type Template struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description,omitempty"`
+	Role        string     `yaml:"role,omitempty"`
+	Provider    string     `yaml:"provider,omitempty"`
+	Model       string     `yaml:"model,omitempty"`
+	Stdin       string     `yaml:"stdin,omitempty"` // variable that receives piped input
+	Variables   []Variable `yaml:"variables,omitempty"`
+	Template    string     `yaml:"template"`
+
+	Path string `yaml:"-"` // file the template was loaded from
+}
+
+// StdinVariable returns the variable piped input is bound to.
+func (t Template) StdinVariable() string {
+	if v := strings.TrimSpace(t.Stdin); v != "" {
+		return v
+	}
+	return stdinVariable
+}
+
+// declared reports whether name is a declared variable or the stdin variable.
+func (t Template) declared(name string) bool {
+	if name == t.StdinVariable() {
+		return true
+	}
+	for _, v := range t.Variables {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Template) parse() (*template.Template, error) {
+	return template.New(t.Name).Option("missingkey=error").Parse(t.Template)
+}
+
+// Render executes the template with vars. Defaults fill unset variables; a missing
+// required variable or an undeclared one is an error.
+func (t Template) Render(vars map[string]string) (string, error) {
+	data := map[string]string{}
+	for _, v := range t.Variables {
+		data[v.Name] = v.Default
+	}
+	var unknown []string
+	for name, value := range vars {
+		if !t.declared(name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		data[name] = value
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("prompt %s: unknown variable(s) %s", t.Name, strings.Join(unknown, ", "))
+	}
+	var missing []string
+	for _, v := range t.Variables {
+		if v.Required && strings.TrimSpace(data[v.Name]) == "" {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt %s: missing required variable(s) %s (use --var name=value)", t.Name, strings.Join(missing, ", "))
+	}
+	if _, ok := data[t.StdinVariable()]; !ok {
+		data[t.StdinVariable()] = ""
+	}
+	tmpl, err := t.parse()
+	if err != nil {
+		return "", fmt.Errorf("prompt %s: %w", t.Name, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("prompt %s: %w", t.Name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func validateTemplate(t Template) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("prompt file %s: missing name", t.Path)
+	}
+	if strings.TrimSpace(t.Template) == "" {
+		return fmt.Errorf("prompt %s: missing template", t.Name)
+	}
+	seen := map[string]bool{}
+	for _, v := range t.Variables {
+		if strings.TrimSpace(v.Name) == "" {
+			return fmt.Errorf("prompt %s: variable name is empty", t.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("prompt %s: variable %s declared twice", t.Name, v.Name)
+		}
+		seen[v.Name] = true
+	}
+	if _, err := t.parse(); err != nil {
+		return fmt.Errorf("prompt %s: %w", t.Name, err)
+	}
+	return nil
+}
+
+// LoadTemplates loads the prompt templates of a directory. A missing directory has none.
+func LoadTemplates(dir string) ([]Template, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read prompts directory: %w", err)
+	}
+	var templates []Template
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml")) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read prompt file %s: %w", path, err)
+		}
+		var t Template
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("parse prompt file %s: %w", path, err)
+		}
+		if t.Name == "" {
+			t.Name = strings.TrimSuffix(strings.TrimSuffix(name, ".yaml"), ".yml")
+		}
+		t.Path = path
+		if err := validateTemplate(t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// UserPromptsDir returns prompt.directory, or prompts/ under the gaia config directory.
+func UserPromptsDir() string {
+	if dir := strings.TrimSpace(viper.GetString("prompt.directory")); dir != "" {
+		return dir
+	}
+	return filepath.Join(config.ConfigDir(), "prompts")
+}
+
+// RepoPromptsDir returns .gaia/prompts of the current repository when the repository is
+// trusted (gaia config trust), like local .gaia.yaml overrides.
+func RepoPromptsDir() (string, bool) {
+	root, err := config.ResolveRepositoryRootFromPath(".")
+	if err != nil {
+		return "", false
+	}
+	dir := filepath.Join(root, ".gaia", "prompts")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	if trusted, err := config.IsRepositoryTrusted(root); err != nil || !trusted {
+		return dir, false
+	}
+	return dir, true
+}
+
+// LoadAll loads the user templates and those of the trusted repository, sorted by name.
+// Repository templates replace user templates of the same name.
+func LoadAll() ([]Template, error) {
+	byName := map[string]Template{}
+	dirs := []string{UserPromptsDir()}
+	if dir, ok := RepoPromptsDir(); ok {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		templates, err := LoadTemplates(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range templates {
+			byName[t.Name] = t
+		}
+	}
+	out := make([]Template, 0, len(byName))
+	for _, t := range byName {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Find returns the template called name.
+func Find(name string) (Template, error) {
+	templates, err := LoadAll()
+	if err != nil {
+		return Template{}, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Template{}, fmt.Errorf("prompt %q not found (gaia prompt list shows the available prompts)", name)
+}
+
+// ParseVars reads name=value pairs; a value starting with @ is read from that file.
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q (use name=value or name=@file)", pair)
+		}
+		if path, isFile := strings.CutPrefix(value, "@"); isFile {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("--var %s: %w", name, err)
+			}
+			value = string(data)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
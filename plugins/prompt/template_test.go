@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writePrompt(t *testing.T, dir, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const reviewPrompt = `description: Review a diff
+role: code
+variables:
+  - name: focus
+    default: correctness
+  - name: ticket
+    required: true
+template: |
+  Review for {{ .focus }} ({{ .ticket }}):
+  {{ .input }}
+`
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "review.yaml", reviewPrompt)
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	if len(templates) != 1 || templates[0].Name != "review" || templates[0].Role != "code" {
+		t.Fatalf("templates = %+v", templates)
+	}
+	tmpl := templates[0]
+
+	got, err := tmpl.Render(map[string]string{"ticket": "OPS-1", "input": "+line"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "Review for correctness (OPS-1):\n+line"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+	if _, err := tmpl.Render(nil); err == nil || !strings.Contains(err.Error(), "ticket") {
+		t.Errorf("missing required variable: err = %v", err)
+	}
+	if _, err := tmpl.Render(map[string]string{"ticket": "x", "bogus": "y"}); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("unknown variable: err = %v", err)
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	if templates, err := LoadTemplates(filepath.Join(t.TempDir(), "missing")); err != nil || templates != nil {
+		t.Errorf("missing directory: %v, %v", templates, err)
+	}
+	dir := t.TempDir()
+	writePrompt(t, dir, "broken.yaml", "template: \"{{ .x \"\n")
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Errorf("a template that does not parse should fail to load")
+	}
+}
+
+func TestLoadAllRepositoryNeedsTrust(t *testing.T) {
+	userDir := t.TempDir()
+	viper.Set("prompt.directory", userDir)
+	t.Cleanup(func() { viper.Set("prompt.directory", "") })
+	writePrompt(t, userDir, "review.yaml", reviewPrompt)
+
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writePrompt(t, filepath.Join(repo, ".gaia", "prompts"), "review.yaml", "description: repo\ntemplate: hi\n")
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(repo)
+
+	if dir, trusted := RepoPromptsDir(); dir == "" || trusted {
+		t.Fatalf("RepoPromptsDir = %q, %t; want the untrusted repository directory", dir, trusted)
+	}
+	tmpl, err := Find("review")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if tmpl.Description != "Review a diff" {
+		t.Errorf("an untrusted repository should not replace user prompts, got %q", tmpl.Description)
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diff.txt")
+	if err := os.WriteFile(path, []byte("+added"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := ParseVars([]string{"focus=a=b", "input=@" + path})
+	if err != nil {
+		t.Fatalf("ParseVars: %v", err)
+	}
+	if vars["focus"] != "a=b" || vars["input"] != "+added" {
+		t.Errorf("vars = %v", vars)
+	}
+	if _, err := ParseVars([]string{"novalue"}); err == nil {
+		t.Errorf("a pair without = should fail")
+	}
+}
+
+func TestMCPPrompt(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "review.yaml", reviewPrompt)
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := mcpPrompt(templates[0])
+	var names []string
+	for _, a := range p.Arguments {
+		names = append(names, a.Name)
+	}
+	if got := strings.Join(names, ","); got != "focus,ticket,input" {
+		t.Errorf("arguments = %s", got)
+	}
+	text, err := p.Handler(context.Background(), map[string]string{"ticket": "OPS-2"})
+	if err != nil || !strings.HasPrefix(text, "Review for correctness (OPS-2):") {
+		t.Errorf("Handler = %q, %v", text, err)
+	}
+}
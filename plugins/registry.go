@@ -6,11 +6,17 @@ import (
 	"gaia/plugins/cache"
 	"gaia/plugins/chat"
 	configplugin "gaia/plugins/config"
+	"gaia/plugins/embed"
+	"gaia/plugins/hooks"
 	"gaia/plugins/investigate"
 	"gaia/plugins/mempalace"
+	"gaia/plugins/models"
+	"gaia/plugins/prompt"
+	"gaia/plugins/review"
 	"gaia/plugins/roles"
 	"gaia/plugins/sanitize"
 	"gaia/plugins/serve"
+	"gaia/plugins/shell"
 	"gaia/plugins/tasks"
 	"gaia/plugins/tools"
 	"gaia/plugins/version"
@@ -57,5 +63,23 @@ func RegisterAll(k *kernel.Kernel) error {
 	if err := k.RegisterPlugin(serve.NewServePlugin()); err != nil {
 		return err
 	}
+	if err := k.RegisterPlugin(models.NewModelsPlugin()); err != nil {
+		return err
+	}
+	if err := k.RegisterPlugin(embed.NewEmbedPlugin()); err != nil {
+		return err
+	}
+	if err := k.RegisterPlugin(prompt.NewPromptPlugin()); err != nil {
+		return err
+	}
+	if err := k.RegisterPlugin(shell.NewShellPlugin()); err != nil {
+		return err
+	}
+	if err := k.RegisterPlugin(review.NewReviewPlugin()); err != nil {
+		return err
+	}
+	if err := k.RegisterPlugin(hooks.NewHooksPlugin()); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,161 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Target selects the changes to review.
+type Target struct {
+	Staged bool   // changes in the index
+	Base   string // changes of HEAD since it forked from Base
+	Range  string // a revision range such as main..feature
+}
+
+// GitDiff returns the diff of target. The zero target reviews all uncommitted changes.
+func GitDiff(ctx context.Context, target Target) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "-U3"}
+	switch {
+	case target.Staged && (target.Base != "" || target.Range != ""):
+		return "", errors.New("--staged cannot be combined with --base or a range")
+	case target.Base != "" && target.Range != "":
+		return "", errors.New("use either --base or a range")
+	case target.Staged:
+		args = append(args, "--staged")
+	case target.Base != "":
+		args = append(args, target.Base+"...HEAD")
+	case target.Range != "":
+		args = append(args, target.Range)
+	default:
+		args = append(args, "HEAD")
+	}
+	// nosemgrep
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
+
+// FileDiff is the part of a diff that changes one file.
+type FileDiff struct {
+	Path    string
+	Binary  bool
+	Deleted bool
+	Lines   []string // hunk lines, starting with their @@ header
+}
+
+var diffGitPattern = regexp.MustCompile(`^diff --git a/(.*) b/(.*)$`)
+
+// ParseDiff splits a unified git diff by file.
+func ParseDiff(diff string) []FileDiff {
+	var files []FileDiff
+	var cur *FileDiff
+	inHunk := false
+	for _, line := range strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n") {
+		if m := diffGitPattern.FindStringSubmatch(line); m != nil {
+			files = append(files, FileDiff{Path: m[2]})
+			cur = &files[len(files)-1]
+			inHunk = false
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+			cur.Lines = append(cur.Lines, line)
+		case inHunk:
+			if line != "" {
+				cur.Lines = append(cur.Lines, line)
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if path := strings.TrimPrefix(line, "+++ "); path == "/dev/null" {
+				cur.Deleted = true
+			} else {
+				cur.Path = strings.TrimPrefix(path, "b/")
+			}
+		case strings.HasPrefix(line, "Binary files "):
+			cur.Binary = true
+		}
+	}
+	return files
+}
+
+// Chunk is the part of a file diff sent to the model in one request.
+type Chunk struct {
+	File  string
+	Part  int // 1-based
+	Parts int
+	Text  string
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// Chunks numbers the changed lines of files with their line in the new file, so findings
+// can point at them, and splits each file into chunks of at most maxBytes. Binary and
+// deleted files are skipped.
+func Chunks(files []FileDiff, maxBytes int) []Chunk {
+	var chunks []Chunk
+	for _, f := range files {
+		if f.Binary || f.Deleted || len(f.Lines) == 0 {
+			continue
+		}
+		var parts []string
+		var b strings.Builder
+		for _, line := range annotate(f.Lines) {
+			if maxBytes > 0 && b.Len() > 0 && b.Len()+len(line)+1 > maxBytes {
+				parts = append(parts, b.String())
+				b.Reset()
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		if b.Len() > 0 {
+			parts = append(parts, b.String())
+		}
+		for i, text := range parts {
+			chunks = append(chunks, Chunk{File: f.Path, Part: i + 1, Parts: len(parts), Text: text})
+		}
+	}
+	return chunks
+}
+
+// annotate prefixes added and context lines with their number in the new file.
+func annotate(lines []string) []string {
+	out := make([]string, 0, len(lines))
+	next := 0
+	for _, line := range lines {
+		if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			next, _ = strconv.Atoi(m[1])
+			out = append(out, line)
+			continue
+		}
+		switch line[0] {
+		case '+':
+			out = append(out, fmt.Sprintf("%5d + %s", next, line[1:]))
+			next++
+		case '-':
+			out = append(out, fmt.Sprintf("      - %s", line[1:]))
+		case '\\':
+			// "\ No newline at end of file"
+		default:
+			out = append(out, fmt.Sprintf("%5d   %s", next, strings.TrimPrefix(line, " ")))
+			next++
+		}
+	}
+	return out
+}
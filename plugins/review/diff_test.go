@@ -0,0 +1,112 @@
+package review
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,4 +10,5 @@ func main() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := a + b
+ 	fmt.Println(a)
+\ No newline at end of file
+diff --git a/logo.png b/logo.png
+index 3333333..4444444 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 5555555..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..6666666
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++--- not a header
++second
+`
+
+func TestParseDiff(t *testing.T) {
+	files := ParseDiff(sampleDiff)
+	if len(files) != 4 {
+		t.Fatalf("ParseDiff returned %d files", len(files))
+	}
+	if files[0].Path != "main.go" || len(files[0].Lines) != 7 {
+		t.Errorf("main.go = %+v", files[0])
+	}
+	if !files[1].Binary {
+		t.Errorf("logo.png not binary: %+v", files[1])
+	}
+	if !files[2].Deleted {
+		t.Errorf("old.txt not deleted: %+v", files[2])
+	}
+	if files[3].Path != "new.txt" || len(files[3].Lines) != 3 {
+		t.Errorf("new.txt = %+v", files[3])
+	}
+}
+
+func TestChunksAnnotateLines(t *testing.T) {
+	chunks := Chunks(ParseDiff(sampleDiff), 0)
+	if len(chunks) != 2 {
+		t.Fatalf("Chunks returned %d chunks: %+v", len(chunks), chunks)
+	}
+	main := chunks[0].Text
+	for _, want := range []string{"   10   \ta := 1", "      - \tb := 2", "   11 + \tb := 3", "   12 + \tc := a + b", "   13   \tfmt.Println(a)"} {
+		if !strings.Contains(main, want+"\n") {
+			t.Errorf("main.go chunk missing %q:\n%s", want, main)
+		}
+	}
+	if strings.Contains(main, "No newline") {
+		t.Errorf("main.go chunk kept the no-newline marker:\n%s", main)
+	}
+	if !strings.Contains(chunks[1].Text, "    1 + --- not a header") {
+		t.Errorf("new.txt chunk:\n%s", chunks[1].Text)
+	}
+}
+
+func TestChunksSplitLargeFiles(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("diff --git a/big.go b/big.go\n--- a/big.go\n+++ b/big.go\n@@ -0,0 +1,100 @@\n")
+	for i := 0; i < 100; i++ {
+		b.WriteString("+line of code\n")
+	}
+	chunks := Chunks(ParseDiff(b.String()), 500)
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	lines := 0
+	for i, c := range chunks {
+		if len(c.Text) > 500 {
+			t.Errorf("chunk %d has %d bytes", i, len(c.Text))
+		}
+		if c.Part != i+1 || c.Parts != len(chunks) || c.File != "big.go" {
+			t.Errorf("chunk %d = %+v", i, c)
+		}
+		lines += strings.Count(c.Text, "+ line of code")
+	}
+	if lines != 100 {
+		t.Errorf("chunks hold %d lines, want 100", lines)
+	}
+	if !strings.Contains(chunks[len(chunks)-1].Text, "  100 + line of code") {
+		t.Errorf("last chunk lost line numbers:\n%s", chunks[len(chunks)-1].Text)
+	}
+}
+
+func TestGitDiffRejectsConflictingTargets(t *testing.T) {
+	if _, err := GitDiff(t.Context(), Target{Staged: true, Base: "main"}); err == nil {
+		t.Error("expected an error for --staged with --base")
+	}
+	if _, err := GitDiff(t.Context(), Target{Base: "main", Range: "a..b"}); err == nil {
+		t.Error("expected an error for --base with a range")
+	}
+}
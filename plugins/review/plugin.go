@@ -0,0 +1,193 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gaia/kernel"
+	"gaia/plugins/ask"
+	"gaia/plugins/roles"
+	"gaia/plugins/shared"
+	"gaia/plugins/version"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultPrompt is used when the review role is not installed.
+const defaultPrompt = "You are a senior engineer reviewing a code change. Report only real problems introduced or " +
+	"exposed by the change: bugs, security issues, broken error handling and performance traps. Do not report style " +
+	"preferences. Use error for bugs and security issues, warning for likely problems and info for minor improvements. " +
+	"Return no findings when the change looks correct."
+
+// ReviewPlugin reviews git diffs with a model and reports findings per line.
+type ReviewPlugin struct {
+	providers map[string]ask.Provider
+}
+
+func NewReviewPlugin() *ReviewPlugin {
+	p := &ReviewPlugin{
+		providers: map[string]ask.Provider{},
+	}
+	p.RegisterProvider(ask.NewOllamaProvider())
+	p.RegisterProvider(ask.NewOpenAIProvider())
+	p.RegisterProvider(ask.NewMistralProvider())
+	return p
+}
+
+func (p *ReviewPlugin) ID() string           { return "review" }
+func (p *ReviewPlugin) DefaultEnabled() bool { return true }
+func (p *ReviewPlugin) DependsOn() []string  { return nil }
+func (p *ReviewPlugin) ConfigSchema() []string {
+	return []string{
+		"review.provider",
+		"review.model",
+		"review.role",
+		"review.timeout_seconds",
+		"review.concurrency",
+		"review.max_chunk_bytes",
+		"review.fail_on",
+	}
+}
+
+func (p *ReviewPlugin) MCPTools() []kernel.MCPTool { return nil }
+
+func (p *ReviewPlugin) RegisterProvider(provider ask.Provider) {
+	if provider == nil {
+		return
+	}
+	p.providers[provider.Name()] = provider
+}
+
+func (p *ReviewPlugin) Register(_ *kernel.Kernel) ([]*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:   "review [<range>]",
+		Short: "Review git changes with a model",
+		Long: "Review git changes with a model. Without arguments, all uncommitted changes are reviewed; " +
+			"--staged reviews the index, --base the commits since the branch forked, and a range such as " +
+			"main..feature the commits in it. Each file is reviewed separately, in chunks for large diffs.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: p.review,
+	}
+	cmd.Flags().Bool("staged", false, "Review staged changes")
+	cmd.Flags().String("base", "", "Review the commits of HEAD since it forked from this branch")
+	cmd.Flags().String("format", "terminal", "Output format: terminal, json or sarif")
+	cmd.Flags().String("provider", "", "Provider name (overrides review.provider)")
+	cmd.Flags().String("model", "", "Model name (overrides review.model)")
+	cmd.Flags().String("role", "", "Role name (overrides review.role)")
+	cmd.Flags().Int("concurrency", 0, "Chunks reviewed at the same time (default review.concurrency)")
+	cmd.Flags().String("fail-on", "", "Exit non-zero on findings at least this severe: none, error, warning or info (default review.fail_on)")
+	return []*cobra.Command{cmd}, nil
+}
+
+func (p *ReviewPlugin) review(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	format = strings.ToLower(strings.TrimSpace(format))
+	if !isFormat(format) {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("unknown format %q (supported: %s)", format, strings.Join(Formats, ", ")))
+	}
+	failFlag, _ := cmd.Flags().GetString("fail-on")
+	failOn, err := FailOn(ask.FirstNonEmpty(failFlag, viper.GetString("review.fail_on")))
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	target := Target{}
+	target.Staged, _ = cmd.Flags().GetBool("staged")
+	target.Base, _ = cmd.Flags().GetString("base")
+	if len(args) == 1 {
+		target.Range = args[0]
+	}
+	diff, err := GitDiff(cmd.Context(), target)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	chunks := Chunks(ParseDiff(diff), viper.GetInt("review.max_chunk_bytes"))
+	if len(chunks) == 0 {
+		if format == "terminal" {
+			return shared.PrintBox(cmd.OutOrStdout(), "Review", "No changes to review")
+		}
+		return WriteReport(cmd.OutOrStdout(), format, Report{Findings: []Finding{}}, version.Version)
+	}
+
+	providerFlag, _ := cmd.Flags().GetString("provider")
+	modelFlag, _ := cmd.Flags().GetString("model")
+	req := ask.AskRequest{
+		Provider: ask.FirstNonEmpty(providerFlag, ask.FirstNonEmpty(viper.GetString("review.provider"), viper.GetString("provider"))),
+		Host:     viper.GetString("host"),
+		Port:     viper.GetInt("port"),
+		Model:    ask.FirstNonEmpty(modelFlag, ask.FirstNonEmpty(viper.GetString("review.model"), viper.GetString("model"))),
+		Timeout:  time.Duration(ask.FirstNonZero(viper.GetInt("review.timeout_seconds"), viper.GetInt("timeout_seconds"))) * time.Second,
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 120 * time.Second
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = ask.ResolveProviderFromModel(req.Model)
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return shared.PrintError(cmd.ErrOrStderr(), "missing review configuration: model")
+	}
+	provider, err := ask.SelectProvider(p.providers, req.Provider)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	roleFlag, _ := cmd.Flags().GetString("role")
+	prompt, err := systemPrompt(ask.FirstNonEmpty(roleFlag, viper.GetString("review.role")), req)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	req.SystemPrompt = prompt
+
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	concurrency = ask.FirstNonZero(concurrency, viper.GetInt("review.concurrency"))
+	retries := viper.GetInt("ask.json_retries")
+	report := Run(cmd.Context(), provider, req, chunks, concurrency, retries)
+	if err := WriteReport(cmd.OutOrStdout(), format, report, version.Version); err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	// A review with unreviewed chunks, or findings at --fail-on, must fail CI.
+	var failure string
+	if len(report.Errors) > 0 {
+		failure = fmt.Sprintf("%d of %d chunks could not be reviewed", len(report.Errors), report.Chunks)
+	} else if n := report.Exceeds(failOn); n > 0 {
+		failure = fmt.Sprintf("%d findings at or above %s", n, failOn)
+	}
+	if failure == "" {
+		return nil
+	}
+	_ = shared.PrintError(cmd.ErrOrStderr(), "Review failed: "+failure)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	return errors.New("review failed")
+}
+
+func isFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// systemPrompt returns the prompt of the named role, or the built-in one when the review
+// role is not installed.
+func systemPrompt(name string, req ask.AskRequest) (string, error) {
+	name = ask.FirstNonEmpty(strings.TrimSpace(name), "review")
+	list, err := roles.LoadRolesWithDefaults()
+	if err != nil {
+		return "", err
+	}
+	resolved, err := roles.ResolveInheritance(list)
+	if err != nil {
+		return "", err
+	}
+	if role, ok := resolved[name]; ok {
+		return roles.ResolveSystemPrompt(role, req.Provider, req.Model), nil
+	}
+	if name != "review" {
+		return "", fmt.Errorf("role %q not found", name)
+	}
+	return defaultPrompt, nil
+}
@@ -0,0 +1,158 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gaia/plugins/shared"
+)
+
+// Formats lists the report formats.
+var Formats = []string{"terminal", "json", "sarif"}
+
+// WriteReport writes report in format.
+func WriteReport(w io.Writer, format string, report Report, toolVersion string) error {
+	switch format {
+	case "", "terminal":
+		return writeTerminal(w, report)
+	case "json":
+		return writeJSON(w, report)
+	case "sarif":
+		return writeJSON(w, SARIF(report, toolVersion))
+	default:
+		return fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeTerminal(w io.Writer, report Report) error {
+	var file string
+	var lines []string
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		err := shared.PrintBox(w, file, strings.Join(lines, "\n"))
+		lines = nil
+		return err
+	}
+	for _, f := range report.Findings {
+		if f.File != file {
+			if err := flush(); err != nil {
+				return err
+			}
+			file = f.File
+		}
+		location := "-"
+		if f.Line > 0 {
+			location = fmt.Sprintf("L%d", f.Line)
+		}
+		lines = append(lines, fmt.Sprintf("%-7s %5s  %s", f.Severity, location, f.Message))
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	for _, e := range report.Errors {
+		if err := shared.PrintError(w, fmt.Sprintf("%s (part %d) was not reviewed: %s", e.File, e.Part, e.Message)); err != nil {
+			return err
+		}
+	}
+	return shared.PrintBox(w, "Review", summary(report))
+}
+
+func summary(report Report) string {
+	counts := map[string]int{}
+	for _, f := range report.Findings {
+		counts[f.Severity]++
+	}
+	var parts []string
+	for _, sev := range Severities {
+		if n := counts[sev]; n > 0 {
+			parts = append(parts, plural(n, sev))
+		}
+	}
+	s := "No findings"
+	if len(parts) > 0 {
+		s = plural(len(report.Findings), "finding") + " (" + strings.Join(parts, ", ") + ")"
+	}
+	s += fmt.Sprintf(" in %s, %s reviewed", plural(report.Files, "file"), plural(report.Chunks, "chunk"))
+	if len(report.Errors) > 0 {
+		s += fmt.Sprintf(", %d failed", len(report.Errors))
+	}
+	return s
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// sarifRuleID is the rule every review finding is reported under.
+const sarifRuleID = "gaia-review"
+
+// SARIF converts report to a SARIF 2.1.0 log, as read by code scanning in CI.
+func SARIF(report Report, toolVersion string) map[string]any {
+	results := make([]map[string]any, 0, len(report.Findings))
+	for _, f := range report.Findings {
+		location := map[string]any{"artifactLocation": map[string]any{"uri": f.File}}
+		if f.Line > 0 {
+			location["region"] = map[string]any{"startLine": f.Line}
+		}
+		results = append(results, map[string]any{
+			"ruleId":    sarifRuleID,
+			"level":     sarifLevel(f.Severity),
+			"message":   map[string]any{"text": f.Message},
+			"locations": []map[string]any{{"physicalLocation": location}},
+		})
+	}
+	notifications := make([]map[string]any, 0, len(report.Errors))
+	for _, e := range report.Errors {
+		notifications = append(notifications, map[string]any{
+			"level":   "error",
+			"message": map[string]any{"text": fmt.Sprintf("%s (part %d) was not reviewed: %s", e.File, e.Part, e.Message)},
+		})
+	}
+	driver := map[string]any{
+		"name":           "gaia",
+		"informationUri": "https://github.com/vonglasow/gaia",
+		"rules": []map[string]any{{
+			"id":               sarifRuleID,
+			"shortDescription": map[string]any{"text": "Code review finding"},
+		}},
+	}
+	if toolVersion != "" {
+		driver["version"] = toolVersion
+	}
+	return map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool":    map[string]any{"driver": driver},
+			"results": results,
+			"invocations": []map[string]any{{
+				"executionSuccessful":        len(report.Errors) == 0,
+				"toolExecutionNotifications": notifications,
+			}},
+		}},
+	}
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case "error":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,111 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var sampleReport = Report{
+	Files:  2,
+	Chunks: 3,
+	Findings: []Finding{
+		{File: "a.go", Line: 4, Severity: "error", Message: "nil dereference"},
+		{File: "a.go", Line: 9, Severity: "warning", Message: "unchecked error"},
+		{File: "b.go", Severity: "info", Message: "consider a test"},
+	},
+	Errors: []ChunkError{{File: "c.go", Part: 1, Message: "timeout"}},
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, "json", sampleReport, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Findings) != 3 || got.Findings[0] != sampleReport.Findings[0] || len(got.Errors) != 1 {
+		t.Errorf("round trip = %+v", got)
+	}
+}
+
+func TestWriteReportSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, "sarif", sampleReport, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string                `json:"ruleId"`
+				Level     string                `json:"level"`
+				Message   struct{ Text string } `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string } `json:"artifactLocation"`
+						Region           *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+			Invocations []struct {
+				ExecutionSuccessful bool `json:"executionSuccessful"`
+			} `json:"invocations"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("sarif = %s", buf.String())
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "gaia" || run.Tool.Driver.Version != "1.0.0" {
+		t.Errorf("driver = %+v", run.Tool.Driver)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("results = %+v", run.Results)
+	}
+	first := run.Results[0]
+	loc := first.Locations[0].PhysicalLocation
+	if first.Level != "error" || first.RuleID != sarifRuleID || loc.ArtifactLocation.URI != "a.go" || loc.Region == nil || loc.Region.StartLine != 4 {
+		t.Errorf("first result = %+v", first)
+	}
+	if run.Results[2].Level != "note" || run.Results[2].Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("info result = %+v", run.Results[2])
+	}
+	if run.Invocations[0].ExecutionSuccessful {
+		t.Error("executionSuccessful with failed chunks")
+	}
+}
+
+func TestWriteReportTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, "terminal", sampleReport, ""); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"a.go", "L4", "nil dereference", "b.go", "c.go (part 1) was not reviewed: timeout",
+		"3 findings (1 error, 1 warning, 1 info) in 2 files, 3 chunks reviewed, 1 failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("terminal report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteReportUnknownFormat(t *testing.T) {
+	if err := WriteReport(&bytes.Buffer{}, "xml", sampleReport, ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,184 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gaia/plugins/ask"
+)
+
+// Severities of findings, most severe first.
+var Severities = []string{"error", "warning", "info"}
+
+// Finding is one problem reported by the review.
+type Finding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ChunkError records a chunk the model could not review.
+type ChunkError struct {
+	File    string `json:"file"`
+	Part    int    `json:"part"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of a review.
+type Report struct {
+	Files    int          `json:"files"`
+	Chunks   int          `json:"chunks"`
+	Findings []Finding    `json:"findings"`
+	Errors   []ChunkError `json:"errors,omitempty"`
+}
+
+// findingsSchema is the JSON reply expected for each chunk.
+var findingsSchema = json.RawMessage(`{"type":"object","properties":{"findings":{"type":"array","items":{"type":"object",` +
+	`"properties":{"line":{"type":"integer"},"severity":{"type":"string"},"message":{"type":"string"}},` +
+	`"required":["line","severity","message"]}}},"required":["findings"]}`)
+
+// Run reviews chunks with up to concurrency requests at a time. req carries the provider
+// settings and the system prompt.
+func Run(ctx context.Context, provider ask.Provider, req ask.AskRequest, chunks []Chunk, concurrency int, retries int) Report {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	type result struct {
+		findings []Finding
+		err      error
+	}
+	results := make([]result, len(chunks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			findings, err := reviewChunk(ctx, provider, req, chunk, retries)
+			results[i] = result{findings: findings, err: err}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Chunks: len(chunks), Findings: []Finding{}}
+	files := map[string]bool{}
+	for i, r := range results {
+		files[chunks[i].File] = true
+		if r.err != nil {
+			report.Errors = append(report.Errors, ChunkError{File: chunks[i].File, Part: chunks[i].Part, Message: r.err.Error()})
+			continue
+		}
+		report.Findings = append(report.Findings, r.findings...)
+	}
+	report.Files = len(files)
+	SortFindings(report.Findings)
+	return report
+}
+
+func reviewChunk(ctx context.Context, provider ask.Provider, req ask.AskRequest, chunk Chunk, retries int) ([]Finding, error) {
+	req.Message = chunkPrompt(chunk)
+	req.Format = &ask.ResponseFormat{Schema: findingsSchema}
+	cctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+	out, err := ask.SendStructured(cctx, provider, req, retries)
+	if err != nil {
+		return nil, err
+	}
+	var reply struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(out), &reply); err != nil {
+		return nil, err
+	}
+	findings := make([]Finding, 0, len(reply.Findings))
+	for _, f := range reply.Findings {
+		f.Message = strings.TrimSpace(f.Message)
+		if f.Message == "" {
+			continue
+		}
+		f.File = chunk.File
+		f.Severity = NormalizeSeverity(f.Severity)
+		if f.Line < 0 {
+			f.Line = 0
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func chunkPrompt(chunk Chunk) string {
+	part := ""
+	if chunk.Parts > 1 {
+		part = fmt.Sprintf(" (part %d of %d)", chunk.Part, chunk.Parts)
+	}
+	return fmt.Sprintf("Review the changes to %s%s. Each line starts with its number in the new file; "+
+		"+ marks added lines and - removed lines, which have no number. Report findings on added lines, with severity error, warning or info.\n\n~~~~diff\n%s~~~~",
+		chunk.File, part, chunk.Text)
+}
+
+// NormalizeSeverity maps the severity of a reply to error, warning or info.
+func NormalizeSeverity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error", "critical", "high", "blocker", "bug":
+		return "error"
+	case "warning", "warn", "medium":
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func severityRank(s string) int {
+	for i, sev := range Severities {
+		if sev == s {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// FailOn checks --fail-on: "none" (or empty) never fails, otherwise a severity fails the
+// review on any finding at least that severe.
+func FailOn(severity string) (string, error) {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if severity == "" || severity == "none" {
+		return "none", nil
+	}
+	if severityRank(severity) == len(Severities) {
+		return "", fmt.Errorf("unknown severity %q for --fail-on (supported: none, %s)", severity, strings.Join(Severities, ", "))
+	}
+	return severity, nil
+}
+
+// Exceeds counts the findings at least as severe as threshold; "none" counts none.
+func (r Report) Exceeds(threshold string) int {
+	limit := severityRank(threshold)
+	n := 0
+	for _, f := range r.Findings {
+		if limit < len(Severities) && severityRank(f.Severity) <= limit {
+			n++
+		}
+	}
+	return n
+}
+
+// SortFindings orders findings by file, then line, then severity.
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return severityRank(a.Severity) < severityRank(b.Severity)
+	})
+}
@@ -0,0 +1,132 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gaia/plugins/ask"
+)
+
+type fakeProvider struct {
+	reply   func(req ask.AskRequest) (string, error)
+	active  atomic.Int32
+	maxSeen atomic.Int32
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Send(_ context.Context, req ask.AskRequest) (ask.AskResponse, error) {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		seen := p.maxSeen.Load()
+		if n <= seen || p.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	text, err := p.reply(req)
+	return ask.AskResponse{Text: text}, err
+}
+
+func (p *fakeProvider) SendStream(ctx context.Context, req ask.AskRequest, onChunk func(string)) (ask.AskResponse, error) {
+	return p.Send(ctx, req)
+}
+
+func lastUserMessage(req ask.AskRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return req.Message
+}
+
+func TestRun(t *testing.T) {
+	provider := &fakeProvider{reply: func(req ask.AskRequest) (string, error) {
+		msg := lastUserMessage(req)
+		switch {
+		case strings.Contains(msg, "broken.go"):
+			return "", errors.New("model unavailable")
+		case strings.Contains(msg, "b.go"):
+			return `{"findings":[{"line":3,"severity":"HIGH","message":"nil dereference"},{"line":1,"severity":"low","message":" "}]}`, nil
+		default:
+			return `{"findings":[{"line":9,"severity":"warning","message":"unchecked error"},{"line":2,"severity":"info","message":"typo"}]}`, nil
+		}
+	}}
+	chunks := []Chunk{
+		{File: "b.go", Part: 1, Parts: 1, Text: "    3 + x.y\n"},
+		{File: "a.go", Part: 1, Parts: 2, Text: "    2 + z\n"},
+		{File: "a.go", Part: 2, Parts: 2, Text: "    9 + f()\n"},
+		{File: "broken.go", Part: 1, Parts: 1, Text: "    1 + q\n"},
+	}
+	req := ask.AskRequest{Model: "m", Timeout: time.Second, SystemPrompt: "review"}
+	report := Run(context.Background(), provider, req, chunks, 2, 0)
+
+	if report.Files != 3 || report.Chunks != 4 {
+		t.Errorf("report files=%d chunks=%d", report.Files, report.Chunks)
+	}
+	want := []Finding{
+		{File: "a.go", Line: 2, Severity: "info", Message: "typo"},
+		{File: "a.go", Line: 2, Severity: "info", Message: "typo"},
+		{File: "a.go", Line: 9, Severity: "warning", Message: "unchecked error"},
+		{File: "a.go", Line: 9, Severity: "warning", Message: "unchecked error"},
+		{File: "b.go", Line: 3, Severity: "error", Message: "nil dereference"},
+	}
+	if len(report.Findings) != len(want) {
+		t.Fatalf("findings = %+v", report.Findings)
+	}
+	for i := range want {
+		if report.Findings[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, report.Findings[i], want[i])
+		}
+	}
+	if len(report.Errors) != 1 || report.Errors[0].File != "broken.go" || !strings.Contains(report.Errors[0].Message, "model unavailable") {
+		t.Errorf("errors = %+v", report.Errors)
+	}
+	if got := provider.maxSeen.Load(); got > 2 {
+		t.Errorf("%d requests ran at once, want at most 2", got)
+	}
+}
+
+func TestChunkPrompt(t *testing.T) {
+	prompt := chunkPrompt(Chunk{File: "a.go", Part: 2, Parts: 3, Text: "    1 + x\n"})
+	for _, want := range []string{"a.go (part 2 of 3)", "~~~~diff\n    1 + x\n~~~~"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("chunkPrompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(chunkPrompt(Chunk{File: "a.go", Part: 1, Parts: 1}), "part") {
+		t.Error("single chunk prompt mentions parts")
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	cases := map[string]string{"Error": "error", "critical": "error", "warn": "warning", "Medium": "warning", "low": "info", "": "info"}
+	for in, want := range cases {
+		if got := NormalizeSeverity(in); got != want {
+			t.Errorf("NormalizeSeverity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFailOn(t *testing.T) {
+	report := Report{Findings: []Finding{{Severity: "warning"}, {Severity: "info"}, {Severity: "warning"}}}
+	cases := map[string]int{"": 0, "none": 0, "error": 0, "Warning": 2, "info": 3}
+	for in, want := range cases {
+		threshold, err := FailOn(in)
+		if err != nil {
+			t.Fatalf("FailOn(%q): %v", in, err)
+		}
+		if got := report.Exceeds(threshold); got != want {
+			t.Errorf("Exceeds(%q) = %d, want %d", threshold, got, want)
+		}
+	}
+	if _, err := FailOn("high"); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
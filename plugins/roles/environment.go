@@ -0,0 +1,104 @@
+package roles
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// placeholderPattern matches the prompt placeholders filled from the environment:
+// %s, %[1]s (operating system) and %[2]s (shell).
+var placeholderPattern = regexp.MustCompile(`%%|%s|%\[([12])\]s`)
+
+// FillPlaceholders fills the placeholders of a role prompt. %[1]s is the operating system and
+// %[2]s the shell; plain %s take the operating system, then the shell. Any other % is kept.
+func FillPlaceholders(prompt, osName, shell string) string {
+	if !strings.Contains(prompt, "%") {
+		return prompt
+	}
+	values := []string{osName, shell}
+	next := 0
+	return placeholderPattern.ReplaceAllStringFunc(prompt, func(m string) string {
+		switch m {
+		case "%%":
+			return m
+		case "%s":
+			if next >= len(values) {
+				return m
+			}
+			next++
+			return values[next-1]
+		case "%[1]s":
+			return osName
+		default:
+			return shell
+		}
+	})
+}
+
+var detectedOS = sync.OnceValue(func() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS"
+	case "windows":
+		return "Windows"
+	case "linux":
+		if name := osReleaseName("/etc/os-release"); name != "" {
+			return name
+		}
+		return "Linux"
+	default:
+		return runtime.GOOS
+	}
+})
+
+// DetectOS returns the name of the operating system, such as "macOS" or "Ubuntu 24.04 LTS":
+// $GAIA_OS when set, so prompts and replay fixtures can be pinned across machines, then the
+// detected system.
+func DetectOS() string {
+	if name := strings.TrimSpace(os.Getenv("GAIA_OS")); name != "" {
+		return name
+	}
+	return detectedOS()
+}
+
+// osReleaseName reads PRETTY_NAME (or NAME) from an os-release file.
+func osReleaseName(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	if name := values["PRETTY_NAME"]; name != "" {
+		return name
+	}
+	return values["NAME"]
+}
+
+// DetectShell returns the user's shell: $GAIA_SHELL, set by the shell-init widgets, then the
+// name of $SHELL.
+func DetectShell() string {
+	if shell := strings.TrimSpace(os.Getenv("GAIA_SHELL")); shell != "" {
+		return shell
+	}
+	if shell := strings.TrimSpace(os.Getenv("SHELL")); shell != "" {
+		return filepath.Base(shell)
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
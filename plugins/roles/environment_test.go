@@ -0,0 +1,72 @@
+package roles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFillPlaceholders(t *testing.T) {
+	cases := []struct {
+		prompt string
+		want   string
+	}{
+		{"managing %s operating system with %s shell", "managing Ubuntu operating system with zsh shell"},
+		{"only %[2]s commands for %[1]s", "only zsh commands for Ubuntu"},
+		{"no placeholders", "no placeholders"},
+		{"100% sure, %d and %% kept", "100% sure, %d and %% kept"},
+		{"%s %s %s", "Ubuntu zsh %s"},
+	}
+	for _, c := range cases {
+		if got := FillPlaceholders(c.prompt, "Ubuntu", "zsh"); got != c.want {
+			t.Errorf("FillPlaceholders(%q) = %q, want %q", c.prompt, got, c.want)
+		}
+	}
+}
+
+func TestResolveSystemPromptFillsPlaceholders(t *testing.T) {
+	t.Setenv("GAIA_SHELL", "fish")
+	t.Setenv("GAIA_OS", "")
+	role := ResolvedRole{Name: "shell", SystemPrompt: "%[2]s commands for %[1]s"}
+	want := "fish commands for " + DetectOS()
+	if got := ResolveSystemPrompt(role, "", ""); got != want {
+		t.Errorf("ResolveSystemPrompt = %q, want %q", got, want)
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	t.Setenv("GAIA_SHELL", "")
+	t.Setenv("SHELL", "/usr/local/bin/zsh")
+	if got := DetectShell(); got != "zsh" {
+		t.Errorf("DetectShell from SHELL = %q", got)
+	}
+	t.Setenv("GAIA_SHELL", "bash")
+	if got := DetectShell(); got != "bash" {
+		t.Errorf("DetectShell from GAIA_SHELL = %q", got)
+	}
+}
+
+func TestDetectOSOverride(t *testing.T) {
+	t.Setenv("GAIA_OS", "macOS")
+	if got := DetectOS(); got != "macOS" {
+		t.Errorf("DetectOS from GAIA_OS = %q", got)
+	}
+	t.Setenv("GAIA_OS", "")
+	if got := DetectOS(); got != detectedOS() {
+		t.Errorf("DetectOS = %q, want detected %q", got, detectedOS())
+	}
+}
+
+func TestOSReleaseName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := "NAME=\"Ubuntu\"\nVERSION_ID=\"24.04\"\nPRETTY_NAME=\"Ubuntu 24.04 LTS\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := osReleaseName(path); got != "Ubuntu 24.04 LTS" {
+		t.Errorf("osReleaseName = %q", got)
+	}
+	if got := osReleaseName(filepath.Join(t.TempDir(), "missing")); got != "" {
+		t.Errorf("osReleaseName missing = %q", got)
+	}
+}
@@ -2,8 +2,13 @@ package roles
 
 import "strings"
 
-// ResolveSystemPrompt returns the best system prompt for a role, considering model/provider overrides.
+// ResolveSystemPrompt returns the best system prompt for a role, considering model/provider overrides,
+// with its placeholders filled from the detected operating system and shell.
 func ResolveSystemPrompt(role ResolvedRole, provider, model string) string {
+	return FillPlaceholders(selectSystemPrompt(role, provider, model), DetectOS(), DetectShell())
+}
+
+func selectSystemPrompt(role ResolvedRole, provider, model string) string {
 	model = strings.TrimSpace(model)
 	provider = strings.TrimSpace(provider)
 	if model != "" {
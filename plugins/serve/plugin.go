@@ -163,6 +163,16 @@ func (p *ServePlugin) runDaemon(ctx context.Context) error {
 		}
 	}
 
+	for _, plugin := range p.k.Plugins() {
+		provider, ok := plugin.(kernel.MCPPromptProvider)
+		if !ok {
+			continue
+		}
+		for _, prompt := range provider.MCPPrompts() {
+			addPrompt(server, prompt)
+		}
+	}
+
 	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
 		return server
 	}, nil)
@@ -186,6 +196,28 @@ func (p *ServePlugin) runDaemon(ctx context.Context) error {
 	return nil
 }
 
+// addPrompt registers a plugin prompt; its text is returned as a single user message.
+func addPrompt(server *mcp.Server, prompt kernel.MCPPrompt) {
+	args := make([]*mcp.PromptArgument, 0, len(prompt.Arguments))
+	for _, a := range prompt.Arguments {
+		args = append(args, &mcp.PromptArgument{Name: a.Name, Description: a.Description, Required: a.Required})
+	}
+	server.AddPrompt(&mcp.Prompt{
+		Name:        prompt.Name,
+		Description: prompt.Description,
+		Arguments:   args,
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		text, err := prompt.Handler(ctx, req.Params.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.GetPromptResult{
+			Description: prompt.Description,
+			Messages:    []*mcp.PromptMessage{{Role: "user", Content: &mcp.TextContent{Text: text}}},
+		}, nil
+	})
+}
+
 func (p *ServePlugin) runStop(cmd *cobra.Command, _ []string) error {
 	pid, err := readPID(pidPath())
 	if err != nil {
@@ -0,0 +1,20 @@
+package shared
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// CopyOSC52 asks the terminal to put text on the system clipboard with the OSC 52 escape
+// sequence. It works over SSH; inside tmux the sequence is wrapped in a passthrough.
+func CopyOSC52(w io.Writer, text string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err := fmt.Fprint(w, seq)
+	return err
+}
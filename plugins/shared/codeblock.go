@@ -0,0 +1,63 @@
+package shared
+
+import "strings"
+
+// CodeBlock is a fenced code block of a Markdown answer.
+type CodeBlock struct {
+	Lang string // first word of the fence info string, lower-cased; empty when unset
+	Code string
+}
+
+// ExtractCodeBlocks returns the fenced code blocks (``` or ~~~) of text in order. A block
+// left open at the end, as in a truncated answer, runs to the end of the text.
+func ExtractCodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := codeFence(lines[i])
+		if !ok {
+			continue
+		}
+		var code []string
+		for i++; i < len(lines); i++ {
+			if closing, rest, ok := codeFence(lines[i]); ok && rest == "" && closing[0] == fence[0] && len(closing) >= len(fence) {
+				break
+			}
+			code = append(code, lines[i])
+		}
+		lang, _, _ := strings.Cut(info, " ")
+		blocks = append(blocks, CodeBlock{Lang: strings.ToLower(strings.Trim(lang, "{}.")), Code: strings.Join(code, "\n")})
+	}
+	return blocks
+}
+
+// codeFence reports whether line is a code fence: at most three spaces of indentation,
+// then three or more backticks or tildes. It returns the fence and the info string.
+func codeFence(line string) (fence, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return "", "", false
+	}
+	marker := trimmed[0]
+	if marker != '`' && marker != '~' {
+		return "", "", false
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, string(marker)))
+	if n < 3 {
+		return "", "", false
+	}
+	info = strings.TrimSpace(trimmed[n:])
+	if marker == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	return trimmed[:n], info, true
+}
+
+// JoinCode joins the code of blocks, separated by a blank line.
+func JoinCode(blocks []CodeBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		parts = append(parts, strings.TrimRight(b.Code, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,20 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	answer := "Intro with ```inline``` code.\n\n```Go\nfunc main() {}\n```\n\nThen:\n\n~~~bash\necho hi\n~~~\n\n````markdown\n```\nnested\n```\n````\n\n```python\nprint('cut off')"
+	blocks := ExtractCodeBlocks(answer)
+	require.Equal(t, []CodeBlock{
+		{Lang: "go", Code: "func main() {}"},
+		{Lang: "bash", Code: "echo hi"},
+		{Lang: "markdown", Code: "```\nnested\n```"},
+		{Lang: "python", Code: "print('cut off')"},
+	}, blocks)
+	require.Equal(t, "func main() {}\n\necho hi", JoinCode(blocks[:2]))
+	require.Empty(t, ExtractCodeBlocks("no code here"))
+}
@@ -0,0 +1,111 @@
+package shared
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type comparePaneMsg struct {
+	pane  int
+	chunk string
+}
+
+type compareDoneMsg struct{}
+
+// comparePaneLines is the number of trailing lines each pane shows while streaming.
+const comparePaneLines = 12
+
+// compareModel renders one fixed-width pane per model, side by side.
+type compareModel struct {
+	titles []string
+	bufs   []strings.Builder
+	width  int
+	done   bool
+}
+
+func newCompareModel(titles []string, width int) *compareModel {
+	return &compareModel{titles: titles, bufs: make([]strings.Builder, len(titles)), width: width}
+}
+
+func (m *compareModel) Init() tea.Cmd { return nil }
+
+func (m *compareModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case comparePaneMsg:
+		if msg.pane >= 0 && msg.pane < len(m.bufs) {
+			m.bufs[msg.pane].WriteString(msg.chunk)
+		}
+	case compareDoneMsg:
+		m.done = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *compareModel) View() string {
+	if m.width <= 0 || len(m.titles) == 0 {
+		return ""
+	}
+	paneWidth := m.width / len(m.titles)
+	if paneWidth < 4 {
+		return ""
+	}
+	panes := make([]string, len(m.titles))
+	for i, title := range m.titles {
+		body := "Waiting for response..."
+		if text := strings.TrimRight(m.bufs[i].String(), "\n"); strings.TrimSpace(text) != "" {
+			body = lipgloss.NewStyle().Width(paneWidth - 2).Render(text)
+		}
+		lines := strings.Split(body, "\n")
+		if len(lines) > comparePaneLines {
+			lines = lines[len(lines)-comparePaneLines:]
+		}
+		panes[i] = renderFixedWidthBox(title, strings.Join(lines, "\n"), paneWidth)
+	}
+	view := lipgloss.JoinHorizontal(lipgloss.Top, panes...)
+	if m.done {
+		return view + "\n"
+	}
+	return view
+}
+
+// DisplayComparison shows one streaming pane per title while run sends chunks tagged with their
+// pane index; send is safe for concurrent use. On a non-TTY nothing is drawn and run just executes.
+func DisplayComparison(ctx context.Context, w io.Writer, titles []string, run func(ctx context.Context, send func(pane int, chunk string))) error {
+	if !detectTTY(w) {
+		run(ctx, func(int, string) {})
+		return nil
+	}
+
+	initialWidth, _ := detectTerminalWidth(w)
+	p := tea.NewProgram(
+		newCompareModel(titles, initialWidth),
+		tea.WithContext(ctx),
+		tea.WithOutput(w),
+		tea.WithInput(nil),
+	)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		run(runCtx, func(pane int, chunk string) {
+			if chunk != "" {
+				p.Send(comparePaneMsg{pane: pane, chunk: chunk})
+			}
+		})
+		p.Send(compareDoneMsg{})
+	}()
+	_, err := p.Run()
+	// Stop in-flight requests if the view quit early (Ctrl-C), and never return while run
+	// is still writing the caller's results.
+	cancel()
+	<-finished
+	return err
+}
@@ -0,0 +1,43 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareModel_PanesSideBySide(t *testing.T) {
+	m := newCompareModel([]string{"model-a", "model-b"}, 80)
+	next, _ := m.Update(comparePaneMsg{pane: 1, chunk: "second answer"})
+	m = next.(*compareModel)
+	view := m.View()
+	lines := strings.Split(view, "\n")
+	require.Contains(t, lines[1], "model-a")
+	require.Contains(t, lines[1], "model-b")
+	require.Contains(t, view, "second answer")
+	require.Contains(t, view, "Waiting for response...")
+	for _, line := range lines {
+		require.Equal(t, 80, lipgloss.Width(line))
+	}
+}
+
+func TestDisplayComparison_NonTTYRunsWithoutDrawing(t *testing.T) {
+	prevDetectTTY := detectTTY
+	detectTTY = func(_ io.Writer) bool { return false }
+	t.Cleanup(func() { detectTTY = prevDetectTTY })
+
+	var out bytes.Buffer
+	ran := false
+	err := DisplayComparison(context.Background(), &out, []string{"a", "b"}, func(_ context.Context, send func(int, string)) {
+		send(0, "chunk")
+		ran = true
+	})
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.Empty(t, out.String())
+}
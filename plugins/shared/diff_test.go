@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	newText := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- a/x
++++ b/x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	require.Equal(t, want, UnifiedDiff("a/x", "b/x", oldText, newText))
+	require.Equal(t, "", UnifiedDiff("a/x", "b/x", oldText, oldText))
+}
+
+func TestUnifiedDiffNewFile(t *testing.T) {
+	want := "--- a/x\n+++ b/x\n@@ -0,0 +1,2 @@\n+one\n+two\n"
+	require.Equal(t, want, UnifiedDiff("a/x", "b/x", "", "one\ntwo\n"))
+}
+
+func TestColorizeDiff(t *testing.T) {
+	diff := UnifiedDiff("a/x", "b/x", "a\n", "b\n")
+	require.Equal(t, strings.Count(diff, "\n"), strings.Count(ColorizeDiff(diff), "\n"))
+	require.Contains(t, ColorizeDiff(diff), "+b")
+}
@@ -0,0 +1,56 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EditorCommand returns the user's editor: $VISUAL, then $EDITOR, then vi.
+func EditorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// EditText opens the editor on a temporary file holding initial and returns the saved
+// content. pattern names the file as in os.CreateTemp, so "*.md" gives Markdown highlighting.
+// The editor command may carry arguments ("code --wait"). When stdin or stdout is redirected,
+// the editor is attached to the controlling terminal instead.
+func EditText(initial, pattern string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	if _, err := f.WriteString(initial); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := EditorCommand()
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "gaia-editor", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if !HasTTYStdin() || !HasTTYStdout() {
+		if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+			defer tty.Close()
+			cmd.Stdin, cmd.Stdout = tty, tty
+		}
+	}
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q: %w", editor, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
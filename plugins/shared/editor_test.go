@@ -0,0 +1,20 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditText(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/hello/goodbye/")
+	require.Equal(t, "sed -i s/hello/goodbye/", EditorCommand())
+	out, err := EditText("hello world\n", "gaia-test-*.md")
+	require.NoError(t, err)
+	require.Equal(t, "goodbye world\n", out)
+
+	t.Setenv("EDITOR", "false")
+	_, err = EditText("x", "gaia-test-*.md")
+	require.Error(t, err)
+}
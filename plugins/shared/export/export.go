@@ -0,0 +1,277 @@
+// Package export renders conversations and answers as Markdown, JSON or standalone HTML.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is an export format.
+type Format string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatJSON     Format = "json"
+	FormatHTML     Format = "html"
+)
+
+// Message is one turn of a transcript.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Usage is the token count of the exported exchange.
+type Usage struct {
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	Estimated        bool `json:"estimated,omitempty"`
+}
+
+// Document is a transcript or a single answer with its metadata.
+type Document struct {
+	Title     string            `json:"title"`
+	Source    string            `json:"source"` // chat, ask, investigate or cache
+	Provider  string            `json:"provider,omitempty"`
+	Model     string            `json:"model,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitzero"`
+	UpdatedAt time.Time         `json:"updated_at,omitzero"`
+	Usage     *Usage            `json:"usage,omitempty"`
+	Summary   string            `json:"summary,omitempty"` // earlier turns, summarized
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Messages  []Message         `json:"messages"`
+}
+
+// ParseFormat reads a format name; "markdown" and "htm" are accepted aliases.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), ".")) {
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	case "json":
+		return FormatJSON, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (use md, json or html)", name)
+}
+
+// FormatForPath picks the format from a file extension, defaulting to Markdown.
+func FormatForPath(path string) Format {
+	if f, err := ParseFormat(filepath.Ext(path)); err == nil {
+		return f
+	}
+	return FormatMarkdown
+}
+
+// Render encodes doc in format f.
+func Render(doc Document, f Format) ([]byte, error) {
+	switch f {
+	case FormatMarkdown:
+		return []byte(renderMarkdown(doc)), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatHTML:
+		return []byte(renderHTML(doc)), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", f)
+}
+
+// WriteFile renders doc to path, in the format of its extension when f is empty.
+func WriteFile(path string, doc Document, f Format) error {
+	if f == "" {
+		f = FormatForPath(path)
+	}
+	data, err := Render(doc, f)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// metadataRows lists the document metadata in display order.
+func metadataRows(doc Document) [][2]string {
+	var rows [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			rows = append(rows, [2]string{name, value})
+		}
+	}
+	add("Source", doc.Source)
+	if doc.Provider != "" {
+		add("Model", fmt.Sprintf("%s (%s)", doc.Model, doc.Provider))
+	} else {
+		add("Model", doc.Model)
+	}
+	add("Role", doc.Role)
+	if !doc.CreatedAt.IsZero() {
+		add("Created", doc.CreatedAt.Format(time.RFC3339))
+	}
+	if !doc.UpdatedAt.IsZero() && !doc.UpdatedAt.Equal(doc.CreatedAt) {
+		add("Updated", doc.UpdatedAt.Format(time.RFC3339))
+	}
+	if u := doc.Usage; u != nil {
+		approx := ""
+		if u.Estimated {
+			approx = "~"
+		}
+		add("Tokens", fmt.Sprintf("%s%d prompt, %s%d completion", approx, u.PromptTokens, approx, u.CompletionTokens))
+	}
+	keys := make([]string, 0, len(doc.Metadata))
+	for k := range doc.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, doc.Metadata[k])
+	}
+	return rows
+}
+
+func roleHeading(role string) string {
+	switch role {
+	case "user":
+		return "You"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	if role == "" {
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+func renderMarkdown(doc Document) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", titleOf(doc))
+	for _, row := range metadataRows(doc) {
+		fmt.Fprintf(&b, "- **%s:** %s\n", row[0], row[1])
+	}
+	if doc.Summary != "" {
+		fmt.Fprintf(&b, "\n## Summary of earlier turns\n\n%s\n", strings.TrimSpace(doc.Summary))
+	}
+	for _, m := range doc.Messages {
+		// Content is Markdown already; it is copied as is so code fences survive.
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", roleHeading(m.Role), strings.TrimSpace(m.Content))
+	}
+	return b.String()
+}
+
+func titleOf(doc Document) string {
+	if t := strings.TrimSpace(doc.Title); t != "" {
+		return t
+	}
+	return "Gaia " + doc.Source
+}
+
+const htmlStyle = `body{font-family:system-ui,sans-serif;max-width:50rem;margin:2rem auto;padding:0 1rem;line-height:1.5;color:#222}
+h1{color:#7D56F4}dl{display:grid;grid-template-columns:max-content auto;gap:.2rem 1rem;color:#555}dt{font-weight:bold}dd{margin:0}
+section{border:1px solid #ddd;border-radius:8px;padding:.5rem 1rem;margin:1rem 0}section.user{background:#f6f4ff}
+section.summary{color:#666}h2{font-size:1rem;margin:.3rem 0}
+pre{background:#1e1e2e;color:#eee;padding:.8rem;border-radius:6px;overflow-x:auto}code{font-family:ui-monospace,monospace}`
+
+func renderHTML(doc Document) string {
+	var b strings.Builder
+	title := html.EscapeString(titleOf(doc))
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<h1>%s</h1>\n", title, htmlStyle, title)
+	if rows := metadataRows(doc); len(rows) > 0 {
+		b.WriteString("<dl>\n")
+		for _, row := range rows {
+			fmt.Fprintf(&b, "<dt>%s</dt><dd>%s</dd>\n", html.EscapeString(row[0]), html.EscapeString(row[1]))
+		}
+		b.WriteString("</dl>\n")
+	}
+	if doc.Summary != "" {
+		fmt.Fprintf(&b, "<section class=\"summary\">\n<h2>Summary of earlier turns</h2>\n%s</section>\n", markdownToHTML(doc.Summary))
+	}
+	for _, m := range doc.Messages {
+		fmt.Fprintf(&b, "<section class=\"%s\">\n<h2>%s</h2>\n%s</section>\n", html.EscapeString(m.Role), html.EscapeString(roleHeading(m.Role)), markdownToHTML(m.Content))
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// markdownToHTML converts the parts of Markdown that matter for model answers: fenced code
+// blocks become <pre><code>, paragraphs are split on blank lines and inline code is kept.
+// Everything else is escaped text.
+func markdownToHTML(text string) string {
+	var b strings.Builder
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			fmt.Fprintf(&b, "<p>%s</p>\n", inlineHTML(strings.Join(para, "\n")))
+			para = nil
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if fence, lang, ok := openFence(trimmed); ok {
+			flush()
+			var code []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != fence; i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if lang != "" {
+				class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(lang))
+			}
+			fmt.Fprintf(&b, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(strings.Join(code, "\n")))
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		para = append(para, line)
+	}
+	flush()
+	return b.String()
+}
+
+// openFence reports whether line opens a fenced code block, returning the closing fence.
+func openFence(line string) (fence, lang string, ok bool) {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			n := len(line) - len(strings.TrimLeft(line, marker[:1]))
+			return line[:n], strings.TrimSpace(line[n:]), true
+		}
+	}
+	return "", "", false
+}
+
+// inlineHTML escapes text, keeps `code` spans and line breaks.
+func inlineHTML(text string) string {
+	parts := strings.Split(text, "`")
+	var b strings.Builder
+	for i, part := range parts {
+		escaped := html.EscapeString(part)
+		if i%2 == 1 && i < len(parts)-1 {
+			fmt.Fprintf(&b, "<code>%s</code>", escaped)
+			continue
+		}
+		if i%2 == 1 {
+			b.WriteString("`")
+		}
+		b.WriteString(strings.ReplaceAll(escaped, "\n", "<br>\n"))
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDocument() Document {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return Document{
+		Title:     "Sorting <things>",
+		Source:    "chat",
+		Provider:  "ollama",
+		Model:     "llama3",
+		Role:      "default",
+		CreatedAt: created,
+		UpdatedAt: created.Add(time.Minute),
+		Usage:     &Usage{PromptTokens: 12, CompletionTokens: 34, Estimated: true},
+		Metadata:  map[string]string{"Session": "abc"},
+		Messages: []Message{
+			{Role: "user", Content: "How do I sort in Go?"},
+			{Role: "assistant", Content: "Use `sort.Slice`:\n\n```go\nsort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })\n```"},
+		},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	data, err := Render(testDocument(), FormatMarkdown)
+	require.NoError(t, err)
+	out := string(data)
+	require.True(t, strings.HasPrefix(out, "# Sorting <things>\n"))
+	require.Contains(t, out, "- **Model:** llama3 (ollama)")
+	require.Contains(t, out, "- **Tokens:** ~12 prompt, ~34 completion")
+	require.Contains(t, out, "- **Session:** abc")
+	require.Contains(t, out, "## You\n\nHow do I sort in Go?")
+	require.Contains(t, out, "```go\nsort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })\n```")
+}
+
+func TestRenderHTML(t *testing.T) {
+	data, err := Render(testDocument(), FormatHTML)
+	require.NoError(t, err)
+	out := string(data)
+	require.Contains(t, out, "<title>Sorting &lt;things&gt;</title>")
+	require.Contains(t, out, `<pre><code class="language-go">sort.Slice(xs, func(i, j int) bool { return xs[i] &lt; xs[j] })</code></pre>`)
+	require.Contains(t, out, "<code>sort.Slice</code>")
+	require.NotContains(t, out, "<things>")
+}
+
+func TestRenderJSONRoundTrip(t *testing.T) {
+	doc := testDocument()
+	data, err := Render(doc, FormatJSON)
+	require.NoError(t, err)
+	var got Document
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, doc, got)
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"md": FormatMarkdown, "Markdown": FormatMarkdown, ".json": FormatJSON, "htm": FormatHTML} {
+		got, err := ParseFormat(name)
+		require.NoError(t, err, name)
+		require.Equal(t, want, got, name)
+	}
+	_, err := ParseFormat("pdf")
+	require.Error(t, err)
+
+	require.Equal(t, FormatHTML, FormatForPath("out/chat.HTML"))
+	require.Equal(t, FormatMarkdown, FormatForPath("notes.txt"))
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "answer.json")
+	require.NoError(t, WriteFile(path, testDocument(), ""))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, json.Valid(data))
+}
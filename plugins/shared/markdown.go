@@ -0,0 +1,124 @@
+package shared
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/termenv"
+)
+
+// defaultAnswerWidth is used when the terminal width cannot be read.
+const defaultAnswerWidth = 80
+
+type markdownKey struct {
+	width   int
+	profile termenv.Profile
+	dark    bool
+}
+
+var (
+	markdownMu        sync.Mutex
+	markdownRenderers = map[markdownKey]*glamour.TermRenderer{}
+)
+
+// markdownStyle returns the glamour style for the color profile, without the document
+// margin: answers are already framed by a box.
+func markdownStyle(profile termenv.Profile, dark bool) ansi.StyleConfig {
+	style := styles.LightStyleConfig
+	switch {
+	case profile == termenv.Ascii:
+		style = styles.ASCIIStyleConfig
+	case dark:
+		style = styles.DarkStyleConfig
+	}
+	zero := uint(0)
+	style.Document.Margin = &zero
+	style.Document.BlockPrefix = ""
+	style.Document.BlockSuffix = ""
+	return style
+}
+
+func markdownRenderer(width int) (*glamour.TermRenderer, error) {
+	key := markdownKey{width: width, profile: lipgloss.ColorProfile()}
+	if key.profile != termenv.Ascii {
+		key.dark = lipgloss.HasDarkBackground()
+	}
+	markdownMu.Lock()
+	defer markdownMu.Unlock()
+	if r, ok := markdownRenderers[key]; ok {
+		return r, nil
+	}
+	r, err := glamour.NewTermRenderer(
+		glamour.WithStyles(markdownStyle(key.profile, key.dark)),
+		glamour.WithColorProfile(key.profile),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return nil, err
+	}
+	markdownRenderers[key] = r
+	return r, nil
+}
+
+// RenderMarkdown renders Markdown for the terminal: headings, lists, tables, links and
+// code blocks highlighted by fence language, wrapped to width columns. Colors follow the
+// terminal color profile and background. On failure the text is returned unchanged.
+func RenderMarkdown(text string, width int) string {
+	if strings.TrimSpace(text) == "" || width <= 0 {
+		return text
+	}
+	r, err := markdownRenderer(width)
+	if err != nil {
+		return text
+	}
+	out, err := r.Render(text)
+	if err != nil {
+		return text
+	}
+	return strings.Trim(out, "\n")
+}
+
+// RenderMarkdownBox renders body as Markdown in a titled box exactly width columns wide.
+func RenderMarkdownBox(title, body string, width int) string {
+	if width < 2 {
+		return ""
+	}
+	return renderFixedWidthBox(title, RenderMarkdown(strings.TrimRight(body, "\n"), width-2), width)
+}
+
+// MarkdownEnabled reports whether answers written to w are rendered as Markdown: w must be
+// a terminal and raw output (the --raw flag) must be off.
+func MarkdownEnabled(w io.Writer, raw bool) bool {
+	return !raw && detectTTY(w)
+}
+
+// answerWidth returns the width of the box an answer is printed in on w.
+func answerWidth(w io.Writer) int {
+	if width, ok := detectTerminalWidth(w); ok {
+		return width
+	}
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.GetSize(f.Fd()); err == nil && width > 0 {
+			return width
+		}
+	}
+	return defaultAnswerWidth
+}
+
+// PrintAnswer writes a model answer in a titled box, rendered as Markdown when
+// MarkdownEnabled(w, raw) and as plain text otherwise.
+func PrintAnswer(w io.Writer, title, body string, raw bool) error {
+	if !MarkdownEnabled(w, raw) {
+		return PrintBox(w, title, body)
+	}
+	_, err := fmt.Fprintln(w, RenderMarkdownBox(title, body, answerWidth(w)))
+	return err
+}
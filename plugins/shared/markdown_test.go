@@ -0,0 +1,66 @@
+package shared
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleMarkdown = "# Sorting\n\nUse `sort.Slice`:\n\n| name | kind |\n|---|---|\n| xs | slice |\n\n```go\nsort.Slice(xs, less)\n```\n"
+
+func TestRenderMarkdown(t *testing.T) {
+	out := RenderMarkdown(sampleMarkdown, 40)
+	require.NotContains(t, out, "```")
+	require.NotContains(t, out, "|---|")
+	require.Contains(t, out, "sort.Slice(xs, less)")
+	require.Contains(t, out, "slice")
+	for _, line := range strings.Split(out, "\n") {
+		require.LessOrEqual(t, lipgloss.Width(line), 40, line)
+	}
+	require.Equal(t, "", RenderMarkdown("", 40))
+}
+
+func TestRenderMarkdownBoxWidth(t *testing.T) {
+	box := RenderMarkdownBox("Answer", sampleMarkdown, 30)
+	lines := strings.Split(box, "\n")
+	require.Contains(t, lines[1], "Answer")
+	for _, line := range lines {
+		require.Equal(t, 30, lipgloss.Width(line), line)
+	}
+}
+
+func TestPrintAnswer(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, PrintAnswer(&buf, "Answer", sampleMarkdown, false))
+	require.Equal(t, RenderBox("Answer", sampleMarkdown)+"\n", buf.String(), "non-TTY output stays raw")
+
+	prevDetectTTY := detectTTY
+	detectTTY = func(_ io.Writer) bool { return true }
+	t.Cleanup(func() { detectTTY = prevDetectTTY })
+	t.Setenv("COLUMNS", "50")
+
+	require.False(t, MarkdownEnabled(&buf, true))
+	buf.Reset()
+	require.NoError(t, PrintAnswer(&buf, "Answer", sampleMarkdown, true))
+	require.Contains(t, buf.String(), "```go", "--raw keeps the Markdown source")
+
+	buf.Reset()
+	require.NoError(t, PrintAnswer(&buf, "Answer", sampleMarkdown, false))
+	require.NotContains(t, buf.String(), "```")
+	require.Equal(t, 50, lipgloss.Width(strings.Split(buf.String(), "\n")[0]))
+}
+
+func TestStreamAnswerModel_Markdown(t *testing.T) {
+	m := newStreamAnswerModel("Answer", 40)
+	m.markdown = true
+	m.Update(streamChunkMsg(sampleMarkdown))
+	require.Contains(t, m.View(), "```", "streaming frames stay plain")
+	m.Update(streamResultMsg{final: sampleMarkdown})
+	view := m.View()
+	require.NotContains(t, view, "```")
+	require.Contains(t, view, "sort.Slice(xs, less)")
+}
@@ -2,11 +2,14 @@ package shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -15,20 +18,50 @@ import (
 
 type streamChunkMsg string
 
+type streamThinkingMsg string
+
 type streamResultMsg struct {
-	final string
-	err   error
+	final     string
+	err       error
+	truncated bool
+}
+
+// ErrStreamInterrupted is returned with the partial answer when the user pressed Ctrl-C
+// during streaming. Callers should keep the partial text but never cache it.
+var ErrStreamInterrupted = errors.New("stream interrupted")
+
+// TruncatedMarker is shown under an answer that was cut short by Ctrl-C.
+const TruncatedMarker = "[truncated]"
+
+// StreamOptions controls optional parts of the streamed answer display.
+type StreamOptions struct {
+	// ShowThinking renders model reasoning in a dimmed pane above the answer.
+	ShowThinking bool
+	// Markdown renders the answer panel as Markdown on a terminal; other output stays raw.
+	Markdown bool
+	// ThinkingOut receives the reasoning when the answer is not written to a terminal, so
+	// piped output holds only the answer. It defaults to os.Stderr.
+	ThinkingOut io.Writer
 }
 
 // streamAnswerModel renders a titled panel that fills as stream chunks arrive.
 type streamAnswerModel struct {
-	title string
-	width int
+	title        string
+	width        int
+	showThinking bool
+	markdown     bool
 
+	thinking      strings.Builder
 	buf           strings.Builder
 	streamFinal   string
 	streamErr     error
 	gotStreamDone bool
+	truncated     bool
+
+	// rendered caches the Markdown of the final frame, keyed by body and width.
+	renderedBody  string
+	renderedWidth int
+	rendered      string
 }
 
 func newStreamAnswerModel(title string, width int) *streamAnswerModel {
@@ -57,8 +90,16 @@ func (m *streamAnswerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case streamChunkMsg:
 		m.buf.WriteString(string(msg))
 		return m, nil
+	case streamThinkingMsg:
+		m.thinking.WriteString(string(msg))
+		return m, nil
 	case streamResultMsg:
 		m.gotStreamDone = true
+		if msg.truncated {
+			// Keep what was streamed; the cancellation error is expected.
+			m.truncated = true
+			return m, tea.Quit
+		}
 		m.streamFinal = msg.final
 		m.streamErr = msg.err
 		// Finalize model content before quitting so the last frame is complete.
@@ -85,7 +126,14 @@ func (m *streamAnswerModel) View() string {
 	}
 
 	body := strings.TrimRight(m.buf.String(), "\n")
-	wrapped := lipgloss.NewStyle().Width(m.innerWidth()).Render(body)
+	var wrapped string
+	if m.markdown && m.gotStreamDone {
+		wrapped = m.markdownBody(body)
+	} else {
+		// Rendering Markdown on every chunk costs quadratic time over the answer, so the
+		// text streams plain and is rendered once on the final frame.
+		wrapped = lipgloss.NewStyle().Width(m.innerWidth()).Render(body)
+	}
 	if wrapped == "" && m.streamErr == nil && !m.gotStreamDone {
 		wrapped = "Waiting for response..."
 	}
@@ -95,7 +143,16 @@ func (m *streamAnswerModel) View() string {
 		}
 		wrapped += m.streamErr.Error()
 	}
+	if m.truncated {
+		if wrapped != "" {
+			wrapped += "\n"
+		}
+		wrapped += TruncatedMarker
+	}
 	rendered := renderFixedWidthBox(m.title, wrapped, m.width)
+	if thinking := strings.TrimSpace(m.thinking.String()); m.showThinking && thinking != "" {
+		rendered = RenderThinkingBox(thinking, m.width) + "\n" + rendered
+	}
 	if m.gotStreamDone {
 		// Keep cursor on a line below the border so Bubble Tea exit cleanup
 		// clears that line, not the bottom border itself.
@@ -104,6 +161,34 @@ func (m *streamAnswerModel) View() string {
 	return rendered
 }
 
+// renderStreamMarkdown renders the final answer panel; tests replace it.
+var renderStreamMarkdown = RenderMarkdown
+
+func (m *streamAnswerModel) markdownBody(body string) string {
+	if m.rendered == "" || m.renderedBody != body || m.renderedWidth != m.innerWidth() {
+		m.renderedBody, m.renderedWidth = body, m.innerWidth()
+		m.rendered = renderStreamMarkdown(body, m.innerWidth())
+	}
+	return m.rendered
+}
+
+var thinkingStyle = lipgloss.NewStyle().Faint(true)
+
+// RenderWrappedBox renders body in a titled box exactly width columns wide, wrapping long lines.
+// It is the frame used by the streamed answer panel.
+func RenderWrappedBox(title, body string, width int) string {
+	if width < 2 {
+		return ""
+	}
+	wrapped := lipgloss.NewStyle().Width(width - 2).Render(strings.TrimRight(body, "\n"))
+	return renderFixedWidthBox(title, wrapped, width)
+}
+
+// RenderThinkingBox renders model reasoning as the dimmed Thinking pane of the answer panel.
+func RenderThinkingBox(thinking string, width int) string {
+	return thinkingStyle.Render(RenderWrappedBox("Thinking", strings.TrimSpace(thinking), width))
+}
+
 // writerIsTTY reports whether w is an *os.File open on a terminal.
 func writerIsTTY(w io.Writer) bool {
 	f, ok := w.(*os.File)
@@ -172,23 +257,94 @@ func renderFixedWidthBox(title, body string, width int) string {
 	return strings.Join(framed, "\n")
 }
 
-// DisplayStreamedAnswer runs runStream, which must call send with each non-empty chunk of output.
-// It returns the final answer string and any error from runStream.
+// interruptSignals delivers Ctrl-C to DisplayStreamedAnswer; tests replace it.
+var interruptSignals = func(c chan<- os.Signal) (stop func()) {
+	signal.Notify(c, os.Interrupt)
+	return func() { signal.Stop(c) }
+}
+
+// exitProcess is called on the second Ctrl-C; tests replace it.
+var exitProcess = os.Exit
+
+// watchInterrupts cancels the stream on the first Ctrl-C and exits with status 130 on
+// the second. The returned flag reports whether the stream was interrupted.
+func watchInterrupts(cancel context.CancelFunc) (*atomic.Bool, func()) {
+	interrupted := &atomic.Bool{}
+	sigs := make(chan os.Signal, 2)
+	stopNotify := interruptSignals(sigs)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigs:
+				if interrupted.Swap(true) {
+					exitProcess(130)
+					return
+				}
+				cancel()
+			}
+		}
+	}()
+	return interrupted, func() {
+		stopNotify()
+		close(done)
+	}
+}
+
+// DisplayStreamedAnswer runs runStream, which must call send with each non-empty chunk of output
+// and pass ctx to the provider request. It returns the final answer string and any error from runStream.
+//
+// The first Ctrl-C cancels ctx: the text streamed so far is shown marked as truncated and returned
+// with ErrStreamInterrupted. A second Ctrl-C exits the process.
 //
 // On a TTY, Bubble Tea is the single renderer and writes in normal terminal flow (no alt-screen).
 // After exit we print exactly one newline so the shell prompt does not overwrite the bottom border.
 // On a non-TTY, send is implemented as PrintRaw.
-func DisplayStreamedAnswer(ctx context.Context, w io.Writer, title string, runStream func(send func(string)) (final string, err error)) (string, error) {
+func DisplayStreamedAnswer(ctx context.Context, w io.Writer, title string, runStream func(ctx context.Context, send func(string)) (final string, err error)) (string, error) {
+	return DisplayStreamedAnswerWithOptions(ctx, w, title, StreamOptions{}, func(ctx context.Context, send, _ func(string)) (string, error) {
+		return runStream(ctx, send)
+	})
+}
+
+// DisplayStreamedAnswerWithOptions is DisplayStreamedAnswer with a second callback, think, for
+// reasoning chunks. Reasoning is only displayed with opts.ShowThinking and is never returned.
+func DisplayStreamedAnswerWithOptions(ctx context.Context, w io.Writer, title string, opts StreamOptions, runStream func(ctx context.Context, send, think func(string)) (final string, err error)) (string, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interrupted, stopWatching := watchInterrupts(cancel)
+	defer stopWatching()
+
 	if !detectTTY(w) {
 		var streamed strings.Builder
+		thinkingOut := opts.ThinkingOut
+		if thinkingOut == nil {
+			thinkingOut = os.Stderr
+		}
+		thought := false
 		send := func(s string) {
 			if s == "" {
 				return
 			}
+			if thought && streamed.Len() == 0 {
+				_ = PrintRaw(thinkingOut, "\n")
+			}
 			_ = PrintRaw(w, s)
 			streamed.WriteString(s)
 		}
-		final, err := runStream(send)
+		think := func(s string) {
+			if s == "" || !opts.ShowThinking || streamed.Len() > 0 {
+				return
+			}
+			_ = PrintRaw(thinkingOut, s)
+			thought = true
+		}
+		final, err := runStream(streamCtx, send, think)
+		if interrupted.Load() {
+			_ = PrintRaw(w, "\n"+TruncatedMarker+"\n")
+			return strings.TrimRight(streamed.String(), "\n"), ErrStreamInterrupted
+		}
 		if err != nil {
 			return "", err
 		}
@@ -203,21 +359,29 @@ func DisplayStreamedAnswer(ctx context.Context, w io.Writer, title string, runSt
 
 	initialWidth, _ := detectTerminalWidth(w)
 	model := newStreamAnswerModel(title, initialWidth)
+	model.showThinking = opts.ShowThinking
+	model.markdown = opts.Markdown
 	p := tea.NewProgram(
 		model,
 		tea.WithContext(ctx),
 		tea.WithOutput(w),
 		tea.WithInput(nil),
+		tea.WithoutSignalHandler(),
 	)
 
 	go func() {
-		final, err := runStream(func(s string) {
+		final, err := runStream(streamCtx, func(s string) {
 			if s == "" {
 				return
 			}
 			p.Send(streamChunkMsg(s))
+		}, func(s string) {
+			if s == "" || !opts.ShowThinking {
+				return
+			}
+			p.Send(streamThinkingMsg(s))
 		})
-		p.Send(streamResultMsg{final: final, err: err})
+		p.Send(streamResultMsg{final: final, err: err, truncated: interrupted.Load()})
 	}()
 
 	tm, runErr := p.Run()
@@ -225,6 +389,10 @@ func DisplayStreamedAnswer(ctx context.Context, w io.Writer, title string, runSt
 	if !ok {
 		return "", fmt.Errorf("stream answer: unexpected model type %T", tm)
 	}
+	if mOut.truncated {
+		_ = PrintRaw(w, "\n")
+		return strings.TrimRight(mOut.buf.String(), "\n"), ErrStreamInterrupted
+	}
 	if runErr != nil {
 		if mOut.streamErr != nil {
 			return "", mOut.streamErr
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -72,7 +75,7 @@ func TestDisplayStreamedAnswer_TTYStreamKeepsFinalFrameAndNewline(t *testing.T)
 	t.Cleanup(func() { detectTerminalWidth = prevDetectWidth })
 
 	var out bytes.Buffer
-	final, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(send func(string)) (string, error) {
+	final, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(_ context.Context, send func(string)) (string, error) {
 		send("hello")
 		return "hello", nil
 	})
@@ -120,7 +123,7 @@ func TestDisplayStreamedAnswer_NoDuplication(t *testing.T) {
 	t.Cleanup(func() { detectTerminalWidth = prevDetectWidth })
 
 	var out bytes.Buffer
-	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(send func(string)) (string, error) {
+	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(_ context.Context, send func(string)) (string, error) {
 		send("hello world")
 		return "hello world", nil
 	})
@@ -140,7 +143,7 @@ func TestDisplayStreamedAnswer_TTYSimulationUsesProvidedWidth(t *testing.T) {
 	t.Cleanup(func() { detectTerminalWidth = prevDetectWidth })
 
 	var out bytes.Buffer
-	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(send func(string)) (string, error) {
+	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(_ context.Context, send func(string)) (string, error) {
 		send("short")
 		return "short", nil
 	})
@@ -161,7 +164,7 @@ func TestDisplayStreamedAnswer_FinalLineIntegrity(t *testing.T) {
 	t.Cleanup(func() { detectTerminalWidth = prevDetectWidth })
 
 	var out bytes.Buffer
-	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(send func(string)) (string, error) {
+	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(_ context.Context, send func(string)) (string, error) {
 		send("hello")
 		return "hello", nil
 	})
@@ -181,3 +184,138 @@ func stripANSIAndCR(s string) string {
 	s = strings.ReplaceAll(s, "\r", "")
 	return s
 }
+
+// fakeInterrupts replaces the process signal and exit hooks. The returned func delivers
+// one Ctrl-C; the exit code stays 0 until exitProcess is called.
+func fakeInterrupts(t *testing.T) (func(), *atomic.Int32) {
+	t.Helper()
+	var sigs chan<- os.Signal
+	prevSignals := interruptSignals
+	interruptSignals = func(c chan<- os.Signal) func() {
+		sigs = c
+		return func() {}
+	}
+	exitCode := &atomic.Int32{}
+	prevExit := exitProcess
+	exitProcess = func(code int) { exitCode.Store(int32(code)) }
+	t.Cleanup(func() {
+		interruptSignals = prevSignals
+		exitProcess = prevExit
+	})
+	return func() { sigs <- os.Interrupt }, exitCode
+}
+
+func TestStreamAnswerModel_TruncatedKeepsPartial(t *testing.T) {
+	m := newStreamAnswerModel("Answer", 60)
+	next, _ := m.Update(streamChunkMsg("partial answer"))
+	m = next.(*streamAnswerModel)
+	next, cmd := m.Update(streamResultMsg{err: context.Canceled, truncated: true})
+	require.NotNil(t, cmd)
+	m = next.(*streamAnswerModel)
+	require.NoError(t, m.streamErr)
+	view := m.View()
+	require.Contains(t, view, "partial answer")
+	require.Contains(t, view, TruncatedMarker)
+	require.NotContains(t, view, "context canceled")
+}
+
+func TestDisplayStreamedAnswer_InterruptReturnsPartial(t *testing.T) {
+	prevDetectTTY := detectTTY
+	detectTTY = func(_ io.Writer) bool { return false }
+	t.Cleanup(func() { detectTTY = prevDetectTTY })
+	interrupt, exitCode := fakeInterrupts(t)
+
+	var out bytes.Buffer
+	final, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(ctx context.Context, send func(string)) (string, error) {
+		send("first part")
+		interrupt()
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.ErrorIs(t, err, ErrStreamInterrupted)
+	require.Equal(t, "first part", final)
+	require.Contains(t, out.String(), TruncatedMarker)
+	require.Zero(t, exitCode.Load())
+}
+
+func TestDisplayStreamedAnswer_SecondInterruptExits(t *testing.T) {
+	prevDetectTTY := detectTTY
+	detectTTY = func(_ io.Writer) bool { return false }
+	t.Cleanup(func() { detectTTY = prevDetectTTY })
+	interrupt, exitCode := fakeInterrupts(t)
+
+	var out bytes.Buffer
+	_, err := DisplayStreamedAnswer(context.Background(), &out, "Answer", func(ctx context.Context, send func(string)) (string, error) {
+		interrupt()
+		<-ctx.Done()
+		interrupt()
+		require.Eventually(t, func() bool { return exitCode.Load() == 130 }, time.Second, 5*time.Millisecond)
+		return "", ctx.Err()
+	})
+	require.ErrorIs(t, err, ErrStreamInterrupted)
+}
+
+func TestStreamAnswerModel_ThinkingPane(t *testing.T) {
+	m := newStreamAnswerModel("Answer", 60)
+	next, _ := m.Update(streamThinkingMsg("considering"))
+	m = next.(*streamAnswerModel)
+	next, _ = m.Update(streamChunkMsg("done"))
+	m = next.(*streamAnswerModel)
+	require.NotContains(t, m.View(), "considering")
+
+	m.showThinking = true
+	view := m.View()
+	require.Contains(t, view, "Thinking")
+	require.Contains(t, view, "considering")
+	require.Less(t, strings.Index(view, "considering"), strings.Index(view, "done"))
+}
+
+func TestDisplayStreamedAnswerWithOptions_ThinkingNotReturned(t *testing.T) {
+	prevDetectTTY := detectTTY
+	detectTTY = func(_ io.Writer) bool { return false }
+	t.Cleanup(func() { detectTTY = prevDetectTTY })
+
+	run := func(ctx context.Context, send, think func(string)) (string, error) {
+		think("hmm")
+		send("answer")
+		return "answer", nil
+	}
+	var hidden, shown bytes.Buffer
+	final, err := DisplayStreamedAnswerWithOptions(context.Background(), &hidden, "Answer", StreamOptions{}, run)
+	require.NoError(t, err)
+	require.Equal(t, "answer", final)
+	require.Equal(t, "answer\n", hidden.String())
+
+	// Piped output holds only the answer; the reasoning goes to ThinkingOut.
+	var thinking bytes.Buffer
+	final, err = DisplayStreamedAnswerWithOptions(context.Background(), &shown, "Answer", StreamOptions{ShowThinking: true, ThinkingOut: &thinking}, run)
+	require.NoError(t, err)
+	require.Equal(t, "answer", final)
+	require.Equal(t, "answer\n", shown.String())
+	require.Equal(t, "hmm\n", thinking.String())
+}
+
+func TestStreamAnswerModel_MarkdownRenderedOnceAtTheEnd(t *testing.T) {
+	calls := 0
+	prev := renderStreamMarkdown
+	renderStreamMarkdown = func(text string, width int) string {
+		calls++
+		return "rendered: " + text
+	}
+	t.Cleanup(func() { renderStreamMarkdown = prev })
+
+	m := newStreamAnswerModel("Answer", 60)
+	m.markdown = true
+	for _, chunk := range []string{"# Title", "\n\n", "**bold**"} {
+		next, _ := m.Update(streamChunkMsg(chunk))
+		m = next.(*streamAnswerModel)
+		require.NotContains(t, m.View(), "rendered:")
+	}
+	require.Zero(t, calls)
+
+	next, _ := m.Update(streamResultMsg{final: "# Title\n\n**bold**"})
+	m = next.(*streamAnswerModel)
+	require.Contains(t, m.View(), "rendered: # Title")
+	_ = m.View()
+	require.Equal(t, 1, calls)
+}
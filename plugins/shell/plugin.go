@@ -0,0 +1,249 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gaia/kernel"
+	"gaia/plugins/ask"
+	"gaia/plugins/roles"
+	"gaia/plugins/shared"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultPrompt is used when the configured role is not installed. Its placeholders are the
+// operating system and the shell, as in the roles.
+const defaultPrompt = "Provide only %[2]s commands for %[1]s without any description. If there is a lack of details, " +
+	"provide the most logical solution. Ensure the output is a valid shell command. If multiple steps are required, " +
+	"try to combine them using &&. Provide only plain text without Markdown formatting."
+
+// ShellPlugin suggests shell commands from the command line being typed, through a widget
+// bound to a hotkey.
+type ShellPlugin struct {
+	providers map[string]ask.Provider
+}
+
+func NewShellPlugin() *ShellPlugin {
+	p := &ShellPlugin{
+		providers: map[string]ask.Provider{},
+	}
+	p.RegisterProvider(ask.NewOllamaProvider())
+	p.RegisterProvider(ask.NewOpenAIProvider())
+	p.RegisterProvider(ask.NewMistralProvider())
+	return p
+}
+
+func (p *ShellPlugin) ID() string           { return "shell" }
+func (p *ShellPlugin) DefaultEnabled() bool { return true }
+func (p *ShellPlugin) DependsOn() []string  { return nil }
+func (p *ShellPlugin) ConfigSchema() []string {
+	return []string{
+		"shell.provider",
+		"shell.model",
+		"shell.role",
+		"shell.key",
+		"shell.confirm",
+		"shell.timeout_seconds",
+	}
+}
+
+func (p *ShellPlugin) MCPTools() []kernel.MCPTool { return nil }
+
+func (p *ShellPlugin) RegisterProvider(provider ask.Provider) {
+	if provider == nil {
+		return
+	}
+	p.providers[provider.Name()] = provider
+}
+
+func (p *ShellPlugin) Register(_ *kernel.Kernel) ([]*cobra.Command, error) {
+	initCmd := &cobra.Command{
+		Use:       "shell-init <bash|zsh|fish>",
+		Short:     "Print a shell widget that turns the command line into a suggested command",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: Shells,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, _ := cmd.Flags().GetString("key")
+			key = ask.FirstNonEmpty(key, viper.GetString("shell.key"))
+			bin, err := os.Executable()
+			if err != nil {
+				bin = "gaia"
+			}
+			script, err := Widget(args[0], key, bin)
+			if err != nil {
+				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+			}
+			return shared.PrintRaw(cmd.OutOrStdout(), script)
+		},
+	}
+	initCmd.Flags().String("key", "", "Letter of the Ctrl+<letter> hotkey (default shell.key)")
+
+	suggestCmd := &cobra.Command{
+		Use:   "suggest [text]",
+		Short: "Suggest a shell command for a description or a command line",
+		Long: "Suggest a shell command for a description or a command line. The command is printed " +
+			"after approval and never run, so the shell-init widgets can place it on the command line.",
+		RunE: p.suggest,
+	}
+	suggestCmd.Flags().String("provider", "", "Provider name (overrides shell.provider)")
+	suggestCmd.Flags().String("model", "", "Model name (overrides shell.model)")
+	suggestCmd.Flags().BoolP("yes", "y", false, "Print the suggestion without asking for approval")
+
+	return []*cobra.Command{initCmd, suggestCmd}, nil
+}
+
+func (p *ShellPlugin) suggest(cmd *cobra.Command, args []string) error {
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" && shared.HasPipedStdin() {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+		}
+		text = strings.TrimSpace(string(data))
+	}
+	if text == "" {
+		return shared.PrintError(cmd.ErrOrStderr(), "Nothing to suggest: type a description or a command first")
+	}
+
+	providerFlag, _ := cmd.Flags().GetString("provider")
+	modelFlag, _ := cmd.Flags().GetString("model")
+	req := ask.AskRequest{
+		Provider: ask.FirstNonEmpty(providerFlag, ask.FirstNonEmpty(viper.GetString("shell.provider"), viper.GetString("provider"))),
+		Host:     viper.GetString("host"),
+		Port:     viper.GetInt("port"),
+		Model:    ask.FirstNonEmpty(modelFlag, ask.FirstNonEmpty(viper.GetString("shell.model"), viper.GetString("model"))),
+		Timeout:  time.Duration(ask.FirstNonZero(viper.GetInt("shell.timeout_seconds"), viper.GetInt("timeout_seconds"))) * time.Second,
+		Message:  text,
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 60 * time.Second
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = ask.ResolveProviderFromModel(req.Model)
+	}
+	if err := validateShellConfig(req); err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	prompt, err := systemPrompt(req)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	req.SystemPrompt = prompt
+	provider, err := ask.SelectProvider(p.providers, req.Provider)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), req.Timeout)
+	defer cancel()
+	resp, err := provider.Send(ctx, req)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Suggestion failed: %v", err))
+	}
+	suggestion := CleanCommand(resp.Text)
+	if suggestion == "" {
+		return shared.PrintError(cmd.ErrOrStderr(), "The model did not suggest a command")
+	}
+
+	confirm := viper.GetBool("shell.confirm")
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		confirm = false
+	}
+	if confirm {
+		ok, err := approve(suggestion)
+		if err != nil {
+			return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return shared.PrintRaw(cmd.OutOrStdout(), suggestion+"\n")
+}
+
+// approve asks on the terminal whether to use the suggestion. stdout is usually captured by
+// the widget, so the prompt is drawn on /dev/tty.
+func approve(suggestion string) (bool, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, errors.New("no terminal available to approve the suggestion (pass --yes to skip approval)")
+	}
+	defer tty.Close()
+	return shared.RunConfirmationPromptTUI(suggestion, "Suggested command", tty, tty)
+}
+
+// systemPrompt returns the prompt of the shell.role role, or the built-in one when the role
+// is not installed.
+func systemPrompt(req ask.AskRequest) (string, error) {
+	name := ask.FirstNonEmpty(strings.TrimSpace(viper.GetString("shell.role")), "shell")
+	list, err := roles.LoadRolesWithDefaults()
+	if err != nil {
+		return "", err
+	}
+	resolved, err := roles.ResolveInheritance(list)
+	if err != nil {
+		return "", err
+	}
+	if role, ok := resolved[name]; ok {
+		return roles.ResolveSystemPrompt(role, req.Provider, req.Model), nil
+	}
+	if name != "shell" {
+		return "", fmt.Errorf("role %q not found", name)
+	}
+	return roles.FillPlaceholders(defaultPrompt, roles.DetectOS(), roles.DetectShell()), nil
+}
+
+// CleanCommand reduces an answer to the command it suggests: reasoning and Markdown are
+// removed, the first code block is preferred, and prompt markers ("$ ") are dropped.
+func CleanCommand(answer string) string {
+	answer, _ = ask.SplitThinking(answer)
+	if blocks := shared.ExtractCodeBlocks(answer); len(blocks) > 0 {
+		answer = blocks[0].Code
+	}
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "`") && strings.HasSuffix(answer, "`") && !strings.Contains(strings.Trim(answer, "`"), "`") {
+		answer = strings.TrimSpace(strings.Trim(answer, "`"))
+	}
+	lines := strings.Split(answer, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.HasPrefix(line, "$ ") {
+			line = strings.TrimPrefix(line, "$ ")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func validateShellConfig(req ask.AskRequest) error {
+	missing := []string{}
+	if strings.TrimSpace(req.Provider) == "" {
+		missing = append(missing, "shell.provider")
+	}
+	if ask.NeedsEndpoint(req.Provider) {
+		if strings.TrimSpace(req.Host) == "" {
+			missing = append(missing, "host")
+		}
+		if req.Port == 0 {
+			missing = append(missing, "port")
+		}
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		missing = append(missing, "model")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing shell configuration: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanCommand(t *testing.T) {
+	cases := []struct {
+		answer string
+		want   string
+	}{
+		{"ls -la", "ls -la"},
+		{"`ls -la`", "ls -la"},
+		{"Run this:\n```bash\n$ find . -name '*.go'\n```\nThen check.", "find . -name '*.go'"},
+		{"<think>list files</think>\n\nls -1", "ls -1"},
+		{"$ cd /tmp && ls\n", "cd /tmp && ls"},
+		{"  \n", ""},
+	}
+	for _, c := range cases {
+		if got := CleanCommand(c.answer); got != c.want {
+			t.Errorf("CleanCommand(%q) = %q, want %q", c.answer, got, c.want)
+		}
+	}
+}
+
+func TestWidget(t *testing.T) {
+	cases := map[string][]string{
+		"bash": {`bind -x '"\C-g": __gaia_suggest'`, "READLINE_LINE=$suggestion", "GAIA_SHELL=bash /usr/bin/gaia suggest"},
+		"zsh":  {"bindkey '^G' __gaia_suggest", "zle -N __gaia_suggest", "BUFFER=$suggestion"},
+		"fish": {`bind \cg __gaia_suggest`, "commandline -r -- $suggestion", "GAIA_SHELL=fish"},
+	}
+	for shell, wants := range cases {
+		script, err := Widget(shell, "g", "/usr/bin/gaia")
+		if err != nil {
+			t.Fatalf("Widget(%s) error: %v", shell, err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(script, want) {
+				t.Errorf("Widget(%s) missing %q:\n%s", shell, want, script)
+			}
+		}
+	}
+}
+
+func TestWidgetKeyAndErrors(t *testing.T) {
+	script, err := Widget("zsh", "X", "/usr/bin/gaia")
+	if err != nil || !strings.Contains(script, "bindkey '^X'") {
+		t.Errorf("Widget key X = %q, %v", script, err)
+	}
+	if _, err := Widget("tcsh", "g", "gaia"); err == nil {
+		t.Error("expected an error for an unsupported shell")
+	}
+	if _, err := Widget("bash", "ctrl+g", "gaia"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("/usr/bin/gaia"); got != "/usr/bin/gaia" {
+		t.Errorf("shellQuote plain = %q", got)
+	}
+	if got := shellQuote("/Users/me/my apps/gaia"); got != "'/Users/me/my apps/gaia'" {
+		t.Errorf("shellQuote space = %q", got)
+	}
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("shellQuote quote = %q", got)
+	}
+}
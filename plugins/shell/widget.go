@@ -0,0 +1,79 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shells lists the shells shell-init supports.
+var Shells = []string{"bash", "zsh", "fish"}
+
+const bashWidget = `# gaia shell widget: Ctrl+%[1]s replaces the command line with a suggested command.
+# Add to ~/.bashrc: eval "$(gaia shell-init bash)"
+__gaia_suggest() {
+  local suggestion
+  suggestion=$(GAIA_SHELL=bash %[2]s suggest -- "$READLINE_LINE" </dev/tty) || return
+  if [ -n "$suggestion" ]; then
+    READLINE_LINE=$suggestion
+    READLINE_POINT=${#READLINE_LINE}
+  fi
+}
+bind -x '"\C-%[3]s": __gaia_suggest'
+`
+
+const zshWidget = `# gaia shell widget: Ctrl+%[1]s replaces the command line with a suggested command.
+# Add to ~/.zshrc: eval "$(gaia shell-init zsh)"
+__gaia_suggest() {
+  local suggestion
+  suggestion=$(GAIA_SHELL=zsh %[2]s suggest -- "$BUFFER" </dev/tty)
+  if [[ $? -eq 0 && -n $suggestion ]]; then
+    BUFFER=$suggestion
+    CURSOR=${#BUFFER}
+  fi
+  zle reset-prompt
+}
+zle -N __gaia_suggest
+bindkey '^%[1]s' __gaia_suggest
+`
+
+const fishWidget = `# gaia shell widget: Ctrl+%[1]s replaces the command line with a suggested command.
+# Add to ~/.config/fish/config.fish: gaia shell-init fish | source
+function __gaia_suggest
+    set -l suggestion (env GAIA_SHELL=fish %[2]s suggest -- (commandline | string collect) </dev/tty | string collect)
+    if test $status -eq 0; and test -n "$suggestion"
+        commandline -r -- $suggestion
+        commandline -f end-of-buffer
+    end
+    commandline -f repaint
+end
+bind \c%[3]s __gaia_suggest
+`
+
+// Widget returns the script binding Ctrl+key to gaia suggest in shell. bin is the gaia
+// executable the widget calls.
+func Widget(shell, key, bin string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if len(key) != 1 || key[0] < 'a' || key[0] > 'z' {
+		return "", fmt.Errorf("invalid key %q: use a single letter (Ctrl+<letter>)", key)
+	}
+	var script string
+	switch shell {
+	case "bash":
+		script = bashWidget
+	case "zsh":
+		script = zshWidget
+	case "fish":
+		script = fishWidget
+	default:
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+	return fmt.Sprintf(script, strings.ToUpper(key), shellQuote(bin), key), nil
+}
+
+// shellQuote quotes s for bash, zsh and fish.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/._-+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
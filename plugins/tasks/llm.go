@@ -1,14 +1,15 @@
 package tasks
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"gaia/plugins/ask"
 )
 
 const defaultModel = "qwen2.5:14b"
@@ -54,26 +55,30 @@ type SessionSummary struct {
 	Messages        []string `json:"messages"`
 }
 
-// LLMClient calls Ollama for task intelligence operations.
+// LLMClient calls Ollama for task intelligence operations. Requests go through
+// ask.SelectProvider, so ask.limits and record/replay apply to them.
 type LLMClient struct {
-	baseURL string
+	host    string
+	port    int
 	model   string
 	timeout time.Duration
-	http    *http.Client
 }
 
 // NewLLMClient creates an LLMClient pointing at the local Ollama instance.
 func NewLLMClient(host string, port int) *LLMClient {
-	return newLLMClientFromURL(fmt.Sprintf("http://%s:%d", host, port), 120*time.Second)
+	return &LLMClient{host: host, port: port, model: defaultModel, timeout: 120 * time.Second}
 }
 
 func newLLMClientFromURL(baseURL string, timeout time.Duration) *LLMClient {
-	return &LLMClient{
-		baseURL: strings.TrimRight(baseURL, "/"),
-		model:   defaultModel,
-		timeout: timeout,
-		http:    &http.Client{Timeout: timeout},
+	c := NewLLMClient("localhost", 11434)
+	c.timeout = timeout
+	if u, err := url.Parse(baseURL); err == nil {
+		c.host = u.Hostname()
+		if port, err := strconv.Atoi(u.Port()); err == nil {
+			c.port = port
+		}
 	}
+	return c
 }
 
 // InferTaskMeta asks the LLM to infer effort, impact, category, and Eisenhower for a new task.
@@ -196,44 +201,22 @@ Only include entries with confidence >= 0.5. Split session time proportionally i
 }
 
 func (c *LLMClient) chat(ctx context.Context, prompt string) (string, error) {
-	payload := map[string]interface{}{
-		"model":  c.model,
-		"stream": false,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-	}
-	body, err := json.Marshal(payload)
+	provider, err := ask.SelectProvider(map[string]ask.Provider{"ollama": ask.NewOllamaProvider()}, "ollama")
 	if err != nil {
 		return "", err
 	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.http.Do(req)
+	resp, err := provider.Send(ctx, ask.AskRequest{
+		Provider: "ollama",
+		Host:     c.host,
+		Port:     c.port,
+		Model:    c.model,
+		Timeout:  c.timeout,
+		Message:  prompt,
+	})
 	if err != nil {
 		return "", fmt.Errorf("ollama request: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return "", fmt.Errorf("ollama error: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(b)))
-	}
-
-	var decoded struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
-	}
-	return decoded.Message.Content, nil
+	return resp.Text, nil
 }
 
 // parseJSON extracts the first JSON object from text (handles LLM preamble/postamble).
@@ -16,6 +16,15 @@ func makeOllamaServer(t *testing.T, responseText string) *httptest.Server {
 	t.Helper()
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/tags" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"models": []map[string]string{{"name": defaultModel}}})
+			return
+		}
+		if r.URL.Path == "/api/show" {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/api/chat", r.URL.Path)
 		resp := map[string]interface{}{
 			"message": map[string]string{"role": "assistant", "content": responseText},
 			"done":    true,
@@ -44,7 +44,7 @@ func loadToolActionConfig(tool, action string) toolActionConfig {
 	}
 }
 
-func runToolAction(ctx context.Context, out io.Writer, errOut io.Writer, in io.Reader, tool, action string, args []string, providers map[string]ask.Provider, pull bool) error {
+func runToolAction(ctx context.Context, out io.Writer, errOut io.Writer, in io.Reader, tool, action string, args []string, providers map[string]ask.Provider, pull, printOnly bool) error {
 	cfg := loadToolActionConfig(tool, action)
 	if strings.TrimSpace(cfg.ContextCommand) == "" && strings.TrimSpace(cfg.ExecuteCommand) == "" {
 		return fmt.Errorf("tool %q action %q has no context_command or execute_command configured", tool, action)
@@ -78,13 +78,9 @@ func runToolAction(ctx context.Context, out io.Writer, errOut io.Writer, in io.R
 		req.Provider = ask.ResolveProviderFromModel(req.Model)
 	}
 
-	provider, ok := providers[req.Provider]
-	if !ok {
-		if fallback, hasFallback := providers["ollama"]; hasFallback {
-			provider = fallback
-		} else {
-			return fmt.Errorf("unknown provider %q", req.Provider)
-		}
+	provider, err := ask.SelectProvider(providers, req.Provider)
+	if err != nil {
+		return err
 	}
 
 	rolePrompt, err := resolveToolRolePrompt(cfg.Role, tool, action, contextOut, req)
@@ -104,6 +100,10 @@ func runToolAction(ctx context.Context, out io.Writer, errOut io.Writer, in io.R
 		return fmt.Errorf("tool action returned empty response")
 	}
 
+	if printOnly {
+		answer, _ := ask.SplitThinking(response)
+		return shared.PrintRaw(out, strings.TrimSpace(answer)+"\n")
+	}
 	if strings.TrimSpace(cfg.ExecuteCommand) == "" {
 		return shared.PrintBox(out, "Response", response)
 	}
@@ -317,28 +317,10 @@ func loadRoleKeywords() map[string][]string {
 }
 
 func applySanitize(req ask.AskRequest, errOut io.Writer) ask.AskRequest {
-	if !viper.GetBool("sanitize.enabled") {
+	opts, ok := sanitizeOptions()
+	if !ok {
 		return req
 	}
-	levelStr := strings.ToLower(strings.TrimSpace(viper.GetString("sanitize.level")))
-	var level sanitizepkg.Level
-	switch levelStr {
-	case "none":
-		level = sanitizepkg.LevelNone
-	case "aggressive":
-		level = sanitizepkg.LevelAggressive
-	case "light", "":
-		level = sanitizepkg.LevelLight
-	default:
-		level = sanitizepkg.LevelLight
-	}
-	opts := sanitizepkg.Options{
-		Level:             level,
-		MaxTokensAfter:    viper.GetInt("sanitize.max_tokens_after"),
-		LogStats:          viper.GetBool("sanitize.log_stats"),
-		PreserveLastUser:  true,
-		MaxDurationMillis: 100,
-	}
 	raw := buildMessagesForSanitize(req)
 	sreq := sanitizepkg.Request{Messages: raw}
 	out, stats, err := sanitizepkg.Sanitize(sreq, opts)
@@ -361,6 +343,32 @@ func applySanitize(req ask.AskRequest, errOut io.Writer) ask.AskRequest {
 	return req
 }
 
+// sanitizeOptions returns the sanitize settings, or false when sanitization is disabled.
+func sanitizeOptions() (sanitizepkg.Options, bool) {
+	if !viper.GetBool("sanitize.enabled") {
+		return sanitizepkg.Options{}, false
+	}
+	levelStr := strings.ToLower(strings.TrimSpace(viper.GetString("sanitize.level")))
+	var level sanitizepkg.Level
+	switch levelStr {
+	case "none":
+		level = sanitizepkg.LevelNone
+	case "aggressive":
+		level = sanitizepkg.LevelAggressive
+	case "light", "":
+		level = sanitizepkg.LevelLight
+	default:
+		level = sanitizepkg.LevelLight
+	}
+	return sanitizepkg.Options{
+		Level:             level,
+		MaxTokensAfter:    viper.GetInt("sanitize.max_tokens_after"),
+		LogStats:          viper.GetBool("sanitize.log_stats"),
+		PreserveLastUser:  true,
+		MaxDurationMillis: 100,
+	}, true
+}
+
 func buildMessagesForSanitize(req ask.AskRequest) []sanitizepkg.Message {
 	out := []sanitizepkg.Message{}
 	if strings.TrimSpace(req.SystemPrompt) != "" {
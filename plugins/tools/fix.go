@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gaia/plugins/ask"
+	"gaia/plugins/mempalace"
+	"gaia/plugins/roles"
+	"gaia/plugins/shared"
+	sanitizepkg "gaia/plugins/shared/sanitize"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// fixOutputBytes bounds the output kept from the failed command before its last lines are taken.
+const fixOutputBytes = 256 << 10
+
+func (p *ToolsPlugin) fixCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fix -- <command> [args...]",
+		Short: "Run a command and ask the model to explain and fix its failure",
+		Long: "Run a command under the tools allow/deny policy. When it fails, the exit code and the end of its " +
+			"output are sent to the model, which explains the failure and suggests a command. The suggestion only " +
+			"runs after approval in the command preview.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: p.fix,
+	}
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().Int("lines", 0, "Number of trailing output lines sent to the model (default tools.fix.tail_lines)")
+	cmd.Flags().String("model", "", "Model name (overrides tools.fix.model)")
+	cmd.Flags().Bool("pull", false, "Pull model from Ollama if available (force refresh)")
+	return cmd
+}
+
+func (p *ToolsPlugin) fix(cmd *cobra.Command, args []string) error {
+	ok, err := authorize(cmd, args[0], args[1:])
+	if !ok {
+		return err
+	}
+	ctx := cmd.Context()
+	full := strings.Join(args, " ")
+
+	output := &tailWriter{max: fixOutputBytes}
+	// nosemgrep
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Stdout = io.MultiWriter(cmd.OutOrStdout(), output)
+	c.Stderr = io.MultiWriter(cmd.ErrOrStderr(), output)
+	c.Stdin = cmd.InOrStdin()
+	runErr := c.Run()
+	if runErr == nil {
+		if err := mempalace.PersistToolExecution(ctx, full, "success", 0); err != nil && viper.GetBool("debug") {
+			_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
+		}
+		return shared.PrintBox(cmd.ErrOrStderr(), "Fix", fmt.Sprintf("%s succeeded, nothing to fix", full))
+	}
+	exitCode := extractExitCode(runErr)
+	lines, _ := cmd.Flags().GetInt("lines")
+	if lines <= 0 {
+		lines = viper.GetInt("tools.fix.tail_lines")
+	}
+	tail := lastLines(sanitizeOutput(cleanOutput(output.String())), lines)
+	if exitCode == -1 {
+		tail = strings.TrimSpace(tail + "\n" + runErr.Error())
+	}
+
+	answer, err := p.diagnose(cmd, full, exitCode, tail)
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("%s failed with exit code %d; diagnosis failed: %v", full, exitCode, err))
+	}
+	if err := shared.PrintAnswer(cmd.OutOrStdout(), "Fix", answer, viper.GetBool("raw")); err != nil {
+		return err
+	}
+	suggestion := suggestedCommand(answer)
+	outcome, err := runSuggestion(ctx, cmd, suggestion)
+	if err := mempalace.PersistFixResult(ctx, full, exitCode, answer, suggestion, outcome); err != nil && viper.GetBool("debug") {
+		_ = shared.PrintRaw(cmd.ErrOrStderr(), fmt.Sprintf("[DEBUG] mempalace persist failed: %v\n", err))
+	}
+	if err != nil {
+		return shared.PrintError(cmd.ErrOrStderr(), err.Error())
+	}
+	return nil
+}
+
+// diagnose asks the model why command failed, with the end of its output.
+func (p *ToolsPlugin) diagnose(cmd *cobra.Command, command string, exitCode int, output string) (string, error) {
+	model, _ := cmd.Flags().GetString("model")
+	pull, _ := cmd.Flags().GetBool("pull")
+	req := ask.AskRequest{
+		Provider:    ask.FirstNonEmpty(viper.GetString("tools.fix.provider"), viper.GetString("provider")),
+		Host:        viper.GetString("host"),
+		Port:        viper.GetInt("port"),
+		Model:       ask.FirstNonEmpty(model, ask.FirstNonEmpty(viper.GetString("tools.fix.model"), viper.GetString("model"))),
+		Timeout:     time.Duration(viper.GetInt("timeout_seconds")) * time.Second,
+		Pull:        pull,
+		ProgressOut: cmd.ErrOrStderr(),
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 120 * time.Second
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		req.Provider = ask.ResolveProviderFromModel(req.Model)
+	}
+	provider, err := ask.SelectProvider(p.providers, req.Provider)
+	if err != nil {
+		return "", err
+	}
+	rolePrompt, err := resolveToolRolePrompt(viper.GetString("tools.fix.role"), "fix", command, output, req)
+	if err != nil {
+		return "", err
+	}
+	req.SystemPrompt = rolePrompt
+	req.Message = buildFixPrompt(command, exitCode, output)
+	req = applySanitize(req, cmd.ErrOrStderr())
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), req.Timeout)
+	defer cancel()
+	resp, err := provider.Send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	answer, _ := ask.SplitThinking(resp.Text)
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	return answer, nil
+}
+
+func buildFixPrompt(command string, exitCode int, output string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The command `%s` failed with exit code %d on %s with the %s shell.\n", command, exitCode, roles.DetectOS(), roles.DetectShell())
+	if output == "" {
+		b.WriteString("It printed nothing.\n")
+	} else {
+		fmt.Fprintf(&b, "The end of its output:\n~~~~\n%s\n~~~~\n", output)
+	}
+	b.WriteString("Explain briefly why it failed. Then give the one shell command that fixes the problem, " +
+		"or that reruns the command correctly, in a single ```sh code block at the end. " +
+		"Leave out the code block when no command can fix it.")
+	return b.String()
+}
+
+// suggestedCommand returns the command of the last code block of answer, or "".
+func suggestedCommand(answer string) string {
+	blocks := shared.ExtractCodeBlocks(answer)
+	if len(blocks) == 0 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(blocks[len(blocks)-1].Code), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "$ ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// runSuggestion offers the suggested command in the command preview and runs it when
+// approved. It returns the outcome that is persisted.
+func runSuggestion(ctx context.Context, cmd *cobra.Command, suggestion string) (string, error) {
+	if suggestion == "" {
+		return "no command suggested", nil
+	}
+	if deniedLine(suggestion) {
+		return "denied", fmt.Errorf("suggested command denied by tools policy: %s", suggestion)
+	}
+	if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
+		return "not run", nil
+	}
+	decision, err := shared.RunCommandPreviewTUI(suggestion, "Suggested fix", cmd.InOrStdin(), cmd.OutOrStdout())
+	if err != nil {
+		return "cancelled", nil
+	}
+	if decision != "run" {
+		return "skipped", nil
+	}
+	// nosemgrep
+	c := exec.CommandContext(ctx, "sh", "-c", suggestion)
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+	c.Stdin = cmd.InOrStdin()
+	if err := c.Run(); err != nil {
+		return fmt.Sprintf("ran, exit code %d", extractExitCode(err)), fmt.Errorf("suggested command failed: %v", err)
+	}
+	return "ran, exit code 0", nil
+}
+
+// tailWriter keeps the last max bytes written to it. stdout and stderr share one writer,
+// so writes are serialized.
+type tailWriter struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	if over := len(w.buf) - w.max; over > 0 {
+		w.buf = append(w.buf[:0], w.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return string(w.buf)
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07`)
+
+// cleanOutput removes terminal escapes and keeps only the final state of lines redrawn
+// with carriage returns, such as progress bars.
+func cleanOutput(s string) string {
+	s = ansiEscape.ReplaceAllString(s, "")
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if idx := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// sanitizeOutput drops noise such as debug and timestamp lines from the command output when
+// sanitize.enabled is set. The output ends up in the user message, which applySanitize keeps
+// as is, so it is filtered here, before its last lines are taken.
+func sanitizeOutput(s string) string {
+	opts, ok := sanitizeOptions()
+	if !ok {
+		return s
+	}
+	opts.PreserveLastUser = false
+	opts.MaxTokensAfter = 0
+	out, _, err := sanitizepkg.Sanitize(sanitizepkg.Request{Messages: []sanitizepkg.Message{{Role: "user", Content: s}}}, opts)
+	if err != nil || len(out.Messages) == 0 {
+		return s
+	}
+	return out.Messages[0].Content
+}
+
+// lastLines returns the last n lines of s, or s when n is not positive.
+func lastLines(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCleanOutput(t *testing.T) {
+	in := "\x1b[31mFAIL\x1b[0m pkg\r\nprogress 10%\rprogress 100%\n\x1b]0;title\x07done   \n"
+	want := "FAIL pkg\nprogress 100%\ndone"
+	if got := cleanOutput(in); got != want {
+		t.Errorf("cleanOutput = %q, want %q", got, want)
+	}
+}
+
+func TestSanitizeOutput(t *testing.T) {
+	noisy := "2026-01-02 10:00:00 starting build\n[DEBUG] loading config\nmain.go:3: undefined: foo\nmain.go:3: undefined: foo\nexit status 1"
+	t.Cleanup(func() { viper.Set("sanitize.enabled", false) })
+	viper.Set("sanitize.enabled", false)
+	if got := sanitizeOutput(noisy); got != noisy {
+		t.Errorf("sanitizeOutput with sanitize disabled = %q", got)
+	}
+	viper.Set("sanitize.enabled", true)
+	if got, want := sanitizeOutput(noisy), "main.go:3: undefined: foo\nexit status 1"; got != want {
+		t.Errorf("sanitizeOutput = %q, want %q", got, want)
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	if got := lastLines("a\nb\nc\nd", 2); got != "c\nd" {
+		t.Errorf("lastLines 2 = %q", got)
+	}
+	if got := lastLines("a\nb", 5); got != "a\nb" {
+		t.Errorf("lastLines 5 = %q", got)
+	}
+	if got := lastLines("a\nb", 0); got != "a\nb" {
+		t.Errorf("lastLines 0 = %q", got)
+	}
+}
+
+func TestTailWriterKeepsEnd(t *testing.T) {
+	w := &tailWriter{max: 5}
+	_, _ = w.Write([]byte("abc"))
+	_, _ = w.Write([]byte("defg"))
+	if got := w.String(); got != "cdefg" {
+		t.Errorf("tailWriter = %q", got)
+	}
+}
+
+func TestSuggestedCommand(t *testing.T) {
+	answer := "The module cache is stale.\n\n```sh\n$ go clean -modcache && make test\n```\n"
+	if got := suggestedCommand(answer); got != "go clean -modcache && make test" {
+		t.Errorf("suggestedCommand = %q", got)
+	}
+	answer = "Try:\n```go\nfmt.Println()\n```\nthen\n```bash\nmake test\n```"
+	if got := suggestedCommand(answer); got != "make test" {
+		t.Errorf("suggestedCommand last block = %q", got)
+	}
+	if got := suggestedCommand("No command can fix this."); got != "" {
+		t.Errorf("suggestedCommand none = %q", got)
+	}
+}
+
+func TestBuildFixPrompt(t *testing.T) {
+	prompt := buildFixPrompt("make test", 2, "undefined: foo")
+	for _, want := range []string{"`make test` failed with exit code 2", "~~~~\nundefined: foo\n~~~~", "```sh"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("buildFixPrompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if prompt := buildFixPrompt("true", 1, ""); !strings.Contains(prompt, "It printed nothing.") {
+		t.Errorf("buildFixPrompt empty output:\n%s", prompt)
+	}
+}
+
+func TestDeniedLine(t *testing.T) {
+	viper.Set("tools.deny", []string{"git push"})
+	viper.Set("tools.deny_patterns", []string{"rm *"})
+	t.Cleanup(func() {
+		viper.Set("tools.deny", nil)
+		viper.Set("tools.deny_patterns", nil)
+	})
+	cases := map[string]bool{
+		"git push --force": true,
+		"rm -rf build":     true,
+		"make && git push": true,
+		"git status":       false,
+		"make test":        false,
+		"":                 false,
+	}
+	for line, want := range cases {
+		if got := deniedLine(line); got != want {
+			t.Errorf("deniedLine(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
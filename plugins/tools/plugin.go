@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"regexp"
 	"strings"
 
 	"gaia/config"
@@ -59,93 +60,127 @@ func (p *ToolsPlugin) Register(k *kernel.Kernel) ([]*cobra.Command, error) {
 				actionArgs = args[2:]
 			}
 			pull, _ := cmd.Flags().GetBool("pull")
-			if err := runToolAction(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), cmd.InOrStdin(), tool, action, actionArgs, p.providers, pull); err != nil {
+			printOnly, _ := cmd.Flags().GetBool("print")
+			if err := runToolAction(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), cmd.InOrStdin(), tool, action, actionArgs, p.providers, pull, printOnly); err != nil {
 				return shared.PrintError(cmd.ErrOrStderr(), err.Error())
 			}
 			return nil
 		},
 	}
 	root.Flags().Bool("pull", false, "Pull model from Ollama if available (force refresh)")
+	root.Flags().Bool("print", false, "Print the raw response and skip execute_command")
 
 	runCmd := &cobra.Command{
 		Use:   "run [command] [args...]",
 		Short: "Run a command with approval",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			command := args[0]
-			subcommand := ""
-			if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
-				subcommand = args[1]
-			}
-			key := command
-			if subcommand != "" {
-				key = command + " " + subcommand
+			allowed, err := authorize(cmd, args[0], args[1:])
+			if !allowed {
+				return err
 			}
+			return runCommand(cmd.Context(), args[0], args[1:], cmd)
+		},
+	}
+
+	root.AddCommand(runCmd)
+	return []*cobra.Command{root, p.fixCommand()}, nil
+}
+
+// authorize applies the tools allow/deny policy to command, asking for a decision when no
+// rule matches. It reports whether the command may run; err is set when the prompt failed.
+func authorize(cmd *cobra.Command, command string, args []string) (bool, error) {
+	subcommand := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcommand = args[0]
+	}
+	key := command
+	if subcommand != "" {
+		key = command + " " + subcommand
+	}
 
-			allowed := viper.GetStringSlice("tools.allow")
-			denied := viper.GetStringSlice("tools.deny")
-			allowPatterns := viper.GetStringSlice("tools.allow_patterns")
-			denyPatterns := viper.GetStringSlice("tools.deny_patterns")
+	allowed := viper.GetStringSlice("tools.allow")
+	denied := viper.GetStringSlice("tools.deny")
+	allowPatterns := viper.GetStringSlice("tools.allow_patterns")
+	denyPatterns := viper.GetStringSlice("tools.deny_patterns")
 
-			if matchExact(denied, key) || matchPattern(denyPatterns, key) {
-				return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Command denied: %s", key))
+	if matchExact(denied, key) || matchPattern(denyPatterns, key) {
+		return false, shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Command denied: %s", key))
+	}
+	if matchExact(allowed, key) || matchPattern(allowPatterns, key) {
+		return true, nil
+	}
+
+	for {
+		decision, pattern, newKey, err := promptDecision(cmd, key, command, subcommand)
+		if err != nil {
+			return false, err
+		}
+		if decision == "edit" {
+			if newKey != "" {
+				key = newKey
 			}
-			if matchExact(allowed, key) || matchPattern(allowPatterns, key) {
-				return runCommand(cmd.Context(), command, args[1:], cmd)
+			continue
+		}
+		if decision == "cancel" {
+			return false, shared.PrintError(cmd.ErrOrStderr(), "Cancelled")
+		}
+		if decision == "deny_exact" {
+			denied = appendUnique(denied, key)
+			if err := persistList("tools.deny", denied); err != nil {
+				return false, err
 			}
-
-			for {
-				decision, pattern, newKey, err := promptDecision(cmd, key, command, subcommand)
-				if err != nil {
-					return err
-				}
-				if decision == "edit" {
-					if newKey != "" {
-						key = newKey
-					}
-					continue
-				}
-				if decision == "cancel" {
-					return shared.PrintError(cmd.ErrOrStderr(), "Cancelled")
-				}
-				if decision == "deny_exact" {
-					denied = appendUnique(denied, key)
-					if err := persistList("tools.deny", denied); err != nil {
-						return err
-					}
-					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Command denied: %s", key))
-				}
-				if decision == "deny_pattern" {
-					denyPatterns = appendUnique(denyPatterns, pattern)
-					if err := persistList("tools.deny_patterns", denyPatterns); err != nil {
-						return err
-					}
-					return shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Command denied: %s", key))
-				}
-				if decision == "allow_exact" {
-					allowed = appendUnique(allowed, key)
-					if err := persistList("tools.allow", allowed); err != nil {
-						return err
-					}
-					return runCommand(cmd.Context(), command, args[1:], cmd)
-				}
-				if decision == "allow_pattern" {
-					allowPatterns = appendUnique(allowPatterns, pattern)
-					if err := persistList("tools.allow_patterns", allowPatterns); err != nil {
-						return err
-					}
-					return runCommand(cmd.Context(), command, args[1:], cmd)
-				}
-				return shared.PrintError(cmd.ErrOrStderr(), "Unknown decision")
+			return false, shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Command denied: %s", key))
+		}
+		if decision == "deny_pattern" {
+			denyPatterns = appendUnique(denyPatterns, pattern)
+			if err := persistList("tools.deny_patterns", denyPatterns); err != nil {
+				return false, err
 			}
-
-		},
+			return false, shared.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("Command denied: %s", key))
+		}
+		if decision == "allow_exact" {
+			allowed = appendUnique(allowed, key)
+			if err := persistList("tools.allow", allowed); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+		if decision == "allow_pattern" {
+			allowPatterns = appendUnique(allowPatterns, pattern)
+			if err := persistList("tools.allow_patterns", allowPatterns); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+		return false, shared.PrintError(cmd.ErrOrStderr(), "Unknown decision")
 	}
+}
 
-	root.AddCommand(runCmd)
-	return []*cobra.Command{root}, nil
+// deniedLine reports whether a tools deny rule matches a shell command line. Every command
+// chained with &&, ||, ; or | is checked, by its key and by its full text.
+func deniedLine(line string) bool {
+	denied := viper.GetStringSlice("tools.deny")
+	denyPatterns := viper.GetStringSlice("tools.deny_patterns")
+	for _, segment := range shellSegments.Split(line, -1) {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			continue
+		}
+		key := fields[0]
+		if len(fields) > 1 && !strings.HasPrefix(fields[1], "-") {
+			key = fields[0] + " " + fields[1]
+		}
+		full := strings.Join(fields, " ")
+		if matchExact(denied, key) || matchPattern(denyPatterns, key) || matchExact(denied, full) || matchPattern(denyPatterns, full) {
+			return true
+		}
+	}
+	return false
 }
 
+var shellSegments = regexp.MustCompile(`&&|\|\||[;|\n]`)
+
 func promptDecision(cmd *cobra.Command, key, command, subcommand string) (string, string, string, error) {
 	if !shared.HasTTYStdin() || !shared.HasTTYStdout() {
 		return "cancel", "", "", shared.PrintError(cmd.ErrOrStderr(), "No TTY available for approval prompt")